@@ -0,0 +1,785 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// Per-chain address validation, keyed by the 1Click blockchain codes used in
+// tokencache.go. Checksummed formats (EIP-55, base58check, bech32, CashAddr,
+// TON, Stellar) are verified in full so a typo is caught before a quote is
+// requested. Chains without a validator get only a basic sanity check.
+
+// AddressError explains why an address was rejected. LooksLike names the
+// chain the address appears to belong to, when that can be told.
+type AddressError struct {
+	Chain     string
+	Reason    string
+	LooksLike string
+}
+
+func (e *AddressError) Error() string {
+	return fmt.Sprintf("invalid %s address: %s", e.Chain, e.Reason)
+}
+
+// evmChains are the chain codes whose addresses are 0x-prefixed hex.
+var evmChains = map[string]bool{
+	"eth": true, "base": true, "arb": true, "bsc": true, "pol": true,
+	"op": true, "avax": true, "gnosis": true, "bera": true, "monad": true,
+	"plasma": true, "xlayer": true,
+}
+
+// addressValidators check one chain's address format. They return a short
+// reason on failure, or "" if the address is valid.
+var addressValidators = map[string]func(string) string{
+	"btc":      func(a string) string { return checkUTXOAddress(a, "bc", 0x00, 0x05) },
+	"ltc":      func(a string) string { return checkUTXOAddress(a, "ltc", 0x30, 0x32, 0x05) },
+	"doge":     func(a string) string { return checkUTXOAddress(a, "", 0x1e, 0x16) },
+	"bch":      checkBCHAddress,
+	"zec":      checkZcashAddress,
+	"sol":      checkSolanaAddress,
+	"ton":      checkTONAddress,
+	"tron":     checkTronAddress,
+	"near":     checkNEARAccount,
+	"xrp":      checkXRPAddress,
+	"xlm":      checkStellarAddress,
+	"stellar":  checkStellarAddress,
+	"sui":      func(a string) string { return checkHexAddress(a, 64, 64) },
+	"apt":      func(a string) string { return checkHexAddress(a, 1, 64) },
+	"aptos":    func(a string) string { return checkHexAddress(a, 1, 64) },
+	"starknet": func(a string) string { return checkHexAddress(a, 1, 64) },
+	"cardano":  checkCardanoAddress,
+	"aleo":     checkAleoAddress,
+}
+
+// addressLookalikes is the order in which detectAddressChain tries chains.
+// Checksummed formats come first so loosely specified ones (Solana's bare
+// base58, NEAR account names) only match what nothing else claims.
+var addressLookalikes = []string{
+	"eth", "btc", "ltc", "doge", "bch", "zec", "tron", "xrp", "ton", "xlm",
+	"cardano", "aleo", "sui", "sol",
+}
+
+// validateAddress checks addr against chain's address format.
+func validateAddress(chain, addr string) error {
+	chain = strings.ToLower(chain)
+	reason := ""
+	switch {
+	case addr == "":
+		reason = "empty"
+	case strings.TrimSpace(addr) != addr || strings.ContainsAny(addr, " \t\r\n"):
+		reason = "contains spaces"
+	case evmChains[chain]:
+		reason = checkEVMAddress(addr)
+	case addressValidators[chain] != nil:
+		reason = addressValidators[chain](addr)
+	default:
+		reason = checkGenericAddress(addr)
+	}
+	if reason == "" {
+		return nil
+	}
+
+	e := &AddressError{Chain: networkDisplayName(chain), Reason: reason}
+	if other := detectAddressChain(addr); other != "" && !sameAddressFamily(other, chain) {
+		e.LooksLike = other
+	}
+	return e
+}
+
+// detectAddressChain returns the chain code addr appears to belong to, or "".
+func detectAddressChain(addr string) string {
+	for _, chain := range addressLookalikes {
+		if evmChains[chain] {
+			if checkEVMAddress(addr) == "" {
+				return chain
+			}
+			continue
+		}
+		if addressValidators[chain](addr) == "" {
+			return chain
+		}
+	}
+	if strings.HasSuffix(addr, ".near") || strings.HasSuffix(addr, ".tg") {
+		if checkNEARAccount(addr) == "" {
+			return "near"
+		}
+	}
+	return ""
+}
+
+// sameAddressFamily reports whether an address detected as detected is also
+// valid on chain (all EVM chains share one format, as do Sui and Aptos).
+func sameAddressFamily(detected, chain string) bool {
+	if detected == chain || evmChains[detected] && evmChains[chain] {
+		return true
+	}
+	return detected == "sui" && (chain == "apt" || chain == "aptos" || chain == "starknet")
+}
+
+// addressProblem returns a user-facing message for a recipient or refund
+// address that is not valid on chain, or "" if it is fine. role is
+// "recipient" or "refund".
+func addressProblem(role, chain, addr string) string {
+	err := validateAddress(chain, addr)
+	if err == nil {
+		return ""
+	}
+	label := "Recipient address"
+	if role == "refund" {
+		label = "Refund address"
+	}
+	ae, ok := err.(*AddressError)
+	if !ok {
+		return label + " is invalid."
+	}
+	if ae.LooksLike != "" {
+		family := networkDisplayName(ae.LooksLike)
+		if evmChains[ae.LooksLike] {
+			family = "an EVM"
+		} else {
+			family = "a " + family
+		}
+		if role == "refund" {
+			return fmt.Sprintf("%s looks like %s address, but refunds are sent on %s. Use an address on the network you're sending from.", label, family, ae.Chain)
+		}
+		return fmt.Sprintf("%s looks like %s address, but you're receiving on %s.", label, family, ae.Chain)
+	}
+	return fmt.Sprintf("%s is not a valid %s address (%s).", label, ae.Chain, ae.Reason)
+}
+
+// checkGenericAddress is the fallback for chains without a validator.
+func checkGenericAddress(addr string) string {
+	if len(addr) < 2 || len(addr) > 128 {
+		return "unexpected length"
+	}
+	for _, c := range addr {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("._:-+/=", c)) {
+			return "unexpected character"
+		}
+	}
+	return ""
+}
+
+// ── EVM ──────────────────────────────────────────────────────
+
+// checkEVMAddress accepts 0x + 40 hex digits. Mixed-case addresses must carry
+// a valid EIP-55 checksum; all-lower and all-upper carry none.
+func checkEVMAddress(addr string) string {
+	if len(addr) != 42 || !strings.HasPrefix(addr, "0x") {
+		return "expected 0x followed by 40 hex characters"
+	}
+	body := addr[2:]
+	if _, err := hex.DecodeString(body); err != nil {
+		return "not hex"
+	}
+	if body == strings.ToLower(body) || body == strings.ToUpper(body) {
+		return ""
+	}
+	if eip55Checksum(body) != addr {
+		return "checksum mismatch, check for a typo"
+	}
+	return ""
+}
+
+// eip55Checksum returns the EIP-55 mixed-case form of a 40-hex-digit address.
+func eip55Checksum(hexAddr string) string {
+	lower := strings.ToLower(strings.TrimPrefix(hexAddr, "0x"))
+	hash := keccak256([]byte(lower))
+	out := []byte(lower)
+	for i, c := range out {
+		nibble := hash[i/2] >> 4
+		if i%2 == 1 {
+			nibble = hash[i/2] & 0x0f
+		}
+		if c >= 'a' && nibble >= 8 {
+			out[i] = c - 32
+		}
+	}
+	return "0x" + string(out)
+}
+
+func checkHexAddress(addr string, minDigits, maxDigits int) string {
+	body, ok := strings.CutPrefix(addr, "0x")
+	if !ok || len(body) < minDigits || len(body) > maxDigits {
+		if minDigits == maxDigits {
+			return fmt.Sprintf("expected 0x followed by %d hex characters", maxDigits)
+		}
+		return "expected a 0x-prefixed hex address"
+	}
+	for _, c := range body {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return "not hex"
+		}
+	}
+	return ""
+}
+
+// ── Bitcoin family ───────────────────────────────────────────
+
+// checkUTXOAddress accepts a segwit address with the given human-readable
+// part (if any) or a base58check address with one of the version bytes.
+func checkUTXOAddress(addr, hrp string, versions ...byte) string {
+	if hrp != "" && strings.HasPrefix(strings.ToLower(addr), hrp+"1") {
+		return checkSegwitAddress(addr, hrp)
+	}
+	payload, reason := base58CheckDecode(addr)
+	if reason != "" {
+		return reason
+	}
+	if len(payload) != 21 || bytes.IndexByte(versions, payload[0]) < 0 {
+		return "wrong address type for this network"
+	}
+	return ""
+}
+
+func checkSegwitAddress(addr, hrp string) string {
+	gotHRP, data, spec, reason := bech32Decode(addr, 90)
+	if reason != "" {
+		return reason
+	}
+	if gotHRP != hrp || len(data) < 1 {
+		return "wrong network prefix"
+	}
+	version := data[0]
+	prog, ok := convertBits(data[1:], 5, 8, false)
+	switch {
+	case !ok || version > 16 || len(prog) < 2 || len(prog) > 40:
+		return "malformed segwit program"
+	case version == 0 && len(prog) != 20 && len(prog) != 32:
+		return "malformed segwit program"
+	case version == 0 && spec != bech32Const:
+		return "checksum mismatch, check for a typo"
+	case version != 0 && spec != bech32mConst:
+		return "checksum mismatch, check for a typo"
+	}
+	return ""
+}
+
+// checkBCHAddress accepts CashAddr (with or without the bitcoincash: prefix)
+// and legacy base58 addresses.
+func checkBCHAddress(addr string) string {
+	lower := strings.ToLower(addr)
+	payload := strings.TrimPrefix(lower, "bitcoincash:")
+	if payload == lower && (strings.HasPrefix(addr, "1") || strings.HasPrefix(addr, "3")) {
+		return checkUTXOAddress(addr, "", 0x00, 0x05)
+	}
+	if addr != lower && addr != strings.ToUpper(addr) {
+		return "mixed case"
+	}
+	if len(payload) != 42 || (payload[0] != 'q' && payload[0] != 'p') {
+		return "expected a CashAddr address starting with q or p"
+	}
+	values := make([]byte, 0, len("bitcoincash")+1+len(payload))
+	for _, c := range "bitcoincash" {
+		values = append(values, byte(c)&0x1f)
+	}
+	values = append(values, 0)
+	for i := 0; i < len(payload); i++ {
+		d := strings.IndexByte(bech32Charset, payload[i])
+		if d < 0 {
+			return "invalid character"
+		}
+		values = append(values, byte(d))
+	}
+	if cashAddrPolymod(values) != 0 {
+		return "checksum mismatch, check for a typo"
+	}
+	return ""
+}
+
+func cashAddrPolymod(values []byte) uint64 {
+	gen := [5]uint64{0x98f2bc8e61, 0x79b76d99e2, 0xf33e5fb3c4, 0xae2eabe2a8, 0x1e4f43e470}
+	c := uint64(1)
+	for _, d := range values {
+		c0 := c >> 35
+		c = (c&0x07ffffffff)<<5 ^ uint64(d)
+		for i := 0; i < 5; i++ {
+			if c0>>i&1 == 1 {
+				c ^= gen[i]
+			}
+		}
+	}
+	return c ^ 1
+}
+
+// checkZcashAddress accepts transparent (t1/t3) and unified (u1) addresses.
+func checkZcashAddress(addr string) string {
+	if strings.HasPrefix(strings.ToLower(addr), "u1") {
+		hrp, _, spec, reason := bech32Decode(addr, 1023)
+		if reason != "" {
+			return reason
+		}
+		if hrp != "u" || spec != bech32mConst {
+			return "malformed unified address"
+		}
+		return ""
+	}
+	payload, reason := base58CheckDecode(addr)
+	if reason != "" {
+		return reason
+	}
+	if len(payload) != 22 || payload[0] != 0x1c || (payload[1] != 0xb8 && payload[1] != 0xbd) {
+		return "expected a transparent (t1/t3) or unified (u1) address"
+	}
+	return ""
+}
+
+// ── Base58 chains ────────────────────────────────────────────
+
+// base58CheckDecode decodes a Bitcoin-alphabet base58check string and
+// returns the payload without its 4-byte checksum.
+func base58CheckDecode(s string) ([]byte, string) {
+	b, ok := base58Decode(s)
+	if !ok {
+		return nil, "invalid character"
+	}
+	if len(b) < 5 {
+		return nil, "too short"
+	}
+	payload, sum := b[:len(b)-4], b[len(b)-4:]
+	if !bytes.Equal(doubleSHA256(payload)[:4], sum) {
+		return nil, "checksum mismatch, check for a typo"
+	}
+	return payload, ""
+}
+
+// base58CheckEncode is the inverse of base58CheckDecode.
+func base58CheckEncode(payload []byte) string {
+	return base58Encode(append(append([]byte(nil), payload...), doubleSHA256(payload)[:4]...))
+}
+
+func doubleSHA256(b []byte) []byte {
+	h := sha256.Sum256(b)
+	h = sha256.Sum256(h[:])
+	return h[:]
+}
+
+func checkTronAddress(addr string) string {
+	payload, reason := base58CheckDecode(addr)
+	if reason != "" {
+		return reason
+	}
+	if len(payload) != 21 || payload[0] != 0x41 {
+		return "expected a base58 address starting with T"
+	}
+	return ""
+}
+
+func checkSolanaAddress(addr string) string {
+	b, ok := base58Decode(addr)
+	if !ok {
+		return "invalid character"
+	}
+	if len(b) != 32 {
+		return "expected a 32-byte base58 address"
+	}
+	return ""
+}
+
+// xrpAlphabet is Ripple's base58 alphabet.
+const xrpAlphabet = "rpshnaf39wBUDNEGHJKLM4PQRST7VWXYZ2bcdeCg65jkm8oFqi1tuvAxyz"
+
+// checkXRPAddress accepts classic r-addresses.
+func checkXRPAddress(addr string) string {
+	if !strings.HasPrefix(addr, "r") {
+		return "expected a classic address starting with r"
+	}
+	payload, reason := base58CheckDecode(xrpToBitcoinAlphabet(addr))
+	if reason != "" {
+		return reason
+	}
+	if len(payload) != 21 || payload[0] != 0x00 {
+		return "expected a classic address starting with r"
+	}
+	return ""
+}
+
+// xrpToBitcoinAlphabet maps each Ripple base58 digit to the Bitcoin digit
+// of the same value, so the Bitcoin decoder can be reused.
+func xrpToBitcoinAlphabet(s string) string {
+	out := make([]byte, len(s))
+	for i := 0; i < len(s); i++ {
+		d := strings.IndexByte(xrpAlphabet, s[i])
+		if d < 0 {
+			return "0" // not in the Bitcoin alphabet either
+		}
+		out[i] = base58Alphabet[d]
+	}
+	return string(out)
+}
+
+// xrpEncode encodes a 20-byte account ID as a classic r-address.
+func xrpEncode(accountID []byte) string {
+	s := base58CheckEncode(append([]byte{0x00}, accountID...))
+	out := make([]byte, len(s))
+	for i := 0; i < len(s); i++ {
+		out[i] = xrpAlphabet[strings.IndexByte(base58Alphabet, s[i])]
+	}
+	return string(out)
+}
+
+// checkCardanoAddress accepts Shelley (addr1…) and Byron (Ae2…, DdzFF…)
+// mainnet addresses.
+func checkCardanoAddress(addr string) string {
+	if strings.HasPrefix(addr, "Ae2") || strings.HasPrefix(addr, "DdzFF") {
+		if _, ok := base58Decode(addr); !ok {
+			return "invalid character"
+		}
+		return ""
+	}
+	hrp, _, spec, reason := bech32Decode(addr, 1023)
+	if reason != "" {
+		return reason
+	}
+	if hrp != "addr" || spec != bech32Const {
+		return "expected a mainnet address starting with addr1"
+	}
+	return ""
+}
+
+func checkAleoAddress(addr string) string {
+	hrp, data, spec, reason := bech32Decode(addr, 90)
+	if reason != "" {
+		return reason
+	}
+	if prog, ok := convertBits(data, 5, 8, false); hrp != "aleo" || spec != bech32mConst || !ok || len(prog) != 32 {
+		return "expected an address starting with aleo1"
+	}
+	return ""
+}
+
+// ── NEAR ─────────────────────────────────────────────────────
+
+// checkNEARAccount accepts implicit accounts (64 lowercase hex), ETH-implicit
+// accounts (0x + 40 lowercase hex) and named accounts.
+func checkNEARAccount(addr string) string {
+	if len(addr) < 2 || len(addr) > 64 {
+		return "account IDs are 2-64 characters"
+	}
+	if len(addr) == 64 && checkHexAddress("0x"+addr, 64, 64) == "" {
+		if addr != strings.ToLower(addr) {
+			return "implicit accounts are lowercase"
+		}
+		return ""
+	}
+	// Named accounts: dot-separated parts of [a-z0-9] runs joined by single
+	// '-' or '_' separators.
+	for _, part := range strings.Split(addr, ".") {
+		if part == "" {
+			return "empty account part"
+		}
+		prevSep := true
+		for i := 0; i < len(part); i++ {
+			c := part[i]
+			switch {
+			case c >= 'a' && c <= 'z' || c >= '0' && c <= '9':
+				prevSep = false
+			case c == '-' || c == '_':
+				if prevSep {
+					return "misplaced - or _"
+				}
+				prevSep = true
+			default:
+				if c >= 'A' && c <= 'Z' {
+					return "account IDs are lowercase"
+				}
+				return "invalid character"
+			}
+		}
+		if prevSep {
+			return "misplaced - or _"
+		}
+	}
+	return ""
+}
+
+// ── TON ──────────────────────────────────────────────────────
+
+// checkTONAddress accepts user-friendly addresses (48 base64 characters,
+// either alphabet) and raw workchain:hex addresses.
+func checkTONAddress(addr string) string {
+	if wc, hash, ok := strings.Cut(addr, ":"); ok {
+		if wc != "0" && wc != "-1" {
+			return "unknown workchain"
+		}
+		return checkHexAddress("0x"+hash, 64, 64)
+	}
+	if len(addr) != 48 {
+		return "expected a 48-character address"
+	}
+	enc := base64.URLEncoding
+	if strings.ContainsAny(addr, "+/") {
+		enc = base64.StdEncoding
+	}
+	b, err := enc.DecodeString(addr)
+	if err != nil || len(b) != 36 {
+		return "invalid character"
+	}
+	if b[0]&0x80 != 0 {
+		return "testnet address"
+	}
+	if tag := b[0]; tag != 0x11 && tag != 0x51 {
+		return "unknown address flags"
+	}
+	if b[1] != 0x00 && b[1] != 0xff {
+		return "unknown workchain"
+	}
+	if crc16XModem(b[:34]) != binary.BigEndian.Uint16(b[34:]) {
+		return "checksum mismatch, check for a typo"
+	}
+	return ""
+}
+
+// tonEncode returns the user-friendly URL-safe form of a workchain-0 address.
+func tonEncode(bounceable bool, hash []byte) string {
+	b := make([]byte, 36)
+	b[0] = 0x51
+	if bounceable {
+		b[0] = 0x11
+	}
+	copy(b[2:34], hash)
+	binary.BigEndian.PutUint16(b[34:], crc16XModem(b[:34]))
+	return base64.URLEncoding.EncodeToString(b)
+}
+
+func crc16XModem(b []byte) uint16 {
+	var crc uint16
+	for _, c := range b {
+		crc ^= uint16(c) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// ── Stellar ──────────────────────────────────────────────────
+
+// checkStellarAddress accepts StrKey account IDs (G…).
+func checkStellarAddress(addr string) string {
+	if len(addr) != 56 || addr[0] != 'G' {
+		return "expected a 56-character address starting with G"
+	}
+	b, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(addr)
+	if err != nil || len(b) != 35 {
+		return "invalid character"
+	}
+	if b[0] != 6<<3 {
+		return "expected a 56-character address starting with G"
+	}
+	if crc16XModem(b[:33]) != binary.LittleEndian.Uint16(b[33:]) {
+		return "checksum mismatch, check for a typo"
+	}
+	return ""
+}
+
+// ── Bech32 ───────────────────────────────────────────────────
+
+// Checksum constants for BIP-173 bech32 and BIP-350 bech32m.
+const (
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+)
+
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		b := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if b>>i&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	out := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]&31)
+	}
+	return out
+}
+
+// bech32Decode splits a bech32 or bech32m string into its human-readable
+// part and 5-bit data (checksum removed). spec is bech32Const or
+// bech32mConst.
+func bech32Decode(s string, maxLen int) (hrp string, data []byte, spec uint32, reason string) {
+	if len(s) > maxLen {
+		return "", nil, 0, "too long"
+	}
+	lower := strings.ToLower(s)
+	if s != lower && s != strings.ToUpper(s) {
+		return "", nil, 0, "mixed case"
+	}
+	pos := strings.LastIndexByte(lower, '1')
+	if pos < 1 || pos+7 > len(lower) {
+		return "", nil, 0, "malformed"
+	}
+	hrp = lower[:pos]
+	for i := pos + 1; i < len(lower); i++ {
+		d := strings.IndexByte(bech32Charset, lower[i])
+		if d < 0 {
+			return "", nil, 0, "invalid character"
+		}
+		data = append(data, byte(d))
+	}
+	spec = bech32Polymod(append(bech32HRPExpand(hrp), data...))
+	if spec != bech32Const && spec != bech32mConst {
+		return "", nil, 0, "checksum mismatch, check for a typo"
+	}
+	return hrp, data[:len(data)-6], spec, ""
+}
+
+// bech32Encode encodes 5-bit data with the given checksum constant.
+func bech32Encode(hrp string, data []byte, spec uint32) string {
+	values := append(bech32HRPExpand(hrp), data...)
+	mod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ spec
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, d := range data {
+		sb.WriteByte(bech32Charset[d])
+	}
+	for i := 0; i < 6; i++ {
+		sb.WriteByte(bech32Charset[mod>>(5*(5-i))&31])
+	}
+	return sb.String()
+}
+
+// segwitEncode returns the bech32/bech32m address for a witness program.
+func segwitEncode(hrp string, version byte, program []byte) string {
+	data, _ := convertBits(program, 8, 5, true)
+	spec := uint32(bech32Const)
+	if version > 0 {
+		spec = bech32mConst
+	}
+	return bech32Encode(hrp, append([]byte{version}, data...), spec)
+}
+
+func convertBits(data []byte, from, to uint, pad bool) ([]byte, bool) {
+	var acc, nbits uint
+	maxv := uint(1)<<to - 1
+	var out []byte
+	for _, v := range data {
+		if uint(v)>>from != 0 {
+			return nil, false
+		}
+		acc = acc<<from | uint(v)
+		nbits += from
+		for nbits >= to {
+			nbits -= to
+			out = append(out, byte(acc>>nbits&maxv))
+		}
+	}
+	if pad {
+		if nbits > 0 {
+			out = append(out, byte(acc<<(to-nbits)&maxv))
+		}
+	} else if nbits >= from || acc<<(to-nbits)&maxv != 0 {
+		return nil, false
+	}
+	return out, true
+}
+
+// ── Keccak-256 ───────────────────────────────────────────────
+
+// keccak256 is the original Keccak-256 used by Ethereum (not FIPS SHA3-256,
+// which pads differently).
+func keccak256(data []byte) []byte {
+	const rate = 136
+	var a [25]uint64
+	block := make([]byte, rate)
+	for len(data) >= rate {
+		keccakAbsorb(&a, data[:rate])
+		data = data[rate:]
+	}
+	for i := range block {
+		block[i] = 0
+	}
+	copy(block, data)
+	block[len(data)] ^= 0x01
+	block[rate-1] ^= 0x80
+	keccakAbsorb(&a, block)
+
+	out := make([]byte, 32)
+	for i := 0; i < 4; i++ {
+		binary.LittleEndian.PutUint64(out[i*8:], a[i])
+	}
+	return out
+}
+
+func keccakAbsorb(a *[25]uint64, block []byte) {
+	for i := 0; i < len(block)/8; i++ {
+		a[i] ^= binary.LittleEndian.Uint64(block[i*8:])
+	}
+	keccakF1600(a)
+}
+
+var keccakRC = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808A, 0x8000000080008000,
+	0x000000000000808B, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008A, 0x0000000000000088, 0x0000000080008009, 0x000000008000000A,
+	0x000000008000808B, 0x800000000000008B, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800A, 0x800000008000000A,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+// keccakRot holds the rho rotation offsets, indexed x + 5y.
+var keccakRot = [25]int{
+	0, 1, 62, 28, 27,
+	36, 44, 6, 55, 20,
+	3, 10, 43, 25, 39,
+	41, 45, 15, 21, 8,
+	18, 2, 61, 56, 14,
+}
+
+func keccakF1600(a *[25]uint64) {
+	var b [25]uint64
+	var c [5]uint64
+	for round := 0; round < 24; round++ {
+		// θ
+		for x := 0; x < 5; x++ {
+			c[x] = a[x] ^ a[x+5] ^ a[x+10] ^ a[x+15] ^ a[x+20]
+		}
+		for x := 0; x < 5; x++ {
+			d := c[(x+4)%5] ^ bits.RotateLeft64(c[(x+1)%5], 1)
+			for y := 0; y < 25; y += 5 {
+				a[y+x] ^= d
+			}
+		}
+		// ρ and π
+		for x := 0; x < 5; x++ {
+			for y := 0; y < 5; y++ {
+				b[y+5*((2*x+3*y)%5)] = bits.RotateLeft64(a[x+5*y], keccakRot[x+5*y])
+			}
+		}
+		// χ
+		for y := 0; y < 25; y += 5 {
+			for x := 0; x < 5; x++ {
+				a[y+x] = b[y+x] ^ (^b[y+(x+1)%5] & b[y+(x+2)%5])
+			}
+		}
+		// ι
+		a[0] ^= keccakRC[round]
+	}
+}
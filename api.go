@@ -0,0 +1,584 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSON API.
+//
+// /api/v1 mirrors the web swap flow for scripts and wallets:
+//
+//	POST /api/v1/quote            dry quote — prices only, no deposit address
+//	POST /api/v1/orders           real quote — order token and deposit details
+//	GET  /api/v1/orders/{token}   live status of an order
+//	GET  /api/v1/tokens           supported tokens
+//
+// It keeps the web flow's guarantees: the order token is the only record of
+// an order, request bodies are never logged, and appFees is always empty.
+
+// apiMaxBody caps request bodies; a swap request is a few hundred bytes.
+const apiMaxBody = 16 << 10
+
+// apiOrderKeyHeader carries the unlock key for a passphrase-locked order.
+const apiOrderKeyHeader = "X-Order-Key"
+
+// API error codes, in addition to the apiErr* codes passed through from 1Click.
+const (
+	apiCodeInvalidRequest   = "invalid_request"
+	apiCodeUnknownToken     = "unknown_token"
+	apiCodeInvalidAmount    = "invalid_amount"
+	apiCodeInvalidAddress   = "invalid_address"
+	apiCodeInvalidOrder     = "invalid_order"
+	apiCodeOrderLocked      = "order_locked"
+	apiCodeQuoteRejected    = "quote_rejected"
+	apiCodeMethodNotAllowed = "method_not_allowed"
+	apiCodeInternal         = "internal_error"
+)
+
+// apiRateLimit is one route's allowance per client /24.
+type apiRateLimit struct {
+	limit  int
+	window time.Duration
+}
+
+var apiRateLimits = map[string]apiRateLimit{
+	"quote":  {30, time.Minute},
+	"orders": {10, time.Minute},
+	"status": {60, time.Minute},
+	"tokens": {60, time.Minute},
+}
+
+// APIErrorBody is the body of every non-2xx API response.
+type APIErrorBody struct {
+	Error APIErrorDetail `json:"error"`
+}
+
+// APIErrorDetail describes a failed API request.
+type APIErrorDetail struct {
+	Code      string `json:"code"`                // machine-readable, e.g. "invalid_address"
+	Message   string `json:"message"`             // human-readable
+	Field     string `json:"field,omitempty"`     // offending request field, if any
+	MinAmount string `json:"minAmount,omitempty"` // for amount_too_low, in human units
+}
+
+// APISwapRequest is the body of POST /api/v1/quote and POST /api/v1/orders.
+// As on the web form, set amount for an exact-input swap, amountOut for an
+// exact-output swap, or neither (orders only) for a deposit address that
+// accepts any amount (ANY_INPUT).
+type APISwapRequest struct {
+	From        string `json:"from"`                  // ticker, e.g. "ETH"
+	FromNetwork string `json:"fromNetwork"`           // chain code, e.g. "eth"
+	To          string `json:"to"`                    // ticker
+	ToNetwork   string `json:"toNetwork"`             // chain code
+	Amount      string `json:"amount,omitempty"`      // human units of from
+	AmountOut   string `json:"amountOut,omitempty"`   // human units of to
+	Recipient   string `json:"recipient"`             // address on toNetwork
+	RefundTo    string `json:"refundTo"`              // address on fromNetwork
+	SlippageBps int    `json:"slippageBps,omitempty"` // 1-5000, default 100
+	Passphrase  string `json:"passphrase,omitempty"`  // orders only: lock the order token
+}
+
+// APIQuote is the response to POST /api/v1/quote.
+type APIQuote struct {
+	From            string     `json:"from"`
+	FromNetwork     string     `json:"fromNetwork"`
+	To              string     `json:"to"`
+	ToNetwork       string     `json:"toNetwork"`
+	SwapType        string     `json:"swapType"` // FLEX_INPUT or EXACT_OUTPUT
+	AmountIn        string     `json:"amountIn"` // estimated for EXACT_OUTPUT
+	AmountInAtomic  string     `json:"amountInAtomic"`
+	AmountOut       string     `json:"amountOut"` // estimated for FLEX_INPUT
+	AmountOutAtomic string     `json:"amountOutAtomic"`
+	AmountInUSD     string     `json:"amountInUsd,omitempty"`
+	AmountOutUSD    string     `json:"amountOutUsd,omitempty"`
+	SpreadUSD       string     `json:"spreadUsd,omitempty"`
+	SpreadPct       string     `json:"spreadPct,omitempty"`
+	Rate            string     `json:"rate,omitempty"`
+	SlippageBps     int        `json:"slippageBps"`
+	TimeEstimate    int        `json:"timeEstimate"` // seconds
+	AppFees         []struct{} `json:"appFees"`      // always empty
+}
+
+// APIOrder describes an order token. POST /api/v1/orders returns it with
+// the deposit details; GET /api/v1/orders/{token} adds live status.
+type APIOrder struct {
+	Token         string      `json:"token"`
+	URL           string      `json:"url"` // web order page, relative to this server
+	From          string      `json:"from"`
+	FromNetwork   string      `json:"fromNetwork"`
+	To            string      `json:"to"`
+	ToNetwork     string      `json:"toNetwork"`
+	SwapType      string      `json:"swapType"`
+	AmountIn      string      `json:"amountIn,omitempty"`  // empty for ANY_INPUT
+	AmountOut     string      `json:"amountOut,omitempty"` // empty for ANY_INPUT
+	Deadline      string      `json:"deadline,omitempty"`
+	CorrelationID string      `json:"correlationId,omitempty"`
+	Upstream      string      `json:"upstream,omitempty"`  // 1Click endpoint that issued the quote
+	Locked        bool        `json:"locked"`              // passphrase-locked and not unlocked
+	Deposit       *APIDeposit `json:"deposit,omitempty"`   // omitted while locked
+	Recipient     string      `json:"recipient,omitempty"` // omitted while locked
+	RefundTo      string      `json:"refundTo,omitempty"`  // omitted while locked
+	UnlockKey     string      `json:"unlockKey,omitempty"` // new locked orders only; send as X-Order-Key
+}
+
+// APIDeposit is where to send funds for an order.
+type APIDeposit struct {
+	Address string `json:"address"`
+	Memo    string `json:"memo,omitempty"` // required when present
+}
+
+// APIOrderStatus is the response to GET /api/v1/orders/{token}.
+type APIOrderStatus struct {
+	APIOrder
+	Status      string               `json:"status"` // PENDING_DEPOSIT, PROCESSING, SUCCESS, REFUNDED, FAILED, INCOMPLETE_DEPOSIT or UNKNOWN
+	Terminal    bool                 `json:"terminal"`
+	UpdatedAt   string               `json:"updatedAt,omitempty"`
+	SwapDetails *SwapDetails         `json:"swapDetails,omitempty"` // omitted while locked
+	Withdrawals []AnyInputWithdrawal `json:"withdrawals,omitempty"` // ANY_INPUT only
+}
+
+// APIToken is a supported token, as listed by GET /api/v1/tokens.
+type APIToken struct {
+	Ticker          string  `json:"ticker"`
+	Name            string  `json:"name,omitempty"`
+	Network         string  `json:"network"` // chain code used in swap requests
+	AssetID         string  `json:"assetId"`
+	Decimals        int     `json:"decimals"`
+	PriceUSD        float64 `json:"priceUsd,omitempty"`
+	ContractAddress string  `json:"contractAddress,omitempty"`
+}
+
+// APITokenList is the response to GET /api/v1/tokens.
+type APITokenList struct {
+	Tokens []APIToken `json:"tokens"`
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeAPIJSON(w, status, APIErrorBody{Error: APIErrorDetail{Code: code, Message: message}})
+}
+
+func writeAPIFieldError(w http.ResponseWriter, status int, code, field, message string) {
+	writeAPIJSON(w, status, APIErrorBody{Error: APIErrorDetail{Code: code, Message: message, Field: field}})
+}
+
+// writeAPIUpstreamError reports a failed 1Click call with the same status
+// and message the web pages use, under the 1Click error code.
+func writeAPIUpstreamError(w http.ResponseWriter, err error, amountToken *TokenInfo) {
+	status, message := describeAPIError(err, amountToken)
+	detail := APIErrorDetail{Code: apiErrUnavailable, Message: message}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.Code == apiErrRateLimited:
+			detail.Code = apiErrRateLimited
+		case !apiErr.Temporary():
+			detail.Code = apiErr.Code
+			if apiErr.Code == apiErrAmountTooLow && apiErr.MinAmount != "" && amountToken != nil {
+				detail.MinAmount = atomicToHuman(apiErr.MinAmount, amountToken.Decimals)
+			}
+		}
+	}
+	writeAPIJSON(w, status, APIErrorBody{Error: detail})
+}
+
+// apiAllow applies route's rate limit, answering 429 when it is exhausted.
+func apiAllow(w http.ResponseWriter, r *http.Request, route string) bool {
+	rl := apiRateLimits[route]
+	if limiter.allowScoped("api/"+route, clientIP(r), rl.limit, rl.window) {
+		return true
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(rl.window.Seconds())))
+	writeAPIError(w, http.StatusTooManyRequests, apiErrRateLimited, "Too many requests. Please wait before trying again.")
+	return false
+}
+
+// apiMethod answers 405 unless r uses method.
+func apiMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeAPIError(w, http.StatusMethodNotAllowed, apiCodeMethodNotAllowed, "Use "+method+" for this endpoint.")
+	return false
+}
+
+// decodeAPIRequest reads a JSON body into v, rejecting unknown fields.
+func decodeAPIRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, apiMaxBody)
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeAPIError(w, http.StatusBadRequest, apiCodeInvalidRequest, "Request body must be a JSON object: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// apiSwap is a validated APISwapRequest.
+type apiSwap struct {
+	fromToken    *TokenInfo
+	toToken      *TokenInfo
+	swapType     string
+	atomicAmount string
+	amountToken  *TokenInfo // token the amount is denominated in
+	slippageBps  int
+}
+
+// resolveAPISwap validates a swap request the way handleQuote validates the
+// web form, writing an error response and returning nil on failure.
+func resolveAPISwap(w http.ResponseWriter, req *APISwapRequest, allowAnyInput bool) *apiSwap {
+	fromToken := findToken(req.From, req.FromNetwork)
+	if fromToken == nil {
+		writeAPIFieldError(w, http.StatusBadRequest, apiCodeUnknownToken, "from", "Unknown token "+req.From+" on network "+req.FromNetwork+". See /api/v1/tokens.")
+		return nil
+	}
+	toToken := findToken(req.To, req.ToNetwork)
+	if toToken == nil {
+		writeAPIFieldError(w, http.StatusBadRequest, apiCodeUnknownToken, "to", "Unknown token "+req.To+" on network "+req.ToNetwork+". See /api/v1/tokens.")
+		return nil
+	}
+
+	req.Recipient = strings.TrimSpace(req.Recipient)
+	req.RefundTo = strings.TrimSpace(req.RefundTo)
+	if problem := addressProblem("recipient", toToken.ChainName, req.Recipient); problem != "" {
+		writeAPIFieldError(w, http.StatusBadRequest, apiCodeInvalidAddress, "recipient", problem)
+		return nil
+	}
+	if problem := addressProblem("refund", fromToken.ChainName, req.RefundTo); problem != "" {
+		writeAPIFieldError(w, http.StatusBadRequest, apiCodeInvalidAddress, "refundTo", problem)
+		return nil
+	}
+
+	s := &apiSwap{fromToken: fromToken, toToken: toToken, slippageBps: req.SlippageBps}
+	if s.slippageBps == 0 {
+		s.slippageBps = 100
+	}
+	if s.slippageBps < 1 || s.slippageBps > 5000 {
+		writeAPIFieldError(w, http.StatusBadRequest, apiCodeInvalidRequest, "slippageBps", "slippageBps must be between 1 and 5000.")
+		return nil
+	}
+
+	// Same precedence as the web form: amount wins if both are set.
+	var err error
+	switch {
+	case req.Amount != "":
+		s.swapType, s.amountToken = "FLEX_INPUT", fromToken
+		s.atomicAmount, err = humanToAtomic(req.Amount, fromToken.Decimals)
+		if err != nil {
+			writeAPIFieldError(w, http.StatusBadRequest, apiCodeInvalidAmount, "amount", "Could not parse the amount: "+err.Error())
+			return nil
+		}
+	case req.AmountOut != "":
+		s.swapType, s.amountToken = "EXACT_OUTPUT", toToken
+		s.atomicAmount, err = humanToAtomic(req.AmountOut, toToken.Decimals)
+		if err != nil {
+			writeAPIFieldError(w, http.StatusBadRequest, apiCodeInvalidAmount, "amountOut", "Could not parse the amount: "+err.Error())
+			return nil
+		}
+	case allowAnyInput:
+		// The amount is only a reference for pricing an ANY_INPUT address.
+		s.swapType, s.amountToken = "ANY_INPUT", fromToken
+		s.atomicAmount, _ = humanToAtomic("1", fromToken.Decimals)
+	default:
+		writeAPIFieldError(w, http.StatusBadRequest, apiCodeInvalidAmount, "amount", "Set amount or amountOut.")
+		return nil
+	}
+	return s
+}
+
+// quoteRequest builds the 1Click request for s. appFees is always empty.
+func (s *apiSwap) quoteRequest(req *APISwapRequest) *QuoteRequest {
+	return &QuoteRequest{
+		SwapType:           s.swapType,
+		SlippageTolerance:  s.slippageBps,
+		OriginAsset:        s.fromToken.DefuseAssetID,
+		DepositType:        "ORIGIN_CHAIN",
+		DestinationAsset:   s.toToken.DefuseAssetID,
+		Amount:             s.atomicAmount,
+		RefundTo:           req.RefundTo,
+		RefundType:         "ORIGIN_CHAIN",
+		Recipient:          req.Recipient,
+		RecipientType:      "DESTINATION_CHAIN",
+		Deadline:           buildDeadline(time.Hour),
+		QuoteWaitingTimeMs: 8000,
+		AppFees:            []struct{}{},
+	}
+}
+
+// registerAPIRoutes adds the JSON API and its documentation to mux.
+func registerAPIRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/", handleAPINotFound)
+	mux.HandleFunc("/api/v1/quote", handleAPIQuote)
+	mux.HandleFunc("/api/v1/orders", handleAPIOrderRoutes)
+	mux.HandleFunc("/api/v1/orders/", handleAPIOrderRoutes)
+	mux.HandleFunc("/api/v1/tokens", handleAPITokens)
+	mux.HandleFunc("/api/openapi.json", handleOpenAPI)
+	mux.HandleFunc("/api/docs", handleAPIDocs)
+}
+
+// handleAPIQuote serves POST /api/v1/quote.
+func handleAPIQuote(w http.ResponseWriter, r *http.Request) {
+	if !apiMethod(w, r, http.MethodPost) || !apiAllow(w, r, "quote") {
+		return
+	}
+	var req APISwapRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+	s := resolveAPISwap(w, &req, false)
+	if s == nil {
+		return
+	}
+
+	dryResp, err := requestDryQuote(r.Context(), s.quoteRequest(&req))
+	if err != nil {
+		writeAPIUpstreamError(w, err, s.amountToken)
+		return
+	}
+	q := &dryResp.Quote
+	if q.AmountOut == "" || q.AmountOut == "0" {
+		writeAPIError(w, http.StatusBadGateway, apiErrNoQuote, "No market makers are currently offering a rate for this pair/amount. Try a larger amount or a different pair.")
+		return
+	}
+
+	humanIn := q.AmountInFormatted
+	if humanIn == "" {
+		humanIn = atomicToHuman(q.AmountIn, s.fromToken.Decimals)
+	}
+	humanOut := q.AmountOutFormatted
+	if humanOut == "" {
+		humanOut = atomicToHuman(q.AmountOut, s.toToken.Decimals)
+	}
+	usd := quoteUSD(s.fromToken, s.toToken, humanIn, humanOut)
+
+	writeAPIJSON(w, http.StatusOK, APIQuote{
+		From:            s.fromToken.Ticker,
+		FromNetwork:     strings.ToLower(s.fromToken.ChainName),
+		To:              s.toToken.Ticker,
+		ToNetwork:       strings.ToLower(s.toToken.ChainName),
+		SwapType:        s.swapType,
+		AmountIn:        humanIn,
+		AmountInAtomic:  q.AmountIn,
+		AmountOut:       humanOut,
+		AmountOutAtomic: q.AmountOut,
+		AmountInUSD:     usd.AmountInUSD,
+		AmountOutUSD:    usd.AmountOutUSD,
+		SpreadUSD:       usd.SpreadUSD,
+		SpreadPct:       usd.SpreadPct,
+		Rate:            usd.Rate,
+		SlippageBps:     s.slippageBps,
+		TimeEstimate:    q.TimeEstimate,
+		AppFees:         []struct{}{},
+	})
+}
+
+// handleAPIOrders serves POST /api/v1/orders.
+func handleAPIOrders(w http.ResponseWriter, r *http.Request) {
+	if !apiMethod(w, r, http.MethodPost) || !apiAllow(w, r, "orders") {
+		return
+	}
+	var req APISwapRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+	if req.Passphrase != "" && len([]rune(req.Passphrase)) < orderLockMinPassphrase {
+		writeAPIFieldError(w, http.StatusBadRequest, apiCodeInvalidRequest, "passphrase", fmt.Sprintf("Choose a passphrase of at least %d characters, or leave it out.", orderLockMinPassphrase))
+		return
+	}
+	s := resolveAPISwap(w, &req, true)
+	if s == nil {
+		return
+	}
+
+	quoteReq := s.quoteRequest(&req)
+	quoteResp, err := requestQuote(r.Context(), quoteReq)
+	if err != nil {
+		writeAPIUpstreamError(w, err, s.amountToken)
+		return
+	}
+	if err := verifyQuoteResponse(quoteReq, quoteResp, s.fromToken); err != nil {
+		log.Printf("api order: %v", err)
+		writeAPIError(w, http.StatusBadGateway, apiCodeQuoteRejected, "The swap provider returned a quote that doesn't match your request, so no deposit address is given. Please try again in a moment.")
+		return
+	}
+
+	order := &OrderData{
+		DepositAddr: quoteResp.Quote.DepositAddress,
+		Memo:        quoteResp.Quote.DepositMemo,
+		FromTicker:  s.fromToken.Ticker,
+		FromNet:     strings.ToLower(s.fromToken.ChainName),
+		ToTicker:    s.toToken.Ticker,
+		ToNet:       strings.ToLower(s.toToken.ChainName),
+		AmountIn:    quoteResp.Quote.AmountInFmt,
+		AmountOut:   quoteResp.Quote.AmountOutFmt,
+		Deadline:    quoteResp.Quote.Deadline,
+		CorrID:      quoteResp.CorrelationID,
+		RefundAddr:  req.RefundTo,
+		RecvAddr:    req.Recipient,
+		SwapType:    s.swapType,
+		Upstream:    quoteResp.endpoint,
+	}
+	if s.swapType == "ANY_INPUT" {
+		order.AmountIn, order.AmountOut = "any", "market rate"
+	}
+
+	var token string
+	var lockKey []byte
+	if req.Passphrase != "" {
+		token, lockKey, err = encryptLockedOrderData(order, req.Passphrase)
+	} else {
+		token, err = encryptOrderData(order)
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, apiCodeInternal, "Failed to create order token.")
+		return
+	}
+	storeAttestation("api", token, lockKey, quoteReq, quoteResp)
+
+	resp := apiOrder(token, order)
+	if lockKey != nil {
+		resp.UnlockKey = encodeUnlockKey(lockKey)
+	}
+	w.Header().Set("Location", "/api/v1/orders/"+token)
+	writeAPIJSON(w, http.StatusCreated, resp)
+}
+
+// handleAPIOrderStatus serves GET /api/v1/orders/{token}. Locked orders
+// show addresses and transactions only with the X-Order-Key header.
+func handleAPIOrderStatus(w http.ResponseWriter, r *http.Request) {
+	if !apiMethod(w, r, http.MethodGet) || !apiAllow(w, r, "status") {
+		return
+	}
+	token := strings.TrimPrefix(r.URL.Path, "/api/v1/orders/")
+	if token == "" || strings.Contains(token, "/") {
+		writeAPIError(w, http.StatusNotFound, apiErrNotFound, "No such endpoint.")
+		return
+	}
+	order, err := decryptOrderData(token)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, apiCodeInvalidOrder, "This order token is invalid or expired. It may have been created on a different server.")
+		return
+	}
+	if order.Locked() {
+		if h := r.Header.Get(apiOrderKeyHeader); h != "" {
+			key := decodeUnlockKey(h)
+			full, err := unlockOrderDataWithKey(order, key)
+			if key == nil || err != nil {
+				writeAPIError(w, http.StatusForbidden, apiCodeOrderLocked, "The "+apiOrderKeyHeader+" header does not unlock this order.")
+				return
+			}
+			order = full
+		}
+	}
+
+	resp := APIOrderStatus{APIOrder: apiOrder(token, order), Status: "UNKNOWN"}
+	if res, err := orderStatuses.get(r.Context(), order); err == nil {
+		resp.Status = normalizeOrderStatus(res.Status.Status)
+		resp.UpdatedAt = res.Status.UpdatedAt
+		if !order.Locked() {
+			resp.SwapDetails = res.Status.SwapDetails
+			if res.Withdrawals != nil {
+				resp.Withdrawals = res.Withdrawals.Withdrawals
+			}
+		}
+	}
+	resp.Terminal = isTerminalStatus(resp.Status)
+	writeAPIJSON(w, http.StatusOK, resp)
+}
+
+// handleAPIOrderRoutes dispatches /api/v1/orders and /api/v1/orders/{token}.
+func handleAPIOrderRoutes(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/api/v1/orders" || r.URL.Path == "/api/v1/orders/" {
+		handleAPIOrders(w, r)
+		return
+	}
+	handleAPIOrderStatus(w, r)
+}
+
+// handleAPITokens serves GET /api/v1/tokens.
+func handleAPITokens(w http.ResponseWriter, r *http.Request) {
+	if !apiMethod(w, r, http.MethodGet) || !apiAllow(w, r, "tokens") {
+		return
+	}
+	tokens, err := getTokens()
+	if err != nil {
+		writeAPIUpstreamError(w, err, nil)
+		return
+	}
+	list := APITokenList{Tokens: make([]APIToken, 0, len(tokens))}
+	for _, t := range tokens {
+		list.Tokens = append(list.Tokens, APIToken{
+			Ticker:          t.Ticker,
+			Name:            t.Name,
+			Network:         strings.ToLower(t.ChainName),
+			AssetID:         t.DefuseAssetID,
+			Decimals:        t.Decimals,
+			PriceUSD:        t.Price,
+			ContractAddress: t.ContractAddress,
+		})
+	}
+	writeAPIJSON(w, http.StatusOK, list)
+}
+
+// handleAPINotFound answers unknown /api/ paths in JSON.
+func handleAPINotFound(w http.ResponseWriter, r *http.Request) {
+	writeAPIError(w, http.StatusNotFound, apiErrNotFound, "No such endpoint.")
+}
+
+// apiOrder describes order, leaving out addresses while it is locked.
+func apiOrder(token string, order *OrderData) APIOrder {
+	o := APIOrder{
+		Token:         token,
+		URL:           "/order/" + token,
+		From:          order.FromTicker,
+		FromNetwork:   order.FromNet,
+		To:            order.ToTicker,
+		ToNetwork:     order.ToNet,
+		SwapType:      order.SwapType,
+		AmountIn:      order.AmountIn,
+		AmountOut:     order.AmountOut,
+		Deadline:      order.Deadline,
+		CorrelationID: order.CorrID,
+		Upstream:      order.Upstream,
+		Locked:        order.Locked(),
+	}
+	if o.SwapType == "" {
+		o.SwapType = "FLEX_INPUT"
+	}
+	if o.SwapType == "ANY_INPUT" {
+		o.AmountIn, o.AmountOut = "", ""
+	}
+	if !o.Locked {
+		o.Deposit = &APIDeposit{Address: order.DepositAddr, Memo: order.Memo}
+		o.Recipient = order.RecvAddr
+		o.RefundTo = order.RefundAddr
+	}
+	return o
+}
+
+// normalizeOrderStatus maps a 1Click status onto the values the API
+// documents. A deposit seen but not yet credited counts as processing.
+func normalizeOrderStatus(s string) string {
+	switch s = strings.ToUpper(s); s {
+	case "PENDING_DEPOSIT", "PROCESSING", "SUCCESS", "REFUNDED", "FAILED", "INCOMPLETE_DEPOSIT":
+		return s
+	case "KNOWN_DEPOSIT_TX":
+		return "PROCESSING"
+	}
+	return "UNKNOWN"
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Quote attestations.
+//
+// After a real quote is placed the server signs what it sent to 1Click and
+// what came back, so a user can later prove the rate they were offered and
+// that no app fee was added — without trusting this server again:
+//
+//	{
+//	  "payload":   {...AttestationPayload, signed as compact JSON...},
+//	  "algorithm": "Ed25519",
+//	  "publicKey": "<hex>",
+//	  "signature": "<base64>"
+//	}
+//
+// The signature covers the compact JSON encoding of payload. Verifiers
+// compact the payload before checking, so re-indenting the file is harmless.
+const attestationType = "uswap-zero/quote-attestation/v1"
+
+// attestationTTL is how long a signed attestation stays downloadable.
+const attestationTTL = 24 * time.Hour
+
+// attestationMaxEntries bounds the in-memory attestation store.
+const attestationMaxEntries = 10000
+
+// attestationKey is the deployment signing key, from ATTESTATION_KEY.
+var attestationKey ed25519.PrivateKey
+
+// AttestationPayload is the signed content of an attestation.
+type AttestationPayload struct {
+	Type          string         `json:"type"`
+	Channel       string         `json:"channel"` // "web", "api" or "telegram"
+	CorrelationID string         `json:"correlationId"`
+	Request       *QuoteRequest  `json:"request"`
+	Response      *QuoteResponse `json:"response"`
+	Upstream      string         `json:"upstream,omitempty"` // 1Click endpoint that answered
+	Commit        string         `json:"commit"`
+	IssuedAt      string         `json:"issuedAt"`
+}
+
+// Attestation is a signed AttestationPayload as served to users.
+type Attestation struct {
+	Payload   json.RawMessage `json:"payload"`
+	Algorithm string          `json:"algorithm"`
+	PublicKey string          `json:"publicKey"`
+	Signature string          `json:"signature"`
+}
+
+// initAttestation loads ATTESTATION_KEY (a 32-byte Ed25519 seed in hex),
+// generating a throwaway key if it is unset.
+func initAttestation() {
+	if seedHex := os.Getenv("ATTESTATION_KEY"); seedHex != "" {
+		seed, err := hex.DecodeString(seedHex)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			log.Fatal("ATTESTATION_KEY must be a 64-character hex string (32-byte Ed25519 seed)")
+		}
+		attestationKey = ed25519.NewKeyFromSeed(seed)
+		return
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		log.Fatal("failed to generate attestation key:", err)
+	}
+	attestationKey = key
+	log.Println("WARNING: ATTESTATION_KEY not set — generated random signing key. Attestations cannot be checked against /verify after restart.")
+}
+
+// attestationPublicKey returns the hex public key published on /verify.
+func attestationPublicKey() string {
+	if attestationKey == nil {
+		return ""
+	}
+	return hex.EncodeToString(attestationKey.Public().(ed25519.PublicKey))
+}
+
+// signAttestation signs a quote exchange and returns the attestation as
+// indented JSON, ready to download.
+func signAttestation(channel string, req *QuoteRequest, resp *QuoteResponse) ([]byte, error) {
+	if attestationKey == nil {
+		return nil, errors.New("attestation key not initialised")
+	}
+	payload, err := json.Marshal(&AttestationPayload{
+		Type:          attestationType,
+		Channel:       channel,
+		CorrelationID: resp.CorrelationID,
+		Request:       req,
+		Response:      resp,
+		Upstream:      resp.endpoint,
+		Commit:        commitHash,
+		IssuedAt:      time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(&Attestation{
+		Payload:   payload,
+		Algorithm: "Ed25519",
+		PublicKey: attestationPublicKey(),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(attestationKey, payload)),
+	}, "", "  ")
+}
+
+// verifyAttestation checks an attestation's signature. If pinnedKey is
+// non-empty it must match the key named in the file.
+func verifyAttestation(doc []byte, pinnedKey string) (*AttestationPayload, error) {
+	var att Attestation
+	if err := json.Unmarshal(doc, &att); err != nil {
+		return nil, fmt.Errorf("not an attestation file: %w", err)
+	}
+	if att.Algorithm != "Ed25519" {
+		return nil, fmt.Errorf("unsupported algorithm %q", att.Algorithm)
+	}
+	pub, err := hex.DecodeString(att.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("malformed public key")
+	}
+	if pinnedKey != "" && !hmac.Equal([]byte(att.PublicKey), []byte(pinnedKey)) {
+		return nil, errors.New("signed by a different key than the one expected")
+	}
+	sig, err := base64.StdEncoding.DecodeString(att.Signature)
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+
+	var signed bytes.Buffer
+	if err := json.Compact(&signed, att.Payload); err != nil {
+		return nil, fmt.Errorf("malformed payload: %w", err)
+	}
+	if !ed25519.Verify(pub, signed.Bytes(), sig) {
+		return nil, errors.New("signature does not match payload")
+	}
+
+	var p AttestationPayload
+	if err := json.Unmarshal(signed.Bytes(), &p); err != nil {
+		return nil, fmt.Errorf("malformed payload: %w", err)
+	}
+	if p.Type != attestationType {
+		return nil, fmt.Errorf("unknown attestation type %q", p.Type)
+	}
+	return &p, nil
+}
+
+// attestationStore keeps recent attestations for download. Entries are
+// sealed under a key derived from the order token (or, for locked orders,
+// the unlock key) and indexed by a hash of it, so memory never holds a
+// readable attestation or anything that links it to an order.
+type attestationStore struct {
+	mu      sync.Mutex
+	entries map[[32]byte]attestationEntry
+}
+
+type attestationEntry struct {
+	sealed  []byte
+	expires time.Time
+}
+
+var attestations = &attestationStore{entries: make(map[[32]byte]attestationEntry)}
+
+func attestationSecrets(secret []byte) (id [32]byte, key []byte) {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("attestation id"))
+	copy(id[:], mac.Sum(nil))
+	mac = hmac.New(sha256.New, secret)
+	mac.Write([]byte("attestation key"))
+	return id, mac.Sum(nil)
+}
+
+// put stores doc for attestationTTL, retrievable with the same secret.
+func (s *attestationStore) put(secret, doc []byte) {
+	id, key := attestationSecrets(secret)
+	gcm, err := orderGCM(key)
+	if err != nil {
+		return
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return
+	}
+	sealed := gcm.Seal(iv, iv, doc, nil)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if len(s.entries) >= attestationMaxEntries {
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+	}
+	if len(s.entries) >= attestationMaxEntries {
+		return
+	}
+	s.entries[id] = attestationEntry{sealed: sealed, expires: now.Add(attestationTTL)}
+}
+
+// get returns the attestation stored under secret, or nil.
+func (s *attestationStore) get(secret []byte) []byte {
+	id, key := attestationSecrets(secret)
+	s.mu.Lock()
+	e, ok := s.entries[id]
+	s.mu.Unlock()
+	if !ok || time.Now().After(e.expires) {
+		return nil
+	}
+	gcm, err := orderGCM(key)
+	if err != nil || len(e.sealed) < gcm.NonceSize() {
+		return nil
+	}
+	doc, err := gcm.Open(nil, e.sealed[:gcm.NonceSize()], e.sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil
+	}
+	return doc
+}
+
+// storeAttestation signs a placed quote and keeps it for download under the
+// order token, or under orderKey for passphrase-locked orders. Failures are
+// logged only: an order must never fail for want of an attestation.
+func storeAttestation(channel, token string, orderKey []byte, req *QuoteRequest, resp *QuoteResponse) []byte {
+	doc, err := signAttestation(channel, req, resp)
+	if err != nil {
+		log.Printf("attestation sign error: %v", err)
+		return nil
+	}
+	secret := []byte(token)
+	if orderKey != nil {
+		secret = orderKey
+	}
+	attestations.put(secret, doc)
+	return doc
+}
+
+// runVerifyAttestation implements `zero verify-attestation file.json`.
+func runVerifyAttestation(args []string) {
+	fs := flag.NewFlagSet("verify-attestation", flag.ExitOnError)
+	pubkey := fs.String("pubkey", "", "expected public key (hex), as shown on /verify")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: zero verify-attestation [-pubkey hex] file.json")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	doc, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	p, err := verifyAttestation(doc, *pubkey)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "INVALID:", err)
+		os.Exit(1)
+	}
+
+	var att Attestation
+	json.Unmarshal(doc, &att)
+	fmt.Println("Signature OK")
+	fmt.Printf("  Signed by     %s\n", att.PublicKey)
+	fmt.Printf("  Issued at     %s (commit %s, %s)\n", p.IssuedAt, p.Commit, p.Channel)
+	fmt.Printf("  Correlation   %s\n", p.CorrelationID)
+	if p.Upstream != "" {
+		fmt.Printf("  Upstream      %s\n", p.Upstream)
+	}
+	if p.Request != nil {
+		fmt.Printf("  Swap          %s  %s -> %s\n", p.Request.SwapType, p.Request.OriginAsset, p.Request.DestinationAsset)
+		fees := "none"
+		if len(p.Request.AppFees) > 0 {
+			fees = fmt.Sprintf("%d entries", len(p.Request.AppFees))
+		}
+		fmt.Printf("  App fees      %s\n", fees)
+	}
+	if p.Response != nil {
+		fmt.Printf("  Quoted        %s in -> %s out\n", p.Response.Quote.AmountInFmt, p.Response.Quote.AmountOutFmt)
+	}
+	if *pubkey == "" {
+		fmt.Fprintln(os.Stderr, "Compare the key above with the one published on the deployment's /verify page, or pass -pubkey.")
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// errCircuitOpen is returned without contacting the upstream while a
+// circuit breaker is open.
+var errCircuitOpen = errors.New("circuit breaker open: upstream marked unavailable")
+
+// circuitBreaker fails fast after a run of consecutive upstream failures.
+//
+//	closed     requests flow; failures are counted
+//	open       requests are rejected until cooldown elapses
+//	half-open  one probe request is let through; its result closes or reopens
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int           // consecutive failures that open the circuit
+	cooldown  time.Duration // how long to stay open before probing
+
+	failures int
+	openedAt time.Time
+	probing  bool
+	trips    int
+	lastErr  string
+	lastFail time.Time
+}
+
+// BreakerStatus is a point-in-time view of a circuitBreaker for /verify.
+type BreakerStatus struct {
+	State     string // "closed", "open" or "half-open"
+	Failures  int
+	Threshold int
+	Trips     int
+	LastError string
+	LastFail  string // time since last failure, empty if none
+	RetryIn   string // time until the next probe while open
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow reports whether a request may proceed. In the half-open state only
+// the first caller gets through; it must report back via success, failure
+// or release.
+func (cb *circuitBreaker) allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.failures < cb.threshold {
+		return true
+	}
+	if time.Since(cb.openedAt) < cb.cooldown || cb.probing {
+		return false
+	}
+	cb.probing = true
+	return true
+}
+
+// success closes the circuit.
+func (cb *circuitBreaker) success() {
+	cb.mu.Lock()
+	cb.failures = 0
+	cb.probing = false
+	cb.mu.Unlock()
+}
+
+// failure records an upstream failure, opening the circuit once the
+// threshold is reached (or immediately if a half-open probe failed).
+// reason is shown publicly on /verify, so it must not contain user data.
+func (cb *circuitBreaker) failure(reason string) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures++
+	cb.lastFail = time.Now()
+	cb.lastErr = reason
+	if cb.probing || cb.failures == cb.threshold {
+		cb.openedAt = time.Now()
+		cb.trips++
+	}
+	cb.probing = false
+}
+
+// release gives up a half-open probe slot without recording a result,
+// e.g. when the caller's context was cancelled.
+func (cb *circuitBreaker) release() {
+	cb.mu.Lock()
+	cb.probing = false
+	cb.mu.Unlock()
+}
+
+// reset returns the breaker to its initial closed state.
+func (cb *circuitBreaker) reset() {
+	cb.mu.Lock()
+	cb.failures, cb.trips, cb.probing = 0, 0, false
+	cb.lastErr, cb.lastFail, cb.openedAt = "", time.Time{}, time.Time{}
+	cb.mu.Unlock()
+}
+
+func (cb *circuitBreaker) status() BreakerStatus {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	st := BreakerStatus{
+		State:     "closed",
+		Failures:  cb.failures,
+		Threshold: cb.threshold,
+		Trips:     cb.trips,
+		LastError: cb.lastErr,
+	}
+	if cb.failures >= cb.threshold {
+		if wait := cb.cooldown - time.Since(cb.openedAt); wait > 0 && !cb.probing {
+			st.State = "open"
+			st.RetryIn = wait.Round(time.Second).String()
+		} else {
+			st.State = "half-open"
+		}
+	}
+	if !cb.lastFail.IsZero() {
+		st.LastFail = time.Since(cb.lastFail).Round(time.Second).String()
+	}
+	return st
+}
@@ -17,24 +17,119 @@ import (
 	"time"
 )
 
+// orderKey is the active key: it seals new order tokens and signs CSRF tokens.
 var orderKey []byte
 
+// orderKeys is the full keyring, active and retired keys by key ID.
+// Key ID 0 is reserved for the legacy single ORDER_SECRET.
+var orderKeys = &orderKeyring{keys: make(map[byte][]byte)}
+
+// orderKeyring maps key IDs to 32-byte AES/HMAC keys.
+type orderKeyring struct {
+	activeID byte
+	keys     map[byte][]byte
+}
+
 func initCrypto() {
-	secretHex := os.Getenv("ORDER_SECRET")
-	if secretHex == "" {
+	ring := &orderKeyring{keys: make(map[byte][]byte)}
+
+	if list := os.Getenv("ORDER_SECRETS"); list != "" {
+		var err error
+		if ring, err = parseOrderSecrets(list); err != nil {
+			log.Fatal("ORDER_SECRETS: ", err)
+		}
+	}
+
+	if secretHex := os.Getenv("ORDER_SECRET"); secretHex != "" {
+		decoded, err := hex.DecodeString(secretHex)
+		if err != nil || len(decoded) < 32 {
+			log.Fatal("ORDER_SECRET must be a 64-character hex string (32 bytes)")
+		}
+		ring.keys[0] = decoded[:32]
+	}
+
+	if len(ring.keys) == 0 {
 		b := make([]byte, 32)
 		if _, err := rand.Read(b); err != nil {
 			log.Fatal("failed to generate random key:", err)
 		}
-		orderKey = b
+		ring.keys[0] = b
 		log.Println("WARNING: ORDER_SECRET not set — generated random key. Tokens will not survive restart.")
-		return
+	} else if len(ring.keys) > 1 {
+		log.Printf("Order keyring: active key %d, %d retired", ring.activeID, len(ring.keys)-1)
+	}
+
+	orderKeys = ring
+	orderKey = ring.keys[ring.activeID]
+}
+
+// parseOrderSecrets parses ORDER_SECRETS: comma-separated "id:hex" entries,
+// newest first. The first entry is the active key; the rest are retired and
+// only used to open existing tokens. IDs are 1-255.
+func parseOrderSecrets(list string) (*orderKeyring, error) {
+	ring := &orderKeyring{keys: make(map[byte][]byte)}
+	for i, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		idStr, secretHex, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("entry %d: want id:hex", i+1)
+		}
+		id, err := strconv.Atoi(idStr)
+		if err != nil || id < 1 || id > 255 {
+			return nil, fmt.Errorf("entry %d: key ID must be 1-255", i+1)
+		}
+		key, err := hex.DecodeString(secretHex)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("key %d: must be a 64-character hex string (32 bytes)", id)
+		}
+		if _, dup := ring.keys[byte(id)]; dup {
+			return nil, fmt.Errorf("key %d: duplicate key ID", id)
+		}
+		if len(ring.keys) == 0 {
+			ring.activeID = byte(id)
+		}
+		ring.keys[byte(id)] = key
+	}
+	if len(ring.keys) == 0 {
+		return nil, fmt.Errorf("no keys")
+	}
+	return ring, nil
+}
+
+// runRotateKey implements `zero rotate-key`: it prints a fresh keyring entry
+// whose ID follows the highest one in the current environment.
+func runRotateKey() {
+	next := 1
+	if list := os.Getenv("ORDER_SECRETS"); list != "" {
+		ring, err := parseOrderSecrets(list)
+		if err != nil {
+			log.Fatal("ORDER_SECRETS: ", err)
+		}
+		for id := range ring.keys {
+			if int(id) >= next {
+				next = int(id) + 1
+			}
+		}
+	}
+	if next > 255 {
+		log.Fatal("ORDER_SECRETS already uses key ID 255 — drop retired keys and restart numbering from 1")
 	}
-	decoded, err := hex.DecodeString(secretHex)
-	if err != nil || len(decoded) < 32 {
-		log.Fatal("ORDER_SECRET must be a 64-character hex string (32 bytes)")
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatal("failed to generate key:", err)
+	}
+	entry := fmt.Sprintf("%d:%s", next, hex.EncodeToString(key))
+
+	fmt.Println(entry)
+	fmt.Fprintln(os.Stderr, "Prepend this entry to ORDER_SECRETS to make it the active key, e.g.")
+	fmt.Fprintf(os.Stderr, "  ORDER_SECRETS=%d:<new>,<existing entries>\n", next)
+	if os.Getenv("ORDER_SECRET") != "" {
+		fmt.Fprintln(os.Stderr, "Keep ORDER_SECRET set until links sealed with it have expired; it stays readable as key 0.")
 	}
-	orderKey = decoded[:32]
 }
 
 // OrderData holds the swap metadata encrypted into the order token.
@@ -52,24 +147,48 @@ type OrderData struct {
 	RefundAddr  string `json:"ra,omitempty"`
 	RecvAddr    string `json:"rca,omitempty"`
 	SwapType    string `json:"st,omitempty"` // FLEX_INPUT, EXACT_OUTPUT, ANY_INPUT (empty = FLEX_INPUT)
+	Upstream    string `json:"u,omitempty"`  // 1Click base URL that issued the quote
+
+	lock *orderLock // set for passphrase-locked orders until unlocked
 }
 
-// encryptOrderData encrypts order data into a base64url token.
-// Format: IV (12 bytes) + GCM tag (16 bytes) + ciphertext → base64url
-func encryptOrderData(data *OrderData) (string, error) {
-	plaintext, err := json.Marshal(data)
+// Order token versions. Versioned tokens start with the version and key ID;
+// both are authenticated as GCM additional data.
+//
+//	legacy: IV (12) | ciphertext+tag                   (no header, any key)
+//	v1:     0x01 | key ID | IV (12) | ciphertext+tag   (JSON plaintext)
+//	v2:     0x02 | key ID | IV (12) | ciphertext+tag   (encodeOrderBinary plaintext)
+//	v3:     0x03 | key ID | IV (12) | ciphertext+tag   (passphrase-locked, see orderlock.go)
+const (
+	orderTokenLegacy byte = 0x00
+	orderTokenV1     byte = 0x01
+	orderTokenV2     byte = 0x02
+)
+
+// orderGCM returns an AES-256-GCM AEAD for key.
+func orderGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
-		return "", fmt.Errorf("marshal order data: %w", err)
+		return nil, fmt.Errorf("create cipher: %w", err)
 	}
-
-	block, err := aes.NewCipher(orderKey)
+	gcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return "", fmt.Errorf("create cipher: %w", err)
+		return nil, fmt.Errorf("create gcm: %w", err)
 	}
+	return gcm, nil
+}
 
-	gcm, err := cipher.NewGCM(block)
+// encryptOrderData encrypts order data into a compact base64url token
+// sealed with the active key. Format: version | key ID | IV | ciphertext+tag
+func encryptOrderData(data *OrderData) (string, error) {
+	return sealOrderToken(orderTokenV2, encodeOrderBinary(data))
+}
+
+// sealOrderToken encrypts plaintext under the active key with a version header.
+func sealOrderToken(version byte, plaintext []byte) (string, error) {
+	gcm, err := orderGCM(orderKey)
 	if err != nil {
-		return "", fmt.Errorf("create gcm: %w", err)
+		return "", err
 	}
 
 	iv := make([]byte, gcm.NonceSize()) // 12 bytes
@@ -77,65 +196,98 @@ func encryptOrderData(data *OrderData) (string, error) {
 		return "", fmt.Errorf("generate iv: %w", err)
 	}
 
-	// Seal appends ciphertext+tag to dst
-	sealed := gcm.Seal(nil, iv, plaintext, nil)
+	header := []byte{version, orderKeys.activeID}
 
-	// Pack: IV + sealed (ciphertext + tag)
-	packed := make([]byte, 0, len(iv)+len(sealed))
+	// Pack: header + IV + sealed (ciphertext + tag)
+	packed := make([]byte, 0, len(header)+len(iv)+len(plaintext)+gcm.Overhead())
+	packed = append(packed, header...)
 	packed = append(packed, iv...)
-	packed = append(packed, sealed...)
+	packed = gcm.Seal(packed, iv, plaintext, header)
 
 	return base64.RawURLEncoding.EncodeToString(packed), nil
 }
 
 // decryptOrderData decrypts a base64url token back to order data.
+// It accepts binary v2 and JSON v1 tokens sealed with any key in the keyring,
+// and legacy unversioned JSON tokens sealed with any key. For passphrase-locked
+// tokens only the public fields are filled in; see unlockOrderData.
 func decryptOrderData(token string) (*OrderData, error) {
 	packed, err := base64.RawURLEncoding.DecodeString(token)
 	if err != nil {
 		return nil, fmt.Errorf("decode base64: %w", err)
 	}
 
-	block, err := aes.NewCipher(orderKey)
+	version, plaintext, err := openOrderToken(packed)
 	if err != nil {
-		return nil, fmt.Errorf("create cipher: %w", err)
+		return nil, err
 	}
 
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, fmt.Errorf("create gcm: %w", err)
+	switch version {
+	case orderTokenV2:
+		return decodeOrderBinary(plaintext)
+	case orderTokenV3Locked:
+		return decodeLockedOrder(plaintext)
 	}
 
-	nonceSize := gcm.NonceSize()
-	if len(packed) < nonceSize+gcm.Overhead() {
-		return nil, fmt.Errorf("token too short")
+	var data OrderData
+	if err := json.Unmarshal(plaintext, &data); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
 	}
+	return &data, nil
+}
 
-	iv := packed[:nonceSize]
-	ciphertext := packed[nonceSize:]
+// openOrderToken authenticates and decrypts a packed token, returning its
+// version (orderTokenLegacy for unversioned tokens) and plaintext.
+func openOrderToken(packed []byte) (byte, []byte, error) {
+	const nonceSize, overhead = 12, 16
 
-	plaintext, err := gcm.Open(nil, iv, ciphertext, nil)
-	if err != nil {
-		return nil, fmt.Errorf("decrypt: %w", err)
+	// A legacy token's first byte is random, so a v1-looking header is only
+	// a hint: fall through to the legacy layout if it doesn't open.
+	if len(packed) >= 2+nonceSize+overhead && packed[0] >= orderTokenV1 && packed[0] <= orderTokenV3Locked {
+		if key, ok := orderKeys.keys[packed[1]]; ok {
+			gcm, err := orderGCM(key)
+			if err != nil {
+				return 0, nil, err
+			}
+			header := packed[:2]
+			iv := packed[2 : 2+nonceSize]
+			if plaintext, err := gcm.Open(nil, iv, packed[2+nonceSize:], header); err == nil {
+				return packed[0], plaintext, nil
+			}
+		}
 	}
 
-	var data OrderData
-	if err := json.Unmarshal(plaintext, &data); err != nil {
-		return nil, fmt.Errorf("unmarshal: %w", err)
+	if len(packed) < nonceSize+overhead {
+		return 0, nil, fmt.Errorf("token too short")
 	}
-	return &data, nil
+	for _, key := range orderKeys.keys {
+		gcm, err := orderGCM(key)
+		if err != nil {
+			return 0, nil, err
+		}
+		if plaintext, err := gcm.Open(nil, packed[:nonceSize], packed[nonceSize:], nil); err == nil {
+			return orderTokenLegacy, plaintext, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("decrypt: no key in the keyring opens this token")
 }
 
-// generateCSRFToken creates a stateless CSRF token using HMAC.
+// generateCSRFToken creates a stateless CSRF token using HMAC with the
+// active key. Format: formID:timestamp:keyID(2 hex)+sig
 func generateCSRFToken(formID string) string {
 	ts := strconv.FormatInt(time.Now().UnixMilli(), 36)
 	payload := formID + ":" + ts
-	mac := hmac.New(sha256.New, orderKey)
+	return payload + ":" + fmt.Sprintf("%02x", orderKeys.activeID) + csrfSig(orderKey, payload)
+}
+
+// csrfSig is the truncated HMAC-SHA256 of payload under key.
+func csrfSig(key []byte, payload string) string {
+	mac := hmac.New(sha256.New, key)
 	mac.Write([]byte(payload))
-	sig := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))[:16]
-	return payload + ":" + sig
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))[:16]
 }
 
-// verifyCSRFToken validates a stateless CSRF token.
+// verifyCSRFToken validates a stateless CSRF token against the keyring.
 func verifyCSRFToken(token, formID string, maxAge time.Duration) bool {
 	parts := strings.SplitN(token, ":", 3)
 	if len(parts) != 3 {
@@ -152,9 +304,17 @@ func verifyCSRFToken(token, formID string, maxAge time.Duration) bool {
 	if time.Since(time.UnixMilli(timestamp)) > maxAge {
 		return false
 	}
-	payload := fid + ":" + ts
-	mac := hmac.New(sha256.New, orderKey)
-	mac.Write([]byte(payload))
-	expected := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))[:16]
-	return hmac.Equal([]byte(sig), []byte(expected))
+	if len(sig) != 18 {
+		return false
+	}
+	id, err := strconv.ParseUint(sig[:2], 16, 8)
+	if err != nil {
+		return false
+	}
+	key, ok := orderKeys.keys[byte(id)]
+	if !ok {
+		return false
+	}
+	expected := csrfSig(key, fid+":"+ts)
+	return hmac.Equal([]byte(sig[2:]), []byte(expected))
 }
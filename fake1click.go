@@ -0,0 +1,583 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fake1Click is an offline stand-in for the NEAR Intents 1Click API.
+// It implements /v0/tokens, /v0/quote (dry and real), /v0/status and
+// /v0/any-input/withdrawals well enough to drive the whole swap lifecycle
+// without network access. Use it from tests via httptest.NewServer, or run
+// `zero fake-1click` and point NEAR_INTENTS_API_URL at it.
+//
+// Nothing here is used by the production server.
+type fake1Click struct {
+	mu       sync.Mutex
+	tokens   []TokenInfo
+	scenario fakeScenario
+	deposits map[string]*fakeDeposit // keyed by deposit address
+	requests int                     // API requests seen (control routes excluded)
+}
+
+// fakeScenario scripts how the fake API behaves.
+type fakeScenario struct {
+	// Flow is the sequence of statuses a deposit walks through, one step per
+	// StepPolls status polls. The last entry is sticky.
+	Flow      []string `json:"flow"`
+	StepPolls int      `json:"stepPolls"`
+
+	// FailBurst makes this many consecutive API requests return 503.
+	// With FailEvery == 0 the burst happens once at startup; otherwise it
+	// repeats every FailEvery requests.
+	FailBurst int `json:"failBurst"`
+	FailEvery int `json:"failEvery"`
+
+	// QuoteDelayMs delays every /v0/quote response.
+	QuoteDelayMs int `json:"quoteDelayMs"`
+
+	// NoLiquidity makes every quote come back with a zero output amount.
+	NoLiquidity bool `json:"noLiquidity"`
+
+	// MinAmountUSD rejects quotes worth less than this with the API's
+	// "Amount is too low" error.
+	MinAmountUSD float64 `json:"minAmountUsd"`
+
+	// Tamper makes real quotes misbehave like a compromised upstream:
+	// "recipient", "refund", "asset", "amount", "deadline", "deposit" or
+	// "signature".
+	Tamper string `json:"tamper"`
+}
+
+// fakeDeposit tracks one deposit address handed out by a real quote.
+type fakeDeposit struct {
+	CorrID  string
+	Request QuoteRequest
+	Quote   QuoteDetail
+	Polls   int
+}
+
+// fakeScenarios are the named presets accepted by `zero fake-1click -scenario`.
+var fakeScenarios = map[string]fakeScenario{
+	"success": {Flow: []string{"PENDING_DEPOSIT", "PROCESSING", "SUCCESS"}},
+	"refund":  {Flow: []string{"PENDING_DEPOSIT", "PROCESSING", "REFUNDED"}},
+	"flaky":   {Flow: []string{"PENDING_DEPOSIT", "PROCESSING", "SUCCESS"}, FailBurst: 2, FailEvery: 5},
+	"slow":    {Flow: []string{"PENDING_DEPOSIT", "PROCESSING", "SUCCESS"}, QuoteDelayMs: 12000},
+	"empty":   {Flow: []string{"PENDING_DEPOSIT"}, NoLiquidity: true},
+}
+
+// fakeTokens is the token list served by the fake API.
+// Prices are fixed so quotes are deterministic.
+var fakeTokens = []TokenInfo{
+	{DefuseAssetID: "nep141:eth.omft.near", Symbol: "ETH", Decimals: 18, ChainName: "eth", Price: 3000},
+	{DefuseAssetID: "nep141:eth-0xdac17f958d2ee523a2206206994597c13d831ec7.omft.near", Symbol: "USDT", Decimals: 6, ChainName: "eth", Price: 1, ContractAddress: "0xdac17f958d2ee523a2206206994597c13d831ec7"},
+	{DefuseAssetID: "nep141:eth-0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48.omft.near", Symbol: "USDC", Decimals: 6, ChainName: "eth", Price: 1, ContractAddress: "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"},
+	{DefuseAssetID: "nep141:base.omft.near", Symbol: "ETH", Decimals: 18, ChainName: "base", Price: 3000},
+	{DefuseAssetID: "nep141:arb.omft.near", Symbol: "ETH", Decimals: 18, ChainName: "arb", Price: 3000},
+	{DefuseAssetID: "nep141:btc.omft.near", Symbol: "BTC", Decimals: 8, ChainName: "btc", Price: 60000},
+	{DefuseAssetID: "nep141:sol.omft.near", Symbol: "SOL", Decimals: 9, ChainName: "sol", Price: 150},
+	{DefuseAssetID: "nep141:wrap.near", Symbol: "NEAR", Decimals: 24, ChainName: "near", Price: 3},
+	{DefuseAssetID: "nep245:v2_1.omni.hot.tg:1117_", Symbol: "TON", Decimals: 9, ChainName: "ton", Price: 5},
+	{DefuseAssetID: "nep141:tron.omft.near", Symbol: "TRX", Decimals: 6, ChainName: "tron", Price: 0.12},
+	{DefuseAssetID: "nep141:tron-d28a265909efecdcee7c5028585214ea0b96f015.omft.near", Symbol: "USDT", Decimals: 6, ChainName: "tron", Price: 1},
+	{DefuseAssetID: "nep141:doge.omft.near", Symbol: "DOGE", Decimals: 8, ChainName: "doge", Price: 0.15},
+	{DefuseAssetID: "nep141:xrp.omft.near", Symbol: "XRP", Decimals: 6, ChainName: "xrp", Price: 0.6},
+}
+
+// fakeQuoteKey signs the fake's quotes. It is fixed so the server can be
+// configured with its public key (see fakeQuotePublicKey).
+var fakeQuoteKey = func() ed25519.PrivateKey {
+	seed := sha256.Sum256([]byte("zero fake-1click quote key"))
+	return ed25519.NewKeyFromSeed(seed[:])
+}()
+
+func fakeQuotePublicKey() string {
+	return "ed25519:" + base58Encode(fakeQuoteKey.Public().(ed25519.PublicKey))
+}
+
+// newFake1Click returns a fake API with the default token list.
+func newFake1Click(sc fakeScenario) *fake1Click {
+	f := &fake1Click{
+		tokens:   append([]TokenInfo(nil), fakeTokens...),
+		deposits: make(map[string]*fakeDeposit),
+	}
+	f.setScenario(sc)
+	return f
+}
+
+// setScenario replaces the active scenario and resets request counters.
+// Existing deposits are kept so in-flight orders continue.
+func (f *fake1Click) setScenario(sc fakeScenario) {
+	if len(sc.Flow) == 0 {
+		sc.Flow = fakeScenarios["success"].Flow
+	}
+	if sc.StepPolls < 1 {
+		sc.StepPolls = 1
+	}
+	f.mu.Lock()
+	f.scenario = sc
+	f.requests = 0
+	f.mu.Unlock()
+}
+
+func (f *fake1Click) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/_fake/") {
+		f.handleControl(w, r)
+		return
+	}
+
+	f.mu.Lock()
+	n := f.requests
+	f.requests++
+	sc := f.scenario
+	f.mu.Unlock()
+
+	if sc.FailBurst > 0 {
+		failing := n < sc.FailBurst
+		if sc.FailEvery > 0 {
+			failing = n%sc.FailEvery < sc.FailBurst
+		}
+		if failing {
+			fakeJSON(w, http.StatusServiceUnavailable, map[string]string{"message": "Service temporarily unavailable"})
+			return
+		}
+	}
+
+	switch r.URL.Path {
+	case "/v0/tokens":
+		f.handleTokens(w, r)
+	case "/v0/quote":
+		if sc.QuoteDelayMs > 0 {
+			select {
+			case <-time.After(time.Duration(sc.QuoteDelayMs) * time.Millisecond):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		f.handleQuote(w, r, sc)
+	case "/v0/status":
+		f.handleStatus(w, r, sc)
+	case "/v0/any-input/withdrawals":
+		f.handleWithdrawals(w, r, sc)
+	default:
+		fakeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+	}
+}
+
+// handleControl serves the scripting endpoints:
+//
+//	GET  /_fake/scenario   current scenario as JSON
+//	POST /_fake/scenario   replace scenario (JSON body, or ?name=<preset>)
+//	POST /_fake/reset      forget all deposits and counters
+func (f *fake1Click) handleControl(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/_fake/scenario" && r.Method == http.MethodGet:
+		f.mu.Lock()
+		sc := f.scenario
+		f.mu.Unlock()
+		fakeJSON(w, http.StatusOK, sc)
+	case r.URL.Path == "/_fake/scenario" && r.Method == http.MethodPost:
+		var sc fakeScenario
+		if name := r.URL.Query().Get("name"); name != "" {
+			preset, ok := fakeScenarios[name]
+			if !ok {
+				fakeJSON(w, http.StatusBadRequest, map[string]string{"message": "unknown scenario " + name})
+				return
+			}
+			sc = preset
+		} else if err := json.NewDecoder(r.Body).Decode(&sc); err != nil {
+			fakeJSON(w, http.StatusBadRequest, map[string]string{"message": "bad scenario: " + err.Error()})
+			return
+		}
+		f.setScenario(sc)
+		fakeJSON(w, http.StatusOK, sc)
+	case r.URL.Path == "/_fake/reset" && r.Method == http.MethodPost:
+		f.mu.Lock()
+		f.deposits = make(map[string]*fakeDeposit)
+		f.requests = 0
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		fakeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+	}
+}
+
+func (f *fake1Click) handleTokens(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		fakeJSON(w, http.StatusMethodNotAllowed, map[string]string{"message": "Method Not Allowed"})
+		return
+	}
+	fakeJSON(w, http.StatusOK, f.tokens)
+}
+
+func (f *fake1Click) tokenByAssetID(id string) *TokenInfo {
+	for i := range f.tokens {
+		if f.tokens[i].DefuseAssetID == id {
+			return &f.tokens[i]
+		}
+	}
+	return nil
+}
+
+func (f *fake1Click) handleQuote(w http.ResponseWriter, r *http.Request, sc fakeScenario) {
+	if r.Method != http.MethodPost {
+		fakeJSON(w, http.StatusMethodNotAllowed, map[string]string{"message": "Method Not Allowed"})
+		return
+	}
+
+	var req QuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		fakeJSON(w, http.StatusBadRequest, map[string]string{"message": "Invalid JSON body"})
+		return
+	}
+
+	from := f.tokenByAssetID(req.OriginAsset)
+	to := f.tokenByAssetID(req.DestinationAsset)
+	switch {
+	case from == nil:
+		fakeJSON(w, http.StatusBadRequest, map[string]string{"message": "originAsset is not valid"})
+		return
+	case to == nil:
+		fakeJSON(w, http.StatusBadRequest, map[string]string{"message": "destinationAsset is not valid"})
+		return
+	case req.Recipient == "":
+		fakeJSON(w, http.StatusBadRequest, map[string]string{"message": "recipient should not be empty"})
+		return
+	case req.RefundTo == "":
+		fakeJSON(w, http.StatusBadRequest, map[string]string{"message": "refundTo should not be empty"})
+		return
+	}
+	amount, ok := new(big.Int).SetString(req.Amount, 10)
+	if !ok || amount.Sign() <= 0 {
+		fakeJSON(w, http.StatusBadRequest, map[string]string{"message": "amount must be a positive integer string"})
+		return
+	}
+
+	// Price the swap from the fixed token prices with a 0.1% spread.
+	var amountIn, amountOut string
+	switch req.SwapType {
+	case "EXACT_OUTPUT":
+		amountOut = req.Amount
+		amountIn = fakeConvert(amountOut, to, from, 1.001)
+	default:
+		amountIn = req.Amount
+		amountOut = fakeConvert(amountIn, from, to, 0.999)
+	}
+	if sc.NoLiquidity {
+		amountOut = "0"
+	}
+
+	detail := QuoteDetail{
+		AmountIn:     amountIn,
+		AmountInFmt:  atomicToHuman(amountIn, from.Decimals),
+		AmountOut:    amountOut,
+		AmountOutFmt: atomicToHuman(amountOut, to.Decimals),
+		TimeEstimate: 60,
+	}
+	inUSD := fakeUSD(amountIn, from)
+	outUSD := fakeUSD(amountOut, to)
+
+	if usd, _ := strconv.ParseFloat(inUSD, 64); sc.MinAmountUSD > 0 && usd < sc.MinAmountUSD {
+		amountToken := from
+		if req.SwapType == "EXACT_OUTPUT" {
+			amountToken = to
+		}
+		min, _ := humanToAtomic(strconv.FormatFloat(sc.MinAmountUSD/amountToken.Price, 'f', amountToken.Decimals, 64), amountToken.Decimals)
+		fakeJSON(w, http.StatusBadRequest, map[string]string{"message": "Amount is too low for bridge, try at least " + min})
+		return
+	}
+
+	if req.Dry {
+		fakeJSON(w, http.StatusOK, map[string]interface{}{
+			"correlationId": fakeRandomHex(16),
+			"timestamp":     time.Now().UTC().Format(time.RFC3339),
+			"quoteRequest":  req,
+			"quote": map[string]interface{}{
+				"amountIn":           detail.AmountIn,
+				"amountInFormatted":  detail.AmountInFmt,
+				"amountInUsd":        inUSD,
+				"amountOut":          detail.AmountOut,
+				"amountOutFormatted": detail.AmountOutFmt,
+				"amountOutUsd":       outUSD,
+				"minAmountOut":       detail.AmountOut,
+				"timeEstimate":       detail.TimeEstimate,
+			},
+		})
+		return
+	}
+
+	if sc.NoLiquidity {
+		fakeJSON(w, http.StatusBadRequest, map[string]string{"message": "Failed to get quote"})
+		return
+	}
+
+	detail.DepositAddress = fakeDepositAddress(from.ChainName)
+	if from.ChainName == "ton" || from.ChainName == "xrp" {
+		detail.DepositMemo = strconv.Itoa(100000 + int(time.Now().UnixNano()%900000))
+	}
+	detail.Deadline = req.Deadline
+	if detail.Deadline == "" {
+		detail.Deadline = buildDeadline(time.Hour)
+	}
+	if req.SwapType == "ANY_INPUT" {
+		detail.AmountIn, detail.AmountInFmt = "", ""
+	}
+
+	corrID := fakeRandomHex(16)
+	f.mu.Lock()
+	f.deposits[detail.DepositAddress] = &fakeDeposit{CorrID: corrID, Request: req, Quote: detail}
+	f.mu.Unlock()
+
+	echo := req
+	switch sc.Tamper {
+	case "recipient":
+		echo.Recipient = "0x000000000000000000000000000000000000bEEF"
+	case "refund":
+		echo.RefundTo = "0x000000000000000000000000000000000000bEEF"
+	case "asset":
+		echo.DestinationAsset = "nep141:wrap.near"
+	case "amount":
+		detail.AmountIn = "1" + detail.AmountIn
+	case "deadline":
+		detail.Deadline = buildDeadline(72 * time.Hour)
+	case "deposit":
+		detail.DepositAddress = "not an address"
+	}
+
+	// Sign the quote object exactly as it goes on the wire.
+	quoteJSON, _ := json.Marshal(detail)
+	sig := ed25519.Sign(fakeQuoteKey, quoteJSON)
+	if sc.Tamper == "signature" {
+		sig[0] ^= 1
+	}
+
+	fakeJSON(w, http.StatusCreated, map[string]interface{}{
+		"correlationId": corrID,
+		"timestamp":     time.Now().UTC().Format(time.RFC3339),
+		"signature":     "ed25519:" + base58Encode(sig),
+		"quoteRequest":  echo,
+		"quote":         json.RawMessage(quoteJSON),
+	})
+}
+
+func (f *fake1Click) handleStatus(w http.ResponseWriter, r *http.Request, sc fakeScenario) {
+	addr := r.URL.Query().Get("depositAddress")
+	f.mu.Lock()
+	dep, ok := f.deposits[addr]
+	var status string
+	if ok {
+		step := dep.Polls / sc.StepPolls
+		if step >= len(sc.Flow) {
+			step = len(sc.Flow) - 1
+		}
+		status = sc.Flow[step]
+		dep.Polls++
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		fakeJSON(w, http.StatusNotFound, map[string]string{"message": "Deposit address " + addr + " not found"})
+		return
+	}
+
+	resp := map[string]interface{}{
+		"correlationId": dep.CorrID,
+		"status":        status,
+		"updatedAt":     time.Now().UTC().Format(time.RFC3339),
+	}
+	if details := fakeSwapDetails(dep, status); details != nil {
+		resp["swapDetails"] = details
+	}
+	fakeJSON(w, http.StatusOK, resp)
+}
+
+// fakeSwapDetails fills in the tx hashes a real status response would carry
+// once funds have moved.
+func fakeSwapDetails(dep *fakeDeposit, status string) *SwapDetails {
+	switch status {
+	case "PENDING_DEPOSIT":
+		return nil
+	case "SUCCESS":
+		return &SwapDetails{
+			AmountIn:     dep.Quote.AmountIn,
+			AmountInFmt:  dep.Quote.AmountInFmt,
+			AmountOut:    dep.Quote.AmountOut,
+			AmountOutFmt: dep.Quote.AmountOutFmt,
+			OriginTxs:    []TransactionDetail{{Hash: "0x" + fakeRandomHex(32), ExplorerURL: "https://example.invalid/tx/origin"}},
+			DestTxs:      []TransactionDetail{{Hash: "0x" + fakeRandomHex(32), ExplorerURL: "https://example.invalid/tx/dest"}},
+		}
+	case "REFUNDED":
+		return &SwapDetails{
+			AmountIn:       dep.Quote.AmountIn,
+			AmountInFmt:    dep.Quote.AmountInFmt,
+			OriginTxs:      []TransactionDetail{{Hash: "0x" + fakeRandomHex(32), ExplorerURL: "https://example.invalid/tx/refund"}},
+			RefundedAmount: dep.Quote.AmountIn,
+			RefundReason:   "Price moved beyond slippage tolerance",
+		}
+	default:
+		return &SwapDetails{
+			AmountIn:    dep.Quote.AmountIn,
+			AmountInFmt: dep.Quote.AmountInFmt,
+			OriginTxs:   []TransactionDetail{{Hash: "0x" + fakeRandomHex(32), ExplorerURL: "https://example.invalid/tx/origin"}},
+		}
+	}
+}
+
+func (f *fake1Click) handleWithdrawals(w http.ResponseWriter, r *http.Request, sc fakeScenario) {
+	addr := r.URL.Query().Get("depositAddress")
+	f.mu.Lock()
+	dep, ok := f.deposits[addr]
+	var status string
+	if ok {
+		step := dep.Polls / sc.StepPolls
+		if step >= len(sc.Flow) {
+			step = len(sc.Flow) - 1
+		}
+		status = sc.Flow[step]
+	}
+	f.mu.Unlock()
+
+	if !ok || dep.Request.SwapType != "ANY_INPUT" {
+		fakeJSON(w, http.StatusNotFound, map[string]string{"message": "Deposit address " + addr + " not found"})
+		return
+	}
+
+	resp := AnyInputWithdrawalsResponse{Withdrawals: []AnyInputWithdrawal{}}
+	if status == "SUCCESS" {
+		resp.Withdrawals = append(resp.Withdrawals, AnyInputWithdrawal{
+			Status:             "SUCCESS",
+			AmountOut:          dep.Quote.AmountOut,
+			AmountOutFormatted: dep.Quote.AmountOutFmt,
+			Timestamp:          time.Now().UTC().Format(time.RFC3339),
+			Hash:               "0x" + fakeRandomHex(32),
+		})
+	}
+	fakeJSON(w, http.StatusOK, resp)
+}
+
+// fakeConvert converts an atomic amount of one token into another at the
+// fixed fake prices, multiplied by factor.
+func fakeConvert(atomic string, from, to *TokenInfo, factor float64) string {
+	if from.Price == 0 || to.Price == 0 {
+		return "0"
+	}
+	in, _ := new(big.Float).SetString(atomicToHuman(atomic, from.Decimals))
+	if in == nil {
+		return "0"
+	}
+	out := new(big.Float).Mul(in, big.NewFloat(from.Price*factor/to.Price))
+	result, err := humanToAtomic(out.Text('f', to.Decimals), to.Decimals)
+	if err != nil {
+		return "0"
+	}
+	return result
+}
+
+// fakeUSD returns the USD value of an atomic amount as a decimal string.
+func fakeUSD(atomic string, t *TokenInfo) string {
+	human, _ := strconv.ParseFloat(atomicToHuman(atomic, t.Decimals), 64)
+	return strconv.FormatFloat(human*t.Price, 'f', 2, 64)
+}
+
+// fakeDepositAddress returns a random deposit address that passes the
+// chain's address validation (see addrvalidate.go).
+func fakeDepositAddress(chain string) string {
+	switch chain {
+	case "btc":
+		return segwitEncode("bc", 0, fakeRandomBytes(20))
+	case "sol":
+		return base58Encode(fakeRandomBytes(32))
+	case "near":
+		return fakeRandomHex(32)
+	case "ton":
+		return tonEncode(false, fakeRandomBytes(32))
+	case "tron":
+		return base58CheckEncode(append([]byte{0x41}, fakeRandomBytes(20)...))
+	case "doge":
+		return base58CheckEncode(append([]byte{0x1e}, fakeRandomBytes(20)...))
+	case "xrp":
+		return xrpEncode(fakeRandomBytes(20))
+	default:
+		return eip55Checksum(fakeRandomHex(20))
+	}
+}
+
+func fakeRandomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+	return b
+}
+
+func fakeRandomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func fakeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// runFake1Click implements the `zero fake-1click` subcommand.
+func runFake1Click(args []string) {
+	fs := flag.NewFlagSet("fake-1click", flag.ExitOnError)
+	addr := fs.String("addr", ":4000", "listen address")
+	name := fs.String("scenario", "success", "preset: success, refund, flaky, slow, empty")
+	flow := fs.String("flow", "", "comma-separated status flow, overrides the preset")
+	stepPolls := fs.Int("step-polls", 0, "status polls per flow step (default 1)")
+	failBurst := fs.Int("fail-burst", -1, "consecutive 503 responses per burst")
+	failEvery := fs.Int("fail-every", -1, "repeat the 503 burst every N requests (0 = once)")
+	quoteDelay := fs.Duration("quote-delay", -1, "delay before every /v0/quote response")
+	noLiquidity := fs.Bool("no-liquidity", false, "return zero output for every quote")
+	tamper := fs.String("tamper", "", "corrupt real quotes: recipient, refund, asset, amount, deadline, deposit, signature")
+	fs.Parse(args)
+
+	sc, ok := fakeScenarios[*name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown scenario %q\n", *name)
+		os.Exit(2)
+	}
+	if *flow != "" {
+		sc.Flow = strings.Split(*flow, ",")
+	}
+	if *stepPolls > 0 {
+		sc.StepPolls = *stepPolls
+	}
+	if *failBurst >= 0 {
+		sc.FailBurst = *failBurst
+	}
+	if *failEvery >= 0 {
+		sc.FailEvery = *failEvery
+	}
+	if *quoteDelay >= 0 {
+		sc.QuoteDelayMs = int(quoteDelay.Milliseconds())
+	}
+	if *noLiquidity {
+		sc.NoLiquidity = true
+	}
+	if *tamper != "" {
+		sc.Tamper = *tamper
+	}
+
+	log.Printf("fake 1Click API listening on %s (scenario %s)", *addr, *name)
+	log.Printf("run the server with NEAR_INTENTS_API_URL=http://localhost%s", *addr)
+	log.Printf("quotes are signed with NEAR_INTENTS_QUOTE_PUBKEY=%s", fakeQuotePublicKey())
+	if err := http.ListenAndServe(*addr, newFake1Click(sc)); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"html/template"
 	"log"
 	"math"
 	"net/http"
@@ -58,22 +59,36 @@ func tokenColorPair(ticker string) (string, string) {
 
 // PageData is the base data passed to every template.
 type PageData struct {
-	Title       string
-	Error       string
-	MetaRefresh int // seconds; 0 = no refresh
-	FromColor   string
-	FromColorA  string
-	ToColor     string
-	ToColorA    string
-	CommitHash  string
-	BuildTime   string
-	BuildLogURL string
-	OnionURL    string
-}
-
-func newPageData(title string) PageData {
+	Title           string
+	Error           string
+	MetaRefresh     int  // seconds; 0 = no refresh
+	NoscriptRefresh bool // MetaRefresh only without JavaScript; the page updates itself
+	FromColor       string
+	FromColorA      string
+	ToColor         string
+	ToColorA        string
+	CommitHash      string
+	BuildTime       string
+	BuildLogURL     string
+	OnionURL        string
+
+	// Inside the Telegram Mini App (see tgapp.go): App prefixes the app's
+	// own links and forms, TGApp carries the signed launch data.
+	App   string
+	TGApp *TGAppPage
+
+	// Language of the page (see i18n.go). LangParam is the ?lang= choice
+	// that links and forms carry along; empty when negotiated.
+	Loc       *locale
+	LangParam string
+	Langs     []LangLink
+}
+
+// newPageData starts a page for r in its language, with title translated.
+func newPageData(r *http.Request, title string) PageData {
+	loc, chosen := requestLocale(r)
 	return PageData{
-		Title:       title,
+		Title:       loc.T(title),
 		FromColor:   "#ffffff",
 		FromColorA:  "255, 255, 255",
 		ToColor:     "#ffffff",
@@ -82,7 +97,36 @@ func newPageData(title string) PageData {
 		BuildTime:   buildTime,
 		BuildLogURL: buildLogURL,
 		OnionURL:    onionURL,
+		Loc:         loc,
+		LangParam:   chosen,
+		Langs:       langLinks(r, loc),
+	}
+}
+
+// T translates msg into the page's language; see locale.T.
+func (p PageData) T(msg string, args ...interface{}) string {
+	return p.Loc.T(msg, args...)
+}
+
+// N translates a counted message into the page's language; see locale.N.
+func (p PageData) N(msg string, n int, args ...interface{}) string {
+	return p.Loc.N(msg, n, args...)
+}
+
+// HTML translates a message that carries its own markup, such as a
+// paragraph with links. The catalog is trusted; string args are escaped.
+func (p PageData) HTML(msg string, args ...interface{}) template.HTML {
+	for i, a := range args {
+		if s, ok := a.(string); ok {
+			args[i] = template.HTMLEscapeString(s)
+		}
 	}
+	return template.HTML(p.Loc.T(msg, args...))
+}
+
+// Link adds the page's ?lang= choice to a local URL.
+func (p PageData) Link(u string) string {
+	return withLang(u, p.LangParam)
 }
 
 // SwapPageData is the data for the swap form page.
@@ -104,6 +148,10 @@ type SwapPageData struct {
 	ModalOpen  string // "from" or "to" if a modal should be open
 	FromToken  *TokenInfo
 	ToToken    *TokenInfo
+
+	// Inline validation errors when /quote sends the form back
+	RecipientError string
+	RefundError    string
 }
 
 // QuotePageData is the data for the quote preview page.
@@ -147,6 +195,11 @@ type OrderPageData struct {
 	IsTerminal    bool
 	StatusStep    int // 0=pending, 1=processing, 2=complete
 	Withdrawals   *AnyInputWithdrawalsResponse
+	Locked        bool   // passphrase-locked and not unlocked: hide addresses
+	UnlockKey     string // set when unlocked in this request
+	UnlockError   string
+	Attestation   bool // a signed quote attestation can be downloaded
+	Telegram      bool // offer "Continue in Telegram"
 }
 
 // CurrenciesPageData is the data for the currencies list page.
@@ -157,7 +210,12 @@ type CurrenciesPageData struct {
 	Search     string
 }
 
-func renderError(w http.ResponseWriter, status int, title, message, action, actionURL string) {
+// renderError renders the error page in r's language. title, message and
+// action are English messages; a message that was formatted already is
+// shown as is.
+func renderError(w http.ResponseWriter, r *http.Request, status int, title, message, action, actionURL string) {
+	page := newPageData(r, title)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(status)
 	templates.ExecuteTemplate(w, "error.html", struct {
 		PageData
@@ -165,13 +223,24 @@ func renderError(w http.ResponseWriter, status int, title, message, action, acti
 		Action    string
 		ActionURL string
 	}{
-		PageData:  newPageData(title),
-		Message:   message,
-		Action:    action,
-		ActionURL: actionURL,
+		PageData:  page,
+		Message:   page.T(message),
+		Action:    page.T(action),
+		ActionURL: page.Link(actionURL),
 	})
 }
 
+// renderAPIError renders a failed 1Click call with a message specific to the
+// failure (see describeAPIError).
+func renderAPIError(w http.ResponseWriter, r *http.Request, err error, title string, amountToken *TokenInfo) {
+	status, message := describeAPIError(err, amountToken)
+	action := "Go Back"
+	if status >= 500 {
+		action = "Try Again"
+	}
+	renderError(w, r, status, title, message, action, "/")
+}
+
 func clientIP(r *http.Request) string {
 	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
 		parts := strings.SplitN(xff, ",", 2)
@@ -186,27 +255,30 @@ func clientIP(r *http.Request) string {
 // handleSwap renders the main swap form.
 func handleSwap(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
-		renderError(w, 404, "Not Found", "Page not found.", "Back to Home", "/")
+		renderError(w, r, 404, "Not Found", "Page not found.", "Back to Home", "/")
 		return
 	}
 
-	networks, _ := getNetworkGroups()
+	renderSwapPage(w, http.StatusOK, swapPageFromQuery(r))
+}
 
+// swapPageFromQuery fills the swap form from r's query parameters.
+func swapPageFromQuery(r *http.Request) SwapPageData {
+	q := r.URL.Query()
 	data := SwapPageData{
-		PageData:   newPageData("uSwap Zero"),
-		From:       r.URL.Query().Get("from"),
-		FromNet:    r.URL.Query().Get("from_net"),
-		To:         r.URL.Query().Get("to"),
-		ToNet:      r.URL.Query().Get("to_net"),
-		Amount:     r.URL.Query().Get("amt"),
-		AmountOut:  r.URL.Query().Get("amt_out"),
-		Recipient:  r.URL.Query().Get("recipient"),
-		Slippage:   r.URL.Query().Get("slippage"),
+		PageData:   newPageData(r, "uSwap Zero"),
+		From:       q.Get("from"),
+		FromNet:    q.Get("from_net"),
+		To:         q.Get("to"),
+		ToNet:      q.Get("to_net"),
+		Amount:     q.Get("amt"),
+		AmountOut:  q.Get("amt_out"),
+		Recipient:  q.Get("recipient"),
+		Slippage:   q.Get("slippage"),
 		CSRFToken:  generateCSRFToken("quote"),
-		Networks:   networks,
-		SearchFrom: r.URL.Query().Get("search_from"),
-		SearchTo:   r.URL.Query().Get("search_to"),
-		ModalOpen:  r.URL.Query().Get("modal"),
+		SearchFrom: q.Get("search_from"),
+		SearchTo:   q.Get("search_to"),
+		ModalOpen:  q.Get("modal"),
 	}
 
 	// Defaults
@@ -221,6 +293,14 @@ func handleSwap(w http.ResponseWriter, r *http.Request) {
 	if data.Slippage == "" {
 		data.Slippage = "1"
 	}
+	return data
+}
+
+// renderSwapPage fills in the token and network details for the swap form
+// and renders it.
+func renderSwapPage(w http.ResponseWriter, status int, data SwapPageData) {
+	networks, _ := getNetworkGroups()
+	data.Networks = networks
 
 	// Set accent colors from selected currencies
 	data.FromColor, data.FromColorA = tokenColorPair(data.From)
@@ -241,6 +321,7 @@ func handleSwap(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
 	templates.ExecuteTemplate(w, "swap.html", data)
 }
 
@@ -256,13 +337,13 @@ func handleQuote(w http.ResponseWriter, r *http.Request) {
 	// Rate limit
 	ip := clientIP(r)
 	if !limiter.allow(ip, 30, time.Minute) {
-		renderError(w, 429, "Too Many Requests", "Please wait a moment before trying again.", "Back to Home", "/")
+		renderError(w, r, 429, "Too Many Requests", "Please wait a moment before trying again.", "Back to Home", "/")
 		return
 	}
 
 	// CSRF check
 	if !verifyCSRFToken(r.FormValue("csrf"), "quote", time.Hour) {
-		renderError(w, 403, "Invalid Request", "Form expired. Please go back and try again.", "Back to Home", "/")
+		renderError(w, r, 403, "Invalid Request", "Form expired. Please go back and try again.", "Back to Home", "/")
 		return
 	}
 
@@ -276,24 +357,43 @@ func handleQuote(w http.ResponseWriter, r *http.Request) {
 	refundAddr := strings.TrimSpace(r.FormValue("refund_addr"))
 	slippage := r.FormValue("slippage")
 
-	// Validation (amount is optional — determines swap type)
-	var errors []string
-	if recipient == "" {
-		errors = append(errors, "Recipient address is required")
-	}
-	if refundAddr == "" {
-		errors = append(errors, "Refund address is required")
-	}
-	if len(errors) > 0 {
-		renderError(w, 400, "Validation Error", "Please check your input:\n"+strings.Join(errors, "\n"), "Go Back", "/")
-		return
-	}
-
 	// Find tokens
 	fromToken := findToken(fromTicker, fromNet)
 	toToken := findToken(toTicker, toNet)
 	if fromToken == nil || toToken == nil {
-		renderError(w, 400, "Unknown Token", "Could not find the selected tokens. Try selecting them again.", "Go Back", "/")
+		renderError(w, r, 400, "Unknown Token", "Could not find the selected tokens. Try selecting them again.", "Go Back", "/")
+		return
+	}
+
+	// Validate addresses for their chains before any quote is requested:
+	// the recipient is on the destination chain, the refund on the origin.
+	// Amount is optional — it determines the swap type.
+	loc, lang := requestLocale(r)
+	recipientErr, refundErr := loc.T("Recipient address is required."), loc.T("Refund address is required.")
+	if recipient != "" {
+		recipientErr = addressProblem("recipient", toToken.ChainName, recipient)
+	}
+	if refundAddr != "" {
+		refundErr = addressProblem("refund", fromToken.ChainName, refundAddr)
+	}
+	if recipientErr != "" || refundErr != "" {
+		data := SwapPageData{
+			PageData:       newPageData(r, "uSwap Zero"),
+			From:           fromTicker,
+			FromNet:        fromNet,
+			To:             toTicker,
+			ToNet:          toNet,
+			Amount:         amount,
+			AmountOut:      amountOutForm,
+			Recipient:      recipient,
+			RefundAddr:     refundAddr,
+			Slippage:       slippage,
+			CSRFToken:      generateCSRFToken("quote"),
+			RecipientError: recipientErr,
+			RefundError:    refundErr,
+		}
+		data.inTGApp(r)
+		renderSwapPage(w, http.StatusBadRequest, data)
 		return
 	}
 
@@ -330,9 +430,14 @@ func handleQuote(w http.ResponseWriter, r *http.Request) {
 			QuoteWaitingTimeMs: 8000,
 			AppFees:            []struct{}{},
 		}
-		quoteResp, err := requestQuote(quoteReq)
+		quoteResp, err := requestQuote(r.Context(), quoteReq)
 		if err != nil {
-			renderError(w, 502, "Quick Swap Failed", "NEAR Intents API is temporarily unavailable. This usually resolves in a few minutes.", "Try Again", "/")
+			renderAPIError(w, r, err, "Quick Swap Failed", fromToken)
+			return
+		}
+		if err := verifyQuoteResponse(quoteReq, quoteResp, fromToken); err != nil {
+			log.Printf("quick swap: %v", err)
+			renderError(w, r, 502, "Quote Rejected", "The swap provider returned a quote that doesn't match your request, so no deposit address is shown. Please try again in a moment.", "Back to Home", "/")
 			return
 		}
 		orderData := &OrderData{
@@ -349,25 +454,29 @@ func handleQuote(w http.ResponseWriter, r *http.Request) {
 			RefundAddr:  refundAddr,
 			RecvAddr:    recipient,
 			SwapType:    "ANY_INPUT",
+			Upstream:    quoteResp.endpoint,
 		}
 		token, err := encryptOrderData(orderData)
 		if err != nil {
-			renderError(w, 500, "Internal Error", "Failed to create order token.", "Back to Home", "/")
+			renderError(w, r, 500, "Internal Error", "Failed to create order token.", "Back to Home", "/")
 			return
 		}
-		http.Redirect(w, r, "/order/"+token, http.StatusFound)
+		tgAppOrderPlaced(r, token, orderData)
+		http.Redirect(w, r, withLang("/order/"+token, lang), http.StatusFound)
 		return
 	}
 
 	// FLEX_INPUT or EXACT_OUTPUT: convert the appropriate amount to atomic.
 	var atomicAmount string
+	amountToken := fromToken
 	if swapType == "EXACT_OUTPUT" {
+		amountToken = toToken
 		atomicAmount, err = humanToAtomic(amountOutForm, toToken.Decimals)
 	} else {
 		atomicAmount, err = humanToAtomic(amount, fromToken.Decimals)
 	}
 	if err != nil {
-		renderError(w, 400, "Invalid Amount", "Could not parse the amount: "+err.Error(), "Go Back", "/")
+		renderError(w, r, 400, "Invalid Amount", loc.T("Could not parse the amount: %s", err.Error()), "Go Back", "/")
 		return
 	}
 
@@ -389,9 +498,9 @@ func handleQuote(w http.ResponseWriter, r *http.Request) {
 		AppFees:            []struct{}{},
 	}
 
-	dryResp, err := requestDryQuote(quoteReq)
+	dryResp, err := requestDryQuote(r.Context(), quoteReq)
 	if err != nil {
-		renderError(w, 502, "Quote Failed", "NEAR Intents API is temporarily unavailable. This usually resolves in a few minutes.", "Try Again", "/")
+		renderAPIError(w, r, err, "Quote Failed", amountToken)
 		return
 	}
 
@@ -408,43 +517,14 @@ func handleQuote(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if dryResp.Quote.AmountOut == "" || dryResp.Quote.AmountOut == "0" {
-		renderError(w, 502, "Quote Unavailable", "No market makers are currently offering a rate for this pair/amount. Try a larger amount or a different pair.", "Go Back", "/")
+		renderError(w, r, 502, "Quote Unavailable", "No market makers are currently offering a rate for this pair/amount. Try a larger amount or a different pair.", "Go Back", "/")
 		return
 	}
 
-	// USD values
-	amountInUSD := ""
-	amountOutUSD := ""
-	spreadUSD := ""
-	spreadPct := ""
-	rate := ""
-
-	inFloat, _ := parseFloat(humanIn)
-	outFloat, _ := parseFloat(humanOut)
-
-	if fromToken.Price > 0 && inFloat > 0 {
-		inUSD := inFloat * fromToken.Price
-		amountInUSD = formatUSD(inUSD)
-
-		if toToken.Price > 0 && outFloat > 0 {
-			outUSD := outFloat * toToken.Price
-			amountOutUSD = formatUSD(outUSD)
-
-			spread := inUSD - outUSD
-			if spread < 0 {
-				spread = 0
-			}
-			spreadUSD = formatUSD(spread)
-			if inUSD > 0 {
-				spreadPct = fmt.Sprintf("%.2f%%", (spread/inUSD)*100)
-			}
-
-			rate = fmt.Sprintf("1 %s = %s %s", fromTicker, formatRate(outFloat/inFloat), toTicker)
-		}
-	}
+	usd := quoteUSD(fromToken, toToken, humanIn, humanOut)
 
 	data := QuotePageData{
-		PageData:     newPageData("Quote Preview"),
+		PageData:     newPageData(r, "Quote Preview"),
 		From:         fromTicker,
 		FromNet:      fromNet,
 		FromTicker:   fromTicker,
@@ -452,10 +532,10 @@ func handleQuote(w http.ResponseWriter, r *http.Request) {
 		ToNet:        toNet,
 		ToTicker:     toTicker,
 		AmountIn:     humanIn,
-		AmountInUSD:  amountInUSD,
+		AmountInUSD:  usd.AmountInUSD,
 		AmountOut:    humanOut,
-		AmountOutUSD: amountOutUSD,
-		Rate:         rate,
+		AmountOutUSD: usd.AmountOutUSD,
+		Rate:         usd.Rate,
 		Recipient:    recipient,
 		RefundAddr:   refundAddr,
 		Slippage:     slippage,
@@ -464,8 +544,8 @@ func handleQuote(w http.ResponseWriter, r *http.Request) {
 		OriginAsset:  fromToken.DefuseAssetID,
 		DestAsset:    toToken.DefuseAssetID,
 		AtomicAmount: atomicAmount,
-		SpreadUSD:    spreadUSD,
-		SpreadPct:    spreadPct,
+		SpreadUSD:    usd.SpreadUSD,
+		SpreadPct:    usd.SpreadPct,
 		FromToken:    fromToken,
 		ToToken:      toToken,
 		HasJWT:       nearIntentsJWT != "",
@@ -474,11 +554,51 @@ func handleQuote(w http.ResponseWriter, r *http.Request) {
 
 	data.FromColor, data.FromColorA = tokenColorPair(fromTicker)
 	data.ToColor, data.ToColorA = tokenColorPair(toTicker)
+	data.inTGApp(r)
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	templates.ExecuteTemplate(w, "quote.html", data)
 }
 
+// quoteUSDValues are the USD figures shown alongside a quote. Fields are
+// empty when a token price is unknown.
+type quoteUSDValues struct {
+	AmountInUSD  string
+	AmountOutUSD string
+	SpreadUSD    string
+	SpreadPct    string
+	Rate         string
+}
+
+// quoteUSD values a quote's human amounts at cached token prices.
+func quoteUSD(fromToken, toToken *TokenInfo, humanIn, humanOut string) quoteUSDValues {
+	var v quoteUSDValues
+	inFloat, _ := parseFloat(humanIn)
+	outFloat, _ := parseFloat(humanOut)
+
+	if fromToken.Price > 0 && inFloat > 0 {
+		inUSD := inFloat * fromToken.Price
+		v.AmountInUSD = formatUSD(inUSD)
+
+		if toToken.Price > 0 && outFloat > 0 {
+			outUSD := outFloat * toToken.Price
+			v.AmountOutUSD = formatUSD(outUSD)
+
+			spread := inUSD - outUSD
+			if spread < 0 {
+				spread = 0
+			}
+			v.SpreadUSD = formatUSD(spread)
+			if inUSD > 0 {
+				v.SpreadPct = fmt.Sprintf("%.2f%%", (spread/inUSD)*100)
+			}
+
+			v.Rate = fmt.Sprintf("1 %s = %s %s", fromToken.Ticker, formatRate(outFloat/inFloat), toToken.Ticker)
+		}
+	}
+	return v
+}
+
 // handleSwapConfirm creates a real quote and redirects to the order page.
 func handleSwapConfirm(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -490,12 +610,12 @@ func handleSwapConfirm(w http.ResponseWriter, r *http.Request) {
 
 	ip := clientIP(r)
 	if !limiter.allow(ip, 10, time.Minute) {
-		renderError(w, 429, "Too Many Requests", "Please wait before creating another swap.", "Back to Home", "/")
+		renderError(w, r, 429, "Too Many Requests", "Please wait before creating another swap.", "Back to Home", "/")
 		return
 	}
 
 	if !verifyCSRFToken(r.FormValue("csrf"), "swap", time.Hour) {
-		renderError(w, 403, "Invalid Request", "Form expired. Please start over.", "Back to Home", "/")
+		renderError(w, r, 403, "Invalid Request", "Form expired. Please start over.", "Back to Home", "/")
 		return
 	}
 
@@ -512,12 +632,35 @@ func handleSwapConfirm(w http.ResponseWriter, r *http.Request) {
 		swapType = "FLEX_INPUT"
 	}
 
+	// Optional passphrase lock — checked before the real quote is requested.
+	loc, lang := requestLocale(r)
+	passphrase := r.FormValue("passphrase")
+	if passphrase != "" || r.FormValue("passphrase_confirm") != "" {
+		if len([]rune(passphrase)) < orderLockMinPassphrase {
+			renderError(w, r, 400, "Passphrase Too Short", loc.T("Choose a passphrase of at least %d characters, or leave both fields empty.", orderLockMinPassphrase), "Go Back", "/")
+			return
+		}
+		if passphrase != r.FormValue("passphrase_confirm") {
+			renderError(w, r, 400, "Passphrases Differ", "The two passphrases do not match.", "Go Back", "/")
+			return
+		}
+	}
+
 	fromToken := findToken(fromTicker, fromNet)
 	toToken := findToken(toTicker, toNet)
 	if fromToken == nil || toToken == nil {
-		renderError(w, 400, "Unknown Token", "Token not found.", "Back to Home", "/")
+		renderError(w, r, 400, "Unknown Token", "Token not found.", "Back to Home", "/")
 		return
 	}
+	for _, problem := range []string{
+		addressProblem("recipient", toToken.ChainName, recipient),
+		addressProblem("refund", fromToken.ChainName, refundAddr),
+	} {
+		if problem != "" {
+			renderError(w, r, 400, "Invalid Address", problem, "Back to Home", "/")
+			return
+		}
+	}
 
 	bps := 100
 	fmt.Sscanf(slippageBPS, "%d", &bps)
@@ -540,9 +683,18 @@ func handleSwapConfirm(w http.ResponseWriter, r *http.Request) {
 		AppFees:            []struct{}{},
 	}
 
-	quoteResp, err := requestQuote(quoteReq)
+	quoteResp, err := requestQuote(r.Context(), quoteReq)
 	if err != nil {
-		renderError(w, 502, "Swap Failed", "NEAR Intents API is temporarily unavailable. This usually resolves in a few minutes.", "Try Again", "/")
+		amountToken := fromToken
+		if swapType == "EXACT_OUTPUT" {
+			amountToken = toToken
+		}
+		renderAPIError(w, r, err, "Swap Failed", amountToken)
+		return
+	}
+	if err := verifyQuoteResponse(quoteReq, quoteResp, fromToken); err != nil {
+		log.Printf("swap: %v", err)
+		renderError(w, r, 502, "Quote Rejected", "The swap provider returned a quote that doesn't match your request, so no deposit address is shown. Please try again in a moment.", "Back to Home", "/")
 		return
 	}
 
@@ -561,42 +713,130 @@ func handleSwapConfirm(w http.ResponseWriter, r *http.Request) {
 		RefundAddr:  refundAddr,
 		RecvAddr:    recipient,
 		SwapType:    swapType,
+		Upstream:    quoteResp.endpoint,
 	}
 
-	token, err := encryptOrderData(orderData)
+	var token string
+	var lockKey []byte
+	if passphrase != "" {
+		token, lockKey, err = encryptLockedOrderData(orderData, passphrase)
+	} else {
+		token, err = encryptOrderData(orderData)
+	}
 	if err != nil {
-		renderError(w, 500, "Internal Error", "Failed to create order token.", "Back to Home", "/")
+		renderError(w, r, 500, "Internal Error", "Failed to create order token.", "Back to Home", "/")
 		return
 	}
+	storeAttestation("web", token, lockKey, quoteReq, quoteResp)
+	tgAppOrderPlaced(r, token, orderData)
 
-	http.Redirect(w, r, "/order/"+token, http.StatusFound)
+	http.Redirect(w, r, withLang("/order/"+token, lang), http.StatusFound)
 }
 
 // handleOrder renders the order status page.
 func handleOrder(w http.ResponseWriter, r *http.Request) {
-	// Extract token from path: /order/{token} or /order/{token}/raw
+	// Extract token from path: /order/{token}, /order/{token}/raw,
+	// /order/{token}/attestation, /order/{token}/events or
+	// /order/{token}/telegram
 	path := strings.TrimPrefix(r.URL.Path, "/order/")
 	isRaw := strings.HasSuffix(path, "/raw")
 	if isRaw {
 		path = strings.TrimSuffix(path, "/raw")
 	}
+	isEvents := strings.HasSuffix(path, "/events")
+	if isEvents {
+		path = strings.TrimSuffix(path, "/events")
+	}
+	isAttestation := strings.HasSuffix(path, "/attestation")
+	if isAttestation {
+		path = strings.TrimSuffix(path, "/attestation")
+	}
+	isTelegram := strings.HasSuffix(path, "/telegram")
+	if isTelegram {
+		path = strings.TrimSuffix(path, "/telegram")
+	}
 
 	if path == "" {
-		renderError(w, 400, "Missing Order", "No order token provided.", "Create New Swap", "/")
+		renderError(w, r, 400, "Missing Order", "No order token provided.", "Create New Swap", "/")
 		return
 	}
 
 	order, err := decryptOrderData(path)
 	if err != nil {
-		renderError(w, 400, "Invalid Order", "This order link is invalid or expired. It may have been created on a different server.", "Create New Swap", "/")
+		renderError(w, r, 400, "Invalid Order", "This order link is invalid or expired. It may have been created on a different server.", "Create New Swap", "/")
 		return
 	}
 
-	// Fetch live status from NEAR Intents
-	status, err := fetchStatus(order.DepositAddr, order.Memo)
-	if err != nil {
-		// If API is down, still show what we know from the token
-		status = &StatusResponse{Status: "UNKNOWN"}
+	// Passphrase-locked orders: unlocked per request via POST, never stored.
+	// The raw API response includes addresses, so it stays locked.
+	locked := order.Locked()
+	unlockKey, unlockErr := "", ""
+	if locked && (isRaw || ((isAttestation || isTelegram) && r.Method != http.MethodPost)) {
+		renderError(w, r, 403, "Order Locked", "This order is locked with a passphrase. Open the order page to unlock it.", "Open Order", "/order/"+path)
+		return
+	}
+	if locked && r.Method == http.MethodPost {
+		r.ParseForm()
+		// Its own bucket, so guesses and swaps don't share a budget.
+		if !limiter.allowScoped("unlock", clientIP(r), 10, time.Minute) {
+			renderError(w, r, 429, "Too Many Requests", "Too many unlock attempts. Please wait a minute.", "Back to Order", "/order/"+path)
+			return
+		}
+		var full *OrderData
+		var key []byte
+		if k := decodeUnlockKey(r.FormValue("key")); k != nil {
+			full, err = unlockOrderDataWithKey(order, k)
+			key = k
+		} else {
+			full, key, err = unlockOrderData(order, r.FormValue("passphrase"))
+		}
+		if err == nil {
+			order, locked, unlockKey = full, false, encodeUnlockKey(key)
+		} else {
+			unlockErr = "Wrong passphrase."
+		}
+	}
+
+	// Signed quote attestation, sealed under the token or unlock key.
+	attSecret := []byte(path)
+	if unlockKey != "" {
+		attSecret = decodeUnlockKey(unlockKey)
+	}
+	if isTelegram {
+		continueInTelegram(w, r, path, locked, unlockKey)
+		return
+	}
+	if isAttestation {
+		doc := attestations.get(attSecret)
+		if locked || doc == nil {
+			renderError(w, r, 404, "No Attestation", "No signed attestation is available for this order. Attestations are kept for 24 hours after the order is placed.", "Back to Order", "/order/"+path)
+			return
+		}
+		name := "attestation.json"
+		if order.CorrID != "" {
+			name = "attestation-" + order.CorrID + ".json"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+		w.Write(doc)
+		return
+	}
+
+	if isEvents {
+		streamOrderEvents(w, r, order, locked)
+		return
+	}
+
+	// Fetch live status (and ANY_INPUT withdrawals) from NEAR Intents,
+	// shared with everyone else viewing this order. If the API is down,
+	// still show what we know from the token.
+	status := &StatusResponse{Status: "UNKNOWN"}
+	var withdrawals *AnyInputWithdrawalsResponse
+	if res, err := orderStatuses.get(r.Context(), order); err == nil {
+		status = res.Status
+		if !locked {
+			withdrawals = res.Withdrawals
+		}
 	}
 
 	if isRaw {
@@ -610,22 +850,7 @@ func handleOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Determine status step and terminal state
-	isTerminal := false
-	statusStep := 0
-	switch status.Status {
-	case "PENDING_DEPOSIT":
-		statusStep = 0
-	case "PROCESSING":
-		statusStep = 1
-	case "SUCCESS":
-		statusStep = 2
-		isTerminal = true
-	case "REFUNDED", "FAILED", "INCOMPLETE_DEPOSIT":
-		statusStep = 2
-		isTerminal = true
-	default:
-		statusStep = 0
-	}
+	statusStep, isTerminal := orderStatusStep(status.Status)
 
 	// Calculate time remaining
 	timeRemaining := ""
@@ -647,22 +872,20 @@ func handleOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate QR code
-	qrData := order.DepositAddr
-	qrSVG := generateQRSVG(qrData, 200)
+	qrSVG := ""
+	if !locked {
+		qrSVG = generateQRSVG(order.DepositAddr, 200)
+	}
 
 	refresh := 0
-	if !isTerminal {
+	if !isTerminal && unlockKey == "" {
+		// An unlocked view can't auto-refresh: a GET would lock it again.
+		// With JavaScript the page follows /events instead of reloading.
 		refresh = 10
 	}
 
-	// For ANY_INPUT orders, fetch withdrawal history.
-	var withdrawals *AnyInputWithdrawalsResponse
-	if order.SwapType == "ANY_INPUT" {
-		withdrawals, _ = fetchAnyInputWithdrawals(order.DepositAddr)
-	}
-
 	data := OrderPageData{
-		PageData:      newPageData("Order Status"),
+		PageData:      newPageData(r, "Order Status"),
 		Token:         path,
 		Order:         order,
 		Status:        status,
@@ -671,8 +894,14 @@ func handleOrder(w http.ResponseWriter, r *http.Request) {
 		IsTerminal:    isTerminal,
 		StatusStep:    statusStep,
 		Withdrawals:   withdrawals,
+		Locked:        locked,
+		UnlockKey:     unlockKey,
+		UnlockError:   unlockErr,
+		Attestation:   !locked && attestations.get(attSecret) != nil,
+		Telegram:      !locked && !isTerminal && tgBotUsername != "",
 	}
 	data.MetaRefresh = refresh
+	data.NoscriptRefresh = true
 	data.FromColor, data.FromColorA = tokenColorPair(order.FromTicker)
 	data.ToColor, data.ToColorA = tokenColorPair(order.ToTicker)
 
@@ -686,7 +915,7 @@ func handleCurrencies(w http.ResponseWriter, r *http.Request) {
 
 	networks, err := getNetworkGroups()
 	if err != nil {
-		renderError(w, 502, "Unavailable", "Could not load currency list. NEAR Intents API may be temporarily unavailable.", "Try Again", "/currencies")
+		renderError(w, r, 502, "Unavailable", "Could not load currency list. NEAR Intents API may be temporarily unavailable.", "Try Again", "/currencies")
 		return
 	}
 
@@ -699,7 +928,7 @@ func handleCurrencies(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := CurrenciesPageData{
-		PageData:   newPageData("Supported Currencies"),
+		PageData:   newPageData(r, "Supported Currencies"),
 		Networks:   networks,
 		TotalCount: totalCount,
 		Search:     search,
@@ -712,7 +941,7 @@ func handleCurrencies(w http.ResponseWriter, r *http.Request) {
 // handleHowItWorks renders the educational page.
 func handleHowItWorks(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	templates.ExecuteTemplate(w, "how_it_works.html", newPageData("How It Works"))
+	templates.ExecuteTemplate(w, "how_it_works.html", newPageData(r, "How It Works"))
 }
 
 // ResellerStats holds formatted display strings for a single reseller.
@@ -799,7 +1028,7 @@ func initCaseStudy() {
 // handleCaseStudy renders the competitor analysis page.
 func handleCaseStudy(w http.ResponseWriter, r *http.Request) {
 	data := CaseStudyPageData{
-		PageData: newPageData("The Crypto Swap Reseller Problem"),
+		PageData: newPageData(r, "The Crypto Swap Reseller Problem"),
 		Eagle:    caseStudyData.Eagle,
 		Lizard:   caseStudyData.Lizard,
 		SwapMy:   caseStudyData.SwapMy,
@@ -817,6 +1046,11 @@ type VerifyPageData struct {
 	Requests    string
 	BinarySize  string
 	EnvVars     []EnvVarStatus
+	NearAPI     BreakerStatus
+	Endpoints   []EndpointStatus
+	AttestationKey string
+	TelegramMode   string // "webhook", "polling" or "" when the bot is off
+	TelegramOutbox TGOutboxStatus
 }
 
 // EnvVarStatus shows whether an env var is configured.
@@ -827,8 +1061,10 @@ type EnvVarStatus struct {
 
 // handleVerify renders the deployment verification page.
 func handleVerify(w http.ResponseWriter, r *http.Request) {
+	page := newPageData(r, "Verify")
+
 	// Go version from build info
-	goVersion := "unknown"
+	goVersion := page.T("unknown")
 	if info, ok := debug.ReadBuildInfo(); ok {
 		goVersion = info.GoVersion
 	}
@@ -840,7 +1076,7 @@ func handleVerify(w http.ResponseWriter, r *http.Request) {
 	reqs := formatCommas(atomic.LoadInt64(&requestCounter))
 
 	// Binary size
-	binSize := "unknown"
+	binSize := page.T("unknown")
 	if exe, err := os.Executable(); err == nil {
 		if fi, err := os.Stat(exe); err == nil {
 			mb := float64(fi.Size()) / 1024 / 1024
@@ -850,8 +1086,9 @@ func handleVerify(w http.ResponseWriter, r *http.Request) {
 
 	// Env var status (key names only — never values)
 	envKeys := []string{
-		"ORDER_SECRET", "NEAR_INTENTS_JWT", "NEAR_INTENTS_EXPLORER_JWT", "NEAR_INTENTS_API_URL", "PORT",
-		"TG_BOT_TOKEN", "TG_APP_URL", "TG_WEBHOOK_SECRET",
+		"ORDER_SECRET", "ORDER_SECRETS", "ATTESTATION_KEY", "NEAR_INTENTS_JWT", "NEAR_INTENTS_EXPLORER_JWT", "NEAR_INTENTS_API_URL", "PORT",
+		"TG_BOT_TOKEN", "TG_APP_URL", "TG_MODE", "TG_WEBHOOK_SECRET", "TG_ALERTS_FILE", "TG_SESSIONS_FILE", "TG_ADDRESSES_FILE",
+		"TG_HISTORY_FILE", "TG_HISTORY_DAYS",
 		"TG_MONITOR_GROUP_ID", "TG_MAIN_CHAT_ID",
 		"TG_SWAPMY_THREAD_ID", "TG_EAGLESWAP_THREAD_ID", "TG_LIZARDSWAP_THREAD_ID",
 	}
@@ -861,12 +1098,17 @@ func handleVerify(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := VerifyPageData{
-		PageData:  newPageData("Verify"),
+		PageData:  page,
 		GoVersion: goVersion,
 		Uptime:    uptime,
 		Requests:  reqs,
 		BinarySize: binSize,
 		EnvVars:   envVars,
+		NearAPI:   nearBreaker.status(),
+		Endpoints: nearPool.status(),
+		AttestationKey: attestationPublicKey(),
+		TelegramMode:   tgMode,
+		TelegramOutbox: tgOutbox.status(),
 	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	templates.ExecuteTemplate(w, "verify.html", data)
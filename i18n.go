@@ -0,0 +1,311 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Localization.
+//
+// Text is written in English where it is used and looked up in a message
+// catalog per language, gettext style: the English text is the message ID,
+// so anything without a translation stays English. Catalogs are the JSON
+// files in locales/, embedded in the binary. "messages" maps a message to
+// its translation or, for a message that depends on a count, to its plural
+// forms by CLDR category ("one", "few", "many", "other"; see
+// pluralCategory). English has a catalog too, for the singular forms of its
+// plurals.
+//
+// Web pages pick a language from ?lang=, which the app's own links and forms
+// carry along (no cookie is set), then from Accept-Language. The Telegram
+// bot uses each user's language_code.
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// locale is one language's catalog.
+type locale struct {
+	Tag  string // base language, e.g. "es"
+	Name string // the language's name in itself, for the language links
+
+	messages map[string]string
+	plurals  map[string]map[string]string
+}
+
+var (
+	locales       = loadLocales()
+	localeList    = sortedLocales(locales)
+	defaultLocale = locales["en"]
+)
+
+// loadLocales parses the embedded catalogs. A malformed catalog is a build
+// mistake, so it panics like a malformed template.
+func loadLocales() map[string]*locale {
+	files, err := fs.Glob(localeFS, "locales/*.json")
+	if err != nil {
+		panic(err)
+	}
+	out := make(map[string]*locale, len(files))
+	for _, name := range files {
+		raw, err := localeFS.ReadFile(name)
+		if err != nil {
+			panic(err)
+		}
+		var file struct {
+			Name     string                     `json:"name"`
+			Messages map[string]json.RawMessage `json:"messages"`
+		}
+		if err := json.Unmarshal(raw, &file); err != nil {
+			panic(fmt.Sprintf("%s: %v", name, err))
+		}
+		l := &locale{
+			Tag:      strings.TrimSuffix(path.Base(name), ".json"),
+			Name:     file.Name,
+			messages: make(map[string]string),
+			plurals:  make(map[string]map[string]string),
+		}
+		for msg, v := range file.Messages {
+			var text string
+			if json.Unmarshal(v, &text) == nil {
+				l.messages[msg] = text
+				continue
+			}
+			var forms map[string]string
+			if err := json.Unmarshal(v, &forms); err != nil || forms["other"] == "" {
+				panic(fmt.Sprintf("%s: %q needs a string or plural forms with \"other\"", name, msg))
+			}
+			l.plurals[msg] = forms
+		}
+		out[l.Tag] = l
+	}
+	if out["en"] == nil {
+		panic("locales/en.json is missing")
+	}
+	return out
+}
+
+func sortedLocales(m map[string]*locale) []*locale {
+	list := make([]*locale, 0, len(m))
+	for _, l := range m {
+		list = append(list, l)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Tag < list[j].Tag })
+	return list
+}
+
+// T translates msg and, given args, formats it with fmt.Sprintf.
+func (l *locale) T(msg string, args ...interface{}) string {
+	text, ok := l.messages[msg]
+	if !ok {
+		text = msg
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// N translates a message that depends on the count n, in the plural form
+// for n, and formats it with n followed by args: N("%d tokens", 3).
+func (l *locale) N(msg string, n int, args ...interface{}) string {
+	tag, forms := l.Tag, l.plurals[msg]
+	if forms == nil {
+		tag, forms = defaultLocale.Tag, defaultLocale.plurals[msg]
+	}
+	text := forms[pluralCategory(tag, n)]
+	if text == "" {
+		text = forms["other"]
+	}
+	if text == "" {
+		text = msg
+	}
+	return fmt.Sprintf(text, append([]interface{}{n}, args...)...)
+}
+
+// pluralCategory returns the CLDR plural category of the count n in the
+// language tag, for whole numbers.
+func pluralCategory(tag string, n int) string {
+	if n < 0 {
+		n = -n
+	}
+	switch tag {
+	case "ja", "ko", "zh", "id", "th", "vi":
+		return "other"
+	case "fr", "pt":
+		if n <= 1 {
+			return "one"
+		}
+	case "ru", "uk", "pl":
+		switch {
+		case n == 1 || tag != "pl" && n%10 == 1 && n%100 != 11:
+			return "one"
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return "few"
+		}
+		return "many"
+	default:
+		if n == 1 {
+			return "one"
+		}
+	}
+	return "other"
+}
+
+// findLocale returns the catalog for a language tag such as "es" or
+// "pt-BR", matching on the base language, or nil if there is none.
+func findLocale(tag string) *locale {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if l := locales[tag]; l != nil {
+		return l
+	}
+	if i := strings.IndexAny(tag, "-_"); i > 0 {
+		return locales[tag[:i]]
+	}
+	return nil
+}
+
+// localeFor is findLocale falling back to English, for Telegram's
+// language_code.
+func localeFor(tag string) *locale {
+	if l := findLocale(tag); l != nil {
+		return l
+	}
+	return defaultLocale
+}
+
+// acceptLanguage returns the best language in an Accept-Language header
+// that has a catalog, or nil. Equal weights keep the header's order.
+func acceptLanguage(header string) *locale {
+	var best *locale
+	bestQ := 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if l := findLocale(tag); l != nil && q > bestQ {
+			best, bestQ = l, q
+		}
+	}
+	return best
+}
+
+// requestLocale picks the language of a web request: ?lang= or a lang form
+// field, then the Mini App user's Telegram language, then Accept-Language.
+// chosen is the ?lang= choice that links and forms carry along, if any.
+func requestLocale(r *http.Request) (l *locale, chosen string) {
+	if l := findLocale(r.FormValue("lang")); l != nil {
+		return l, l.Tag
+	}
+	if launch := tgAppFromContext(r.Context()); launch != nil {
+		if l := findLocale(launch.User.LanguageCode); l != nil {
+			return l, ""
+		}
+	}
+	if l := acceptLanguage(r.Header.Get("Accept-Language")); l != nil {
+		return l, ""
+	}
+	return defaultLocale, ""
+}
+
+// withLang adds lang=tag to a local URL; it returns u unchanged when tag
+// is empty.
+func withLang(u, tag string) string {
+	if tag == "" {
+		return u
+	}
+	sep := "?"
+	if strings.Contains(u, "?") {
+		sep = "&"
+	}
+	return u + sep + "lang=" + url.QueryEscape(tag)
+}
+
+// LangLink is a link to the current page in another language.
+type LangLink struct {
+	Tag     string
+	Name    string
+	URL     string
+	Current bool
+}
+
+// langLinks links the page of a GET request in every language. Other
+// requests can't be repeated, so their links go to the swap form.
+func langLinks(r *http.Request, current *locale) []LangLink {
+	target := url.URL{Path: "/"}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		target = url.URL{Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+	}
+	links := make([]LangLink, len(localeList))
+	for i, l := range localeList {
+		q := target.Query()
+		q.Set("lang", l.Tag)
+		target.RawQuery = q.Encode()
+		links[i] = LangLink{Tag: l.Tag, Name: l.Name, URL: target.String(), Current: l == current}
+	}
+	return links
+}
+
+// --- Display width ---
+
+// Telegram shows the box-drawing cards in a monospace font, where East
+// Asian wide characters take two columns and combining marks none, so card
+// text is measured in columns rather than runes.
+
+// runeWidth returns the number of columns r takes in a monospace font.
+func runeWidth(r rune) int {
+	switch {
+	case r == 0 || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case r >= 0x1100 && r <= 0x115f, // Hangul Jamo
+		r >= 0x2e80 && r <= 0x303e, // CJK radicals, punctuation
+		r >= 0x3041 && r <= 0x33ff, // kana, CJK compatibility
+		r >= 0x3400 && r <= 0x4dbf, // CJK extension A
+		r >= 0x4e00 && r <= 0x9fff, // CJK unified ideographs
+		r >= 0xa000 && r <= 0xa4cf, // Yi
+		r >= 0xac00 && r <= 0xd7a3, // Hangul syllables
+		r >= 0xf900 && r <= 0xfaff, // CJK compatibility ideographs
+		r >= 0xfe30 && r <= 0xfe4f, // CJK compatibility forms
+		r >= 0xff00 && r <= 0xff60, // fullwidth forms
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f, // emoji
+		r >= 0x1f900 && r <= 0x1f9ff,
+		r >= 0x20000 && r <= 0x3fffd: // CJK extensions B and later
+		return 2
+	}
+	return 1
+}
+
+// textWidth returns the number of columns s takes in a monospace font.
+func textWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		w += runeWidth(r)
+	}
+	return w
+}
+
+// truncWidth cuts s to at most max columns without splitting a character.
+func truncWidth(s string, max int) string {
+	w := 0
+	for i, r := range s {
+		if w+runeWidth(r) > max {
+			return s[:i]
+		}
+		w += runeWidth(r)
+	}
+	return s
+}
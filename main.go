@@ -1,14 +1,17 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"html/template"
 	"io/fs"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -22,6 +25,10 @@ var (
 // onionURL is the .onion address for this deployment, set via ONION_URL env var.
 var onionURL = os.Getenv("ONION_URL")
 
+// shuttingDown is closed when the server starts shutting down, so
+// long-lived handlers such as /order/{token}/events return.
+var shuttingDown = make(chan struct{})
+
 //go:embed templates/*
 var templateFS embed.FS
 
@@ -52,19 +59,29 @@ type rateBucket struct {
 var limiter = &rateLimiter{counters: make(map[string]*rateBucket)}
 
 func (rl *rateLimiter) allow(ip string, limit int, window time.Duration) bool {
+	return rl.allowScoped("", ip, limit, window)
+}
+
+// allowScoped is allow with a separate set of buckets per scope, so one
+// route's traffic doesn't use up another's allowance.
+func (rl *rateLimiter) allowScoped(scope, ip string, limit int, window time.Duration) bool {
 	// Use /24 prefix for IPv4
 	prefix := ip
 	if idx := strings.LastIndex(ip, "."); idx > 0 {
 		prefix = ip[:idx]
 	}
+	key := prefix
+	if scope != "" {
+		key = scope + " " + prefix
+	}
 
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	bucket, ok := rl.counters[prefix]
+	bucket, ok := rl.counters[key]
 	now := time.Now()
 	if !ok || now.After(bucket.resetAt) {
-		rl.counters[prefix] = &rateBucket{count: 1, resetAt: now.Add(window)}
+		rl.counters[key] = &rateBucket{count: 1, resetAt: now.Add(window)}
 		return true
 	}
 	bucket.count++
@@ -123,12 +140,30 @@ func initTemplates() {
 }
 
 func main() {
+	// Subcommands — developer tooling shipped in the same binary
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fake-1click":
+			runFake1Click(os.Args[2:])
+			return
+		case "rotate-key":
+			runRotateKey()
+			return
+		case "verify-attestation":
+			runVerifyAttestation(os.Args[2:])
+			return
+		}
+	}
+
 	initCrypto()
+	initAttestation()
 	initNearIntents()
+	nearPool.startHealthChecks()
 	initTemplates()
 	initCaseStudy()
 	startCacheRefresher()
 	limiter.startCleanup()
+	orderStatuses.startCleanup()
 
 	mux := http.NewServeMux()
 
@@ -152,11 +187,34 @@ func main() {
 		http.Redirect(w, r, "https://github.com/uSwapExchange/zero", http.StatusFound)
 	})
 
+	// JSON API, /api/openapi.json and /api/docs
+	registerAPIRoutes(mux)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Telegram bot (optional — disabled if TG_BOT_TOKEN is unset)
+	var tgPollDone <-chan struct{}
 	if initTelegramBot() {
-		mux.HandleFunc("/tg/webhook/"+tgWebhookSecret, handleTelegramWebhook)
+		// Load every persisted store before the first update is handled,
+		// so updates never write to a store that a later load replaces.
+		initTGSessions()
+		initTGAlerts()
+		initTGAddrBooks()
+		initTGHistory()
+		if tgMode == tgModePolling {
+			tgPollDone = startTGPolling(ctx)
+		} else {
+			mux.HandleFunc("/tg/webhook/"+tgWebhookSecret, handleTelegramWebhook)
+		}
+		mux.HandleFunc("/tg/app", handleTGApp)
+		mux.HandleFunc("/tg/app/", handleTGApp)
 		tgSessions.startCleanup()
-		log.Printf("Telegram bot enabled")
+		tgWatches.start()
+		tgHistory.start()
+		tgAlerts.start()
+		startSealedFileSync()
+		log.Printf("Telegram bot enabled (%s)", tgMode)
 	}
 
 	// Reseller monitor (optional — disabled if TG_MONITOR_GROUP_ID is unset)
@@ -177,7 +235,29 @@ func main() {
 		incrementRequests()
 		mux.ServeHTTP(w, r)
 	})
-	if err := http.ListenAndServe(":"+port, handler); err != nil {
-		log.Fatal(err)
+	srv := &http.Server{Addr: ":" + port, Handler: handler}
+	go func() {
+		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	// On SIGINT/SIGTERM: stop taking requests and Telegram updates, then
+	// let what's in flight finish.
+	<-ctx.Done()
+	log.Printf("Shutting down")
+	close(shuttingDown)
+	drainCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(drainCtx); err != nil {
+		log.Printf("HTTP shutdown: %v", err)
+	}
+	if tgPollDone != nil {
+		select {
+		case <-tgPollDone:
+		case <-drainCtx.Done():
+		}
 	}
+	drainTelegram(drainCtx)
+	saveSealedFiles()
 }
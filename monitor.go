@@ -175,7 +175,6 @@ func runResellerPoller(groupID int64, r monitorReseller, cursors cursorFile) {
 
 			if r.ThreadID != 0 && tgBotToken != "" {
 				postMonitorCard(groupID, r.ThreadID, r.Name, tx, fee, monitorStats[r.Affiliate])
-				time.Sleep(200 * time.Millisecond)
 			}
 
 			cursor.LastAddr = tx.DepositAddress
@@ -2,27 +2,33 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
+	"strings"
 	"time"
 )
 
 var (
-	nearIntentsBaseURL = "https://1click.chaindefuser.com"
-	nearIntentsJWT     string
-	nearHTTPClient     = &http.Client{Timeout: 30 * time.Second}
+	nearIntentsJWT string
+	nearHTTPClient = &http.Client{} // per-request timeouts come from the context
 )
 
 func initNearIntents() {
-	if url := os.Getenv("NEAR_INTENTS_API_URL"); url != "" {
-		nearIntentsBaseURL = url
+	if urls := parseEndpointList(os.Getenv("NEAR_INTENTS_API_URL")); len(urls) > 0 {
+		nearPool.set(urls)
 	}
 	nearIntentsJWT = os.Getenv("NEAR_INTENTS_JWT")
 	explorerJWT = os.Getenv("NEAR_INTENTS_EXPLORER_JWT")
+	initQuoteVerification()
 }
 
 // QuoteRequest is the payload for POST /v0/quote
@@ -46,10 +52,14 @@ type QuoteRequest struct {
 // QuoteResponse is the response from POST /v0/quote (real, non-dry quote).
 // The API nests quote details inside a "quote" field.
 type QuoteResponse struct {
-	CorrelationID string      `json:"correlationId"`
-	Timestamp     string      `json:"timestamp"`
-	Signature     string      `json:"signature"`
-	Quote         QuoteDetail `json:"quote"`
+	CorrelationID string        `json:"correlationId"`
+	Timestamp     string        `json:"timestamp"`
+	Signature     string        `json:"signature"`
+	QuoteRequest  *QuoteRequest `json:"quoteRequest,omitempty"` // echo of the request
+	Quote         QuoteDetail   `json:"quote"`
+
+	rawQuote json.RawMessage // "quote" as received, for signature checks
+	endpoint string          // base URL of the 1Click endpoint that answered
 }
 
 // QuoteDetail contains the swap parameters inside a QuoteResponse.
@@ -130,69 +140,317 @@ type TokenInfo struct {
 	ContractAddress string  `json:"contractAddress,omitempty"`
 }
 
+// 1Click error classifications carried in APIError.Code.
+const (
+	apiErrAmountTooLow     = "amount_too_low"
+	apiErrUnsupportedPair  = "unsupported_pair"
+	apiErrInvalidRecipient = "invalid_recipient"
+	apiErrInvalidRefund    = "invalid_refund"
+	apiErrBadDeadline      = "bad_deadline"
+	apiErrNoQuote          = "no_quote"
+	apiErrNotFound         = "not_found"
+	apiErrRateLimited      = "rate_limited"
+	apiErrUnauthorized     = "unauthorized"
+	apiErrUnavailable      = "unavailable"
+	apiErrBadRequest       = "bad_request"
+)
+
+// APIError is a non-2xx response from the 1Click API, parsed from its
+// error body ({"message": "..."} or {"message": ["...", ...]}).
+type APIError struct {
+	StatusCode int
+	Code       string // one of the apiErr* constants
+	Message    string // upstream message, verbatim
+	MinAmount  string // atomic minimum amount, when the API reports one
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Message)
+}
+
+// Temporary reports whether retrying later may succeed (5xx, rate limits).
+func (e *APIError) Temporary() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
+
+// apiMinAmountRe extracts the atomic minimum from messages such as
+// "Amount is too low for bridge, try at least 1000000".
+var apiMinAmountRe = regexp.MustCompile(`(?i)at least (\d+)`)
+
+// parseAPIError builds an APIError from a non-2xx status and response body.
+func parseAPIError(status int, body []byte) *APIError {
+	e := &APIError{StatusCode: status}
+
+	var raw struct {
+		Message json.RawMessage `json:"message"`
+	}
+	if json.Unmarshal(body, &raw) == nil && len(raw.Message) > 0 {
+		var msg string
+		var list []string
+		if json.Unmarshal(raw.Message, &msg) == nil {
+			e.Message = msg
+		} else if json.Unmarshal(raw.Message, &list) == nil {
+			e.Message = strings.Join(list, "; ")
+		}
+	}
+	if e.Message == "" {
+		e.Message = strings.TrimSpace(string(body))
+		if len(e.Message) > 200 {
+			e.Message = e.Message[:200]
+		}
+	}
+
+	e.Code = classifyAPIError(status, e.Message)
+	if e.Code == apiErrAmountTooLow {
+		if m := apiMinAmountRe.FindStringSubmatch(e.Message); m != nil {
+			e.MinAmount = m[1]
+		}
+	}
+	return e
+}
+
+// classifyAPIError maps a status code and upstream message to an apiErr* code.
+func classifyAPIError(status int, message string) string {
+	msg := strings.ToLower(message)
+	switch {
+	case status >= 500:
+		return apiErrUnavailable
+	case status == http.StatusTooManyRequests:
+		return apiErrRateLimited
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return apiErrUnauthorized
+	case status == http.StatusNotFound:
+		return apiErrNotFound
+	case strings.Contains(msg, "refundto") || strings.Contains(msg, "refund address"):
+		return apiErrInvalidRefund
+	case strings.Contains(msg, "recipient"):
+		return apiErrInvalidRecipient
+	case strings.Contains(msg, "deadline"):
+		return apiErrBadDeadline
+	// After the field checks: "deadline must be at least 60 seconds" is
+	// about the deadline, not the amount.
+	case strings.Contains(msg, "too low") || strings.Contains(msg, "at least"):
+		return apiErrAmountTooLow
+	case strings.Contains(msg, "originasset") || strings.Contains(msg, "destinationasset") ||
+		strings.Contains(msg, "tokenin") || strings.Contains(msg, "tokenout") ||
+		strings.Contains(msg, "not supported") || strings.Contains(msg, "unsupported"):
+		return apiErrUnsupportedPair
+	case strings.Contains(msg, "failed to get quote") || strings.Contains(msg, "no quote"):
+		return apiErrNoQuote
+	}
+	return apiErrBadRequest
+}
+
+// describeAPIError turns a failed 1Click call into a message the user can act on.
+// amountToken is the token the request amount is denominated in (used to
+// render minimums) and may be nil. Transport failures, 5xx responses and
+// anything unrecognised map to the generic "temporarily unavailable" text
+// with a 502; problems the user can fix map to a 400.
+func describeAPIError(err error, amountToken *TokenInfo) (int, string) {
+	const unavailable = "NEAR Intents API is temporarily unavailable. This usually resolves in a few minutes."
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Temporary() {
+		if apiErr != nil && apiErr.Code == apiErrRateLimited {
+			return http.StatusTooManyRequests, "NEAR Intents is rate limiting requests. Please wait a moment and try again."
+		}
+		return http.StatusBadGateway, unavailable
+	}
+
+	switch apiErr.Code {
+	case apiErrAmountTooLow:
+		if apiErr.MinAmount != "" && amountToken != nil {
+			min := atomicToHuman(apiErr.MinAmount, amountToken.Decimals)
+			return http.StatusBadRequest, "Amount is below the minimum for this pair. Try at least " + min + " " + amountToken.Ticker + "."
+		}
+		return http.StatusBadRequest, "Amount is below the minimum for this pair. Try a larger amount."
+	case apiErrUnsupportedPair:
+		return http.StatusBadRequest, "This token pair is not supported by NEAR Intents right now. Try a different pair or network."
+	case apiErrInvalidRecipient:
+		return http.StatusBadRequest, "The recipient address was rejected. Check that it is a valid address on the destination network."
+	case apiErrInvalidRefund:
+		return http.StatusBadRequest, "The refund address was rejected. Check that it is a valid address on the source network."
+	case apiErrBadDeadline:
+		return http.StatusBadRequest, "The quote deadline was rejected. Please start over to get a fresh quote."
+	case apiErrNoQuote:
+		return http.StatusBadGateway, "No market makers are currently offering a rate for this pair/amount. Try a larger amount or a different pair."
+	case apiErrUnauthorized, apiErrNotFound:
+		return http.StatusBadGateway, unavailable
+	}
+	return http.StatusBadRequest, "NEAR Intents rejected the request: " + apiErr.Message
+}
+
+// Per-attempt timeouts for each 1Click endpoint. Quotes are the slowest:
+// the API holds the request open for up to quoteWaitingTimeMs while solvers bid.
+const (
+	nearTimeoutTokens      = 20 * time.Second
+	nearTimeoutQuote       = 35 * time.Second
+	nearTimeoutStatus      = 10 * time.Second
+	nearTimeoutWithdrawals = 10 * time.Second
+)
+
+// Retry policy: exponential backoff with full jitter, capped at nearBackoffMax.
+// Variables rather than constants so tests can shorten them.
+var (
+	nearMaxAttempts = 3
+	nearBackoffBase = 500 * time.Millisecond
+	nearBackoffMax  = 5 * time.Second
+)
+
+// nearBreaker opens after 5 consecutive failed requests and fails fast for
+// 30s before letting a single probe through.
+var nearBreaker = newCircuitBreaker(5, 30*time.Second)
+
+// nearBackoff returns the delay before retry number attempt (0-based):
+// a random duration in [0, min(base*2^attempt, max)].
+func nearBackoff(attempt int) time.Duration {
+	d := nearBackoffBase << attempt
+	if d <= 0 || d > nearBackoffMax {
+		d = nearBackoffMax
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
 // nearRequest makes an authenticated request to the NEAR Intents API.
-// On 5xx responses it retries once after a short delay.
-func nearRequest(method, path string, body interface{}) ([]byte, error) {
+// Each attempt is bounded by timeout and by ctx. Transport errors, 5xx and
+// 429 responses fail over to the next endpoint in nearPool immediately;
+// once every endpoint has been tried, further retries back off
+// exponentially. Any other non-2xx response is returned immediately as an
+// *APIError. While nearBreaker is open the request fails fast with
+// errCircuitOpen. Use it only for requests that are safe to repeat.
+func nearRequest(ctx context.Context, method, path string, timeout time.Duration, body interface{}) ([]byte, error) {
+	data, _, err := nearRequestEndpoint(ctx, method, path, timeout, body, true)
+	return data, err
+}
+
+// nearRequestEndpoint is nearRequest that also returns the base URL of the
+// endpoint that answered, so a quote can record where it came from.
+//
+// A request that is not idempotent — a real quote opens a deposit address —
+// is only retried when it provably never left the client (nearNotSent):
+// after a 5xx or a timeout upstream may have acted on it, and a retry could
+// hand out a second address for the same swap.
+func nearRequestEndpoint(ctx context.Context, method, path string, timeout time.Duration, body interface{}, idempotent bool) ([]byte, string, error) {
 	var bodyBytes []byte
 	if body != nil {
 		b, err := json.Marshal(body)
 		if err != nil {
-			return nil, fmt.Errorf("marshal request: %w", err)
+			return nil, "", fmt.Errorf("marshal request: %w", err)
 		}
 		bodyBytes = b
 	}
 
-	const maxAttempts = 2
-	for attempt := 0; attempt < maxAttempts; attempt++ {
-		var bodyReader io.Reader
-		if bodyBytes != nil {
-			bodyReader = bytes.NewReader(bodyBytes)
-		}
+	if !nearBreaker.allow() {
+		return nil, "", errCircuitOpen
+	}
 
-		req, err := http.NewRequest(method, nearIntentsBaseURL+path, bodyReader)
-		if err != nil {
-			return nil, fmt.Errorf("create request: %w", err)
+	endpoints := nearPool.ordered()
+	attempts := nearMaxAttempts + len(endpoints) - 1
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt >= len(endpoints) {
+			select {
+			case <-time.After(nearBackoff(attempt - len(endpoints))):
+			case <-ctx.Done():
+				nearBreaker.release()
+				return nil, "", ctx.Err()
+			}
 		}
 
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Accept", "application/json")
-		if nearIntentsJWT != "" {
-			req.Header.Set("Authorization", "Bearer "+nearIntentsJWT)
+		ep := endpoints[attempt%len(endpoints)]
+		data, err := nearAttempt(ctx, ep.url, method, path, timeout, bodyBytes)
+		if err == nil {
+			nearPool.recordSuccess(ep)
+			nearBreaker.success()
+			return data, ep.url, nil
 		}
-
-		resp, err := nearHTTPClient.Do(req)
-		if err != nil {
-			if attempt < maxAttempts-1 {
-				time.Sleep(2 * time.Second)
-				continue
-			}
-			return nil, fmt.Errorf("request failed: %w", err)
+		if ctx.Err() != nil {
+			// The caller gave up; that says nothing about upstream health.
+			nearBreaker.release()
+			return nil, "", ctx.Err()
 		}
 
-		data, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			return nil, fmt.Errorf("read response: %w", err)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && !apiErr.Temporary() {
+			// A definitive answer from a healthy API.
+			nearPool.recordSuccess(ep)
+			nearBreaker.success()
+			return nil, "", err
 		}
-
-		if resp.StatusCode >= 500 && attempt < maxAttempts-1 {
-			time.Sleep(2 * time.Second)
-			continue
+		nearPool.recordFailure(ep, nearFailureReason(err))
+		lastErr = err
+		if !idempotent && !nearNotSent(err) {
+			break
 		}
+	}
 
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(data))
-		}
+	nearBreaker.failure(nearFailureReason(lastErr))
+	return nil, "", lastErr
+}
+
+// nearAttempt performs a single HTTP round trip against baseURL bounded by timeout.
+func nearAttempt(ctx context.Context, baseURL, method, path string, timeout time.Duration, bodyBytes []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var bodyReader io.Reader
+	if bodyBytes != nil {
+		bodyReader = bytes.NewReader(bodyBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, baseURL+path, bodyReader)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if nearIntentsJWT != "" {
+		req.Header.Set("Authorization", "Bearer "+nearIntentsJWT)
+	}
+
+	resp, err := nearHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
 
-		return data, nil
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
 	}
 
-	return nil, fmt.Errorf("request failed after %d attempts", maxAttempts)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, parseAPIError(resp.StatusCode, data)
+	}
+	return data, nil
+}
+
+// nearNotSent reports whether err means the request never reached the
+// server: the connection could not be opened, so nothing was written.
+func nearNotSent(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// nearFailureReason summarises a failed request for the breaker without
+// the request URL, which can carry deposit addresses.
+func nearFailureReason(err error) string {
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return fmt.Sprintf("HTTP %d", apiErr.StatusCode)
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	default:
+		return "connection error"
+	}
 }
 
 // fetchTokens retrieves the supported token list from NEAR Intents.
-func fetchTokens() ([]TokenInfo, error) {
-	data, err := nearRequest("GET", "/v0/tokens", nil)
+func fetchTokens(ctx context.Context) ([]TokenInfo, error) {
+	data, err := nearRequest(ctx, "GET", "/v0/tokens", nearTimeoutTokens, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -214,9 +472,9 @@ func fetchTokens() ([]TokenInfo, error) {
 }
 
 // requestDryQuote sends a dry quote request and parses the nested response.
-func requestDryQuote(req *QuoteRequest) (*DryQuoteResponse, error) {
+func requestDryQuote(ctx context.Context, req *QuoteRequest) (*DryQuoteResponse, error) {
 	req.Dry = true
-	data, err := nearRequest("POST", "/v0/quote", req)
+	data, err := nearRequest(ctx, "POST", "/v0/quote", nearTimeoutQuote, req)
 	if err != nil {
 		return nil, err
 	}
@@ -228,10 +486,12 @@ func requestDryQuote(req *QuoteRequest) (*DryQuoteResponse, error) {
 	return &resp, nil
 }
 
-// requestQuote sends a real (non-dry) quote request to NEAR Intents.
-func requestQuote(req *QuoteRequest) (*QuoteResponse, error) {
+// requestQuote sends a real (non-dry) quote request to NEAR Intents. Each
+// one opens a deposit address, so it is not retried once it may have
+// reached upstream.
+func requestQuote(ctx context.Context, req *QuoteRequest) (*QuoteResponse, error) {
 	req.Dry = false
-	data, err := nearRequest("POST", "/v0/quote", req)
+	data, endpoint, err := nearRequestEndpoint(ctx, "POST", "/v0/quote", nearTimeoutQuote, req, false)
 	if err != nil {
 		return nil, err
 	}
@@ -240,17 +500,23 @@ func requestQuote(req *QuoteRequest) (*QuoteResponse, error) {
 	if err := json.Unmarshal(data, &resp); err != nil {
 		return nil, fmt.Errorf("parse quote response: %w", err)
 	}
+	var raw struct {
+		Quote json.RawMessage `json:"quote"`
+	}
+	json.Unmarshal(data, &raw)
+	resp.rawQuote = raw.Quote
+	resp.endpoint = endpoint
 	return &resp, nil
 }
 
 // fetchStatus checks the status of a swap by deposit address.
 // depositMemo is optional but required for chains that use memos (TON, XRP, NEAR, Stellar).
-func fetchStatus(depositAddress, depositMemo string) (*StatusResponse, error) {
+func fetchStatus(ctx context.Context, depositAddress, depositMemo string) (*StatusResponse, error) {
 	q := url.Values{"depositAddress": {depositAddress}}
 	if depositMemo != "" {
 		q.Set("depositMemo", depositMemo)
 	}
-	data, err := nearRequest("GET", "/v0/status?"+q.Encode(), nil)
+	data, err := nearRequest(ctx, "GET", "/v0/status?"+q.Encode(), nearTimeoutStatus, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -287,9 +553,9 @@ type AnyInputWithdrawalsResponse struct {
 }
 
 // fetchAnyInputWithdrawals retrieves completed swap history for an ANY_INPUT deposit address.
-func fetchAnyInputWithdrawals(depositAddress string) (*AnyInputWithdrawalsResponse, error) {
+func fetchAnyInputWithdrawals(ctx context.Context, depositAddress string) (*AnyInputWithdrawalsResponse, error) {
 	q := url.Values{"depositAddress": {depositAddress}}
-	data, err := nearRequest("GET", "/v0/any-input/withdrawals?"+q.Encode(), nil)
+	data, err := nearRequest(ctx, "GET", "/v0/any-input/withdrawals?"+q.Encode(), nearTimeoutWithdrawals, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -299,4 +565,3 @@ func fetchAnyInputWithdrawals(depositAddress string) (*AnyInputWithdrawalsRespon
 	}
 	return &resp, nil
 }
-
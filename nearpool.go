@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultNearIntentsURL = "https://1click.chaindefuser.com"
+
+// Health checks run only when more than one endpoint is configured —
+// with a single endpoint there is nothing to fail over to.
+const (
+	nearHealthInterval = 30 * time.Second
+	nearHealthTimeout  = 5 * time.Second
+)
+
+// nearEndpoint is one 1Click API base URL and its observed health.
+type nearEndpoint struct {
+	url      string
+	priority int // position in NEAR_INTENTS_API_URL, 0 = primary
+
+	mu        sync.Mutex
+	healthy   bool
+	latency   time.Duration // smoothed health-check round trip
+	requests  int64
+	errors    int64
+	lastErr   string
+	lastCheck time.Time
+}
+
+// EndpointStatus is a point-in-time view of a nearEndpoint for /verify.
+type EndpointStatus struct {
+	URL       string
+	Healthy   bool
+	InUse     bool
+	Latency   string
+	Requests  int64
+	Errors    int64
+	LastError string
+	LastCheck string
+}
+
+// nearEndpointPool holds the configured endpoints in priority order.
+type nearEndpointPool struct {
+	mu        sync.RWMutex
+	endpoints []*nearEndpoint
+	lastUsed  *nearEndpoint
+}
+
+var nearPool = &nearEndpointPool{}
+
+func init() {
+	nearPool.set([]string{defaultNearIntentsURL})
+}
+
+// parseEndpointList splits NEAR_INTENTS_API_URL on commas and whitespace,
+// dropping trailing slashes and duplicates.
+func parseEndpointList(s string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\t'
+	}) {
+		f = strings.TrimRight(f, "/")
+		if f == "" || seen[f] {
+			continue
+		}
+		seen[f] = true
+		urls = append(urls, f)
+	}
+	return urls
+}
+
+// set replaces the endpoint list. Every endpoint starts out healthy.
+func (p *nearEndpointPool) set(urls []string) {
+	eps := make([]*nearEndpoint, len(urls))
+	for i, u := range urls {
+		eps[i] = &nearEndpoint{url: u, priority: i, healthy: true}
+	}
+	p.mu.Lock()
+	p.endpoints = eps
+	p.lastUsed = nil
+	p.mu.Unlock()
+}
+
+// urls returns the configured base URLs in priority order.
+func (p *nearEndpointPool) urls() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	urls := make([]string, len(p.endpoints))
+	for i, ep := range p.endpoints {
+		urls[i] = ep.url
+	}
+	return urls
+}
+
+// ordered returns the endpoints best-first: healthy before unhealthy, then
+// lowest measured latency, then configured priority.
+func (p *nearEndpointPool) ordered() []*nearEndpoint {
+	p.mu.RLock()
+	eps := append([]*nearEndpoint(nil), p.endpoints...)
+	p.mu.RUnlock()
+
+	type rank struct {
+		healthy bool
+		latency time.Duration
+	}
+	ranks := make(map[*nearEndpoint]rank, len(eps))
+	for _, ep := range eps {
+		ep.mu.Lock()
+		ranks[ep] = rank{ep.healthy, ep.latency}
+		ep.mu.Unlock()
+	}
+	sort.SliceStable(eps, func(i, j int) bool {
+		a, b := ranks[eps[i]], ranks[eps[j]]
+		if a.healthy != b.healthy {
+			return a.healthy
+		}
+		if a.latency != b.latency && a.latency > 0 && b.latency > 0 {
+			return a.latency < b.latency
+		}
+		return eps[i].priority < eps[j].priority
+	})
+	return eps
+}
+
+// recordSuccess marks ep healthy and remembers it as the endpoint in use.
+// Any definitive answer counts, including 4xx errors.
+func (p *nearEndpointPool) recordSuccess(ep *nearEndpoint) {
+	ep.mu.Lock()
+	ep.requests++
+	ep.healthy = true
+	ep.mu.Unlock()
+
+	p.mu.Lock()
+	p.lastUsed = ep
+	p.mu.Unlock()
+}
+
+// recordFailure marks ep unhealthy until its next successful request or
+// health check. reason is shown on /verify and must not contain user data.
+func (p *nearEndpointPool) recordFailure(ep *nearEndpoint, reason string) {
+	ep.mu.Lock()
+	ep.requests++
+	ep.errors++
+	ep.healthy = false
+	ep.lastErr = reason
+	ep.mu.Unlock()
+}
+
+// check probes every endpoint concurrently with GET /v0/tokens.
+func (p *nearEndpointPool) check(ctx context.Context) {
+	p.mu.RLock()
+	eps := append([]*nearEndpoint(nil), p.endpoints...)
+	p.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, ep := range eps {
+		wg.Add(1)
+		go func(ep *nearEndpoint) {
+			defer wg.Done()
+			ep.probe(ctx)
+		}(ep)
+	}
+	wg.Wait()
+}
+
+func (ep *nearEndpoint) probe(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, nearHealthTimeout)
+	defer cancel()
+
+	start := time.Now()
+	healthy, reason := false, ""
+	req, err := http.NewRequestWithContext(ctx, "GET", ep.url+"/v0/tokens", nil)
+	if err == nil {
+		req.Header.Set("Accept", "application/json")
+		if nearIntentsJWT != "" {
+			req.Header.Set("Authorization", "Bearer "+nearIntentsJWT)
+		}
+		var resp *http.Response
+		if resp, err = nearHTTPClient.Do(req); err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			healthy = resp.StatusCode < 500
+			if !healthy {
+				reason = fmt.Sprintf("HTTP %d", resp.StatusCode)
+			}
+		}
+	}
+	if err != nil {
+		reason = nearFailureReason(err)
+	}
+	rtt := time.Since(start)
+
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	ep.healthy = healthy
+	ep.lastCheck = time.Now()
+	if !healthy {
+		ep.lastErr = reason
+		return
+	}
+	if ep.latency == 0 {
+		ep.latency = rtt
+	} else {
+		ep.latency = (ep.latency*7 + rtt) / 8
+	}
+}
+
+// startHealthChecks probes all endpoints every nearHealthInterval.
+// It does nothing when only one endpoint is configured.
+func (p *nearEndpointPool) startHealthChecks() {
+	p.mu.RLock()
+	n := len(p.endpoints)
+	p.mu.RUnlock()
+	if n < 2 {
+		return
+	}
+	log.Printf("NEAR Intents: %d endpoints configured, health checks every %s", n, nearHealthInterval)
+	go func() {
+		for {
+			p.check(context.Background())
+			time.Sleep(nearHealthInterval)
+		}
+	}()
+}
+
+func (p *nearEndpointPool) status() []EndpointStatus {
+	p.mu.RLock()
+	eps := append([]*nearEndpoint(nil), p.endpoints...)
+	lastUsed := p.lastUsed
+	p.mu.RUnlock()
+
+	out := make([]EndpointStatus, 0, len(eps))
+	for _, ep := range eps {
+		ep.mu.Lock()
+		st := EndpointStatus{
+			URL:       redactEndpointURL(ep.url),
+			Healthy:   ep.healthy,
+			InUse:     ep == lastUsed,
+			Requests:  ep.requests,
+			Errors:    ep.errors,
+			LastError: ep.lastErr,
+		}
+		if ep.latency > 0 {
+			st.Latency = ep.latency.Round(time.Millisecond).String()
+		}
+		if !ep.lastCheck.IsZero() {
+			st.LastCheck = time.Since(ep.lastCheck).Round(time.Second).String()
+		}
+		ep.mu.Unlock()
+		out = append(out, st)
+	}
+	return out
+}
+
+// redactEndpointURL strips credentials and query strings so a relay URL
+// can be published on /verify.
+func redactEndpointURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "(invalid URL)"
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
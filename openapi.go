@@ -0,0 +1,478 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// OpenAPI document for the machine-readable routes.
+//
+// apiEndpoints lists every route integrators may call; the document served
+// at /api/openapi.json is generated from it and from the Go types the
+// handlers encode and decode, so field lists can't drift from the code.
+// TestOpenAPIMatchesHandlers checks the routes, methods and status codes.
+
+// apiEndpoint describes one documented operation.
+type apiEndpoint struct {
+	Method      string
+	Path        string // OpenAPI path template, e.g. /api/v1/orders/{token}
+	OperationID string
+	Summary     string
+	Description string
+	Params      []openAPIParameter
+	Request     interface{} // JSON body type, nil for none
+	Responses   []apiEndpointResponse
+	Passthrough bool // the 200 body is 1Click's response verbatim and may carry extra fields
+}
+
+// apiEndpointResponse is one documented response of an apiEndpoint.
+type apiEndpointResponse struct {
+	Status      int
+	Description string
+	Body        interface{} // JSON body type; nil for an HTML error page
+}
+
+var tokenPathParam = openAPIParameter{
+	Name: "token", In: "path", Required: true,
+	Description: "Order token, as returned by POST /api/v1/orders or found in an /order/ link.",
+	Schema:      &openAPISchema{Type: "string"},
+}
+
+// apiErrors lists the standard JSON error responses for the given statuses.
+func apiErrors(statuses ...int) []apiEndpointResponse {
+	desc := map[int]string{
+		http.StatusBadRequest:          "Invalid request; see error.code and error.field.",
+		http.StatusForbidden:           "The order is locked and X-Order-Key does not unlock it.",
+		http.StatusMethodNotAllowed:    "Wrong HTTP method.",
+		http.StatusTooManyRequests:     "Rate limited; retry after the Retry-After header.",
+		http.StatusInternalServerError: "Internal error.",
+		http.StatusBadGateway:          "NEAR Intents is unavailable, has no quote, or returned a quote that failed verification.",
+	}
+	var out []apiEndpointResponse
+	for _, s := range statuses {
+		out = append(out, apiEndpointResponse{Status: s, Description: desc[s], Body: APIErrorBody{}})
+	}
+	return out
+}
+
+var apiEndpoints = []apiEndpoint{
+	{
+		Method: "POST", Path: "/api/v1/quote", OperationID: "quote",
+		Summary:     "Dry quote",
+		Description: "Prices a swap without reserving a deposit address. Set amount for an exact-input swap or amountOut for an exact-output swap.",
+		Request:     APISwapRequest{},
+		Responses: append([]apiEndpointResponse{
+			{Status: http.StatusOK, Description: "Quote.", Body: APIQuote{}},
+		}, apiErrors(400, 405, 429, 502)...),
+	},
+	{
+		Method: "POST", Path: "/api/v1/orders", OperationID: "createOrder",
+		Summary:     "Create an order",
+		Description: "Requests a real quote, checks it against the request and returns the order token with the deposit details. Leave out both amounts for an ANY_INPUT address. With a passphrase the token is locked and the response carries its unlockKey.",
+		Request:     APISwapRequest{},
+		Responses: append([]apiEndpointResponse{
+			{Status: http.StatusCreated, Description: "Order created. Location points at its status.", Body: APIOrder{}},
+		}, apiErrors(400, 405, 429, 500, 502)...),
+	},
+	{
+		Method: "GET", Path: "/api/v1/orders/{token}", OperationID: "getOrder",
+		Summary:     "Order status",
+		Description: "Live status of an order. For a locked order, addresses and transactions are left out unless X-Order-Key is sent.",
+		Params: []openAPIParameter{tokenPathParam, {
+			Name: "X-Order-Key", In: "header",
+			Description: "unlockKey of a passphrase-locked order.",
+			Schema:      &openAPISchema{Type: "string"},
+		}},
+		Responses: append([]apiEndpointResponse{
+			{Status: http.StatusOK, Description: "Order status.", Body: APIOrderStatus{}},
+		}, apiErrors(400, 403, 405, 429)...),
+	},
+	{
+		Method: "GET", Path: "/api/v1/tokens", OperationID: "listTokens",
+		Summary:     "Supported tokens",
+		Description: "Every token that can be swapped. Use ticker and network in swap requests.",
+		Responses: append([]apiEndpointResponse{
+			{Status: http.StatusOK, Description: "Token list.", Body: APITokenList{}},
+		}, apiErrors(405, 429, 502)...),
+	},
+	{
+		Method: "GET", Path: "/order/{token}/raw", OperationID: "getOrderRaw",
+		Summary:     "Raw order status",
+		Description: "The NEAR Intents status response for an order, passed through unchanged. Not available for locked orders.",
+		Params:      []openAPIParameter{tokenPathParam},
+		Passthrough: true,
+		Responses: []apiEndpointResponse{
+			{Status: http.StatusOK, Description: "1Click status response.", Body: StatusResponse{}},
+			{Status: http.StatusBadRequest, Description: "Invalid order token (HTML page)."},
+			{Status: http.StatusForbidden, Description: "The order is locked (HTML page)."},
+		},
+	},
+	{
+		Method: "GET", Path: "/order/{token}/attestation", OperationID: "getAttestation",
+		Summary:     "Signed quote attestation",
+		Description: "The Ed25519-signed record of the quote request and response, kept for 24 hours. Locked orders need a POST with the unlock key as form field key.",
+		Params:      []openAPIParameter{tokenPathParam},
+		Responses: []apiEndpointResponse{
+			{Status: http.StatusOK, Description: "Attestation.", Body: Attestation{}},
+			{Status: http.StatusBadRequest, Description: "Invalid order token (HTML page)."},
+			{Status: http.StatusForbidden, Description: "The order is locked (HTML page)."},
+			{Status: http.StatusNotFound, Description: "No attestation is kept for this order (HTML page)."},
+		},
+	},
+	{
+		Method: "GET", Path: "/api/openapi.json", OperationID: "openapi",
+		Summary:     "This document",
+		Description: "The OpenAPI description of these routes. A readable version is at /api/docs.",
+		Responses: []apiEndpointResponse{
+			{Status: http.StatusOK, Description: "OpenAPI 3.0 document.", Body: json.RawMessage(nil)},
+		},
+	},
+}
+
+// openAPIFieldOverrides documents fields whose Go type doesn't say what
+// they hold.
+var openAPIFieldOverrides = map[string]reflect.Type{
+	"Attestation.Payload": reflect.TypeOf(AttestationPayload{}),
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*openAPISchema `json:"schemas"`
+}
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary"`
+	Description string                      `json:"description,omitempty"`
+	Parameters  []openAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Required    bool           `json:"required,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                    `json:"required"`
+	Content  map[string]openAPIMedia `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                  `json:"description"`
+	Content     map[string]openAPIMedia `json:"content,omitempty"`
+}
+
+type openAPIMedia struct {
+	Schema *openAPISchema `json:"schema,omitempty"`
+}
+
+type openAPISchema struct {
+	Ref        string                    `json:"$ref,omitempty"`
+	Type       string                    `json:"type,omitempty"`
+	Format     string                    `json:"format,omitempty"`
+	Properties map[string]*openAPISchema `json:"properties,omitempty"`
+	Required   []string                  `json:"required,omitempty"`
+	Items      *openAPISchema            `json:"items,omitempty"`
+
+	fields []string // property names in struct order, for /api/docs
+}
+
+const openAPISchemaPrefix = "#/components/schemas/"
+
+// openAPISchemas generates component schemas from Go types, following
+// encoding/json's rules for names, omitempty and embedded structs.
+type openAPISchemas map[string]*openAPISchema
+
+func (s openAPISchemas) of(t reflect.Type) *openAPISchema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return s.of(t.Elem())
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &openAPISchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &openAPISchema{Type: "number"}
+	case reflect.Slice:
+		if t == reflect.TypeOf(json.RawMessage(nil)) {
+			return &openAPISchema{} // any JSON value
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &openAPISchema{Type: "string", Format: "byte"}
+		}
+		return &openAPISchema{Type: "array", Items: s.of(t.Elem())}
+	case reflect.Map:
+		return &openAPISchema{Type: "object"}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		if _, ok := s[t.Name()]; !ok {
+			s[t.Name()] = &openAPISchema{} // placeholder for recursive types
+			*s[t.Name()] = *s.object(t)
+		}
+		return &openAPISchema{Ref: openAPISchemaPrefix + t.Name()}
+	}
+	return &openAPISchema{}
+}
+
+func (s openAPISchemas) object(t reflect.Type) *openAPISchema {
+	obj := &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{}}
+	s.addFields(obj, t)
+	return obj
+}
+
+func (s openAPISchemas) addFields(obj *openAPISchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			s.addFields(obj, f.Type)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		ft := f.Type
+		if override, ok := openAPIFieldOverrides[t.Name()+"."+f.Name]; ok {
+			ft = override
+		}
+		obj.Properties[name] = s.of(ft)
+		obj.fields = append(obj.fields, name)
+		if !strings.Contains(opts, "omitempty") {
+			obj.Required = append(obj.Required, name)
+		}
+	}
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  *openAPIDocument
+	openAPIJSON []byte
+)
+
+// openAPISpec returns the generated document and its JSON encoding.
+func openAPISpec() (*openAPIDocument, []byte) {
+	openAPIOnce.Do(func() {
+		openAPIDoc = buildOpenAPI()
+		openAPIJSON, _ = json.MarshalIndent(openAPIDoc, "", "  ")
+	})
+	return openAPIDoc, openAPIJSON
+}
+
+func buildOpenAPI() *openAPIDocument {
+	schemas := openAPISchemas{}
+	doc := &openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:       "uSwap Zero API",
+			Version:     "1",
+			Description: "Zero-fee swaps through NEAR Intents. Orders live only in their encrypted token; nothing is stored or logged, and appFees is always empty. Rate limits apply per route and client /24.",
+		},
+		Paths:      map[string]map[string]*openAPIOperation{},
+		Components: openAPIComponents{Schemas: schemas},
+	}
+
+	for _, ep := range apiEndpoints {
+		op := &openAPIOperation{
+			OperationID: ep.OperationID,
+			Summary:     ep.Summary,
+			Description: ep.Description,
+			Parameters:  ep.Params,
+			Responses:   map[string]*openAPIResponse{},
+		}
+		if ep.Request != nil {
+			op.RequestBody = &openAPIRequestBody{
+				Required: true,
+				Content:  map[string]openAPIMedia{"application/json": {Schema: schemas.of(reflect.TypeOf(ep.Request))}},
+			}
+		}
+		for _, r := range ep.Responses {
+			resp := &openAPIResponse{Description: r.Description}
+			if r.Body != nil {
+				resp.Content = map[string]openAPIMedia{"application/json": {Schema: schemas.of(reflect.TypeOf(r.Body))}}
+			} else {
+				resp.Content = map[string]openAPIMedia{"text/html": {}}
+			}
+			op.Responses[strconv.Itoa(r.Status)] = resp
+		}
+		if doc.Paths[ep.Path] == nil {
+			doc.Paths[ep.Path] = map[string]*openAPIOperation{}
+		}
+		doc.Paths[ep.Path][strings.ToLower(ep.Method)] = op
+	}
+	return doc
+}
+
+// handleOpenAPI serves /api/openapi.json.
+func handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if !apiMethod(w, r, http.MethodGet) {
+		return
+	}
+	_, spec := openAPISpec()
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(spec)
+}
+
+// APIDocsPageData is the data for the /api/docs reference page.
+type APIDocsPageData struct {
+	PageData
+	Info       openAPIInfo
+	Operations []apiDocOperation
+	Schemas    []apiDocSchema
+}
+
+type apiDocOperation struct {
+	Method      string
+	Path        string
+	ID          string
+	Summary     string
+	Description string
+	Params      []openAPIParameter
+	Request     apiDocType
+	Responses   []apiDocResponse
+}
+
+type apiDocResponse struct {
+	Status      string
+	Description string
+	Body        apiDocType
+	HTML        bool
+}
+
+type apiDocSchema struct {
+	Name   string
+	Fields []apiDocField
+}
+
+type apiDocField struct {
+	Name     string
+	Type     apiDocType
+	Required bool
+}
+
+// apiDocType is a schema type for display: Text, linking to the Ref
+// schema when there is one.
+type apiDocType struct {
+	Text string
+	Ref  string
+}
+
+func docType(l *locale, s *openAPISchema) apiDocType {
+	switch {
+	case s == nil:
+		return apiDocType{}
+	case s.Ref != "":
+		name := strings.TrimPrefix(s.Ref, openAPISchemaPrefix)
+		return apiDocType{Text: name, Ref: name}
+	case s.Type == "array":
+		t := docType(l, s.Items)
+		t.Text = l.T("array of %s", t.Text)
+		return t
+	case s.Type == "":
+		return apiDocType{Text: "any"}
+	case s.Format != "":
+		return apiDocType{Text: s.Type + " (" + s.Format + ")"}
+	}
+	return apiDocType{Text: s.Type}
+}
+
+// apiDocsPage lays out the generated document for the HTML reference,
+// keeping apiEndpoints' order for operations and sorting schemas by name.
+// Its descriptions are translated into the page's language; the JSON
+// document stays in English.
+func apiDocsPage(r *http.Request) APIDocsPageData {
+	doc, _ := openAPISpec()
+	data := APIDocsPageData{PageData: newPageData(r, "API Reference"), Info: doc.Info}
+	l := data.Loc
+	data.Info.Description = l.T(doc.Info.Description)
+
+	for _, ep := range apiEndpoints {
+		op := doc.Paths[ep.Path][strings.ToLower(ep.Method)]
+		d := apiDocOperation{
+			Method:      ep.Method,
+			Path:        ep.Path,
+			ID:          op.OperationID,
+			Summary:     l.T(op.Summary),
+			Description: l.T(op.Description),
+		}
+		for _, p := range op.Parameters {
+			p.Description = l.T(p.Description)
+			d.Params = append(d.Params, p)
+		}
+		if op.RequestBody != nil {
+			d.Request = docType(l, op.RequestBody.Content["application/json"].Schema)
+		}
+		codes := make([]string, 0, len(op.Responses))
+		for code := range op.Responses {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			resp := op.Responses[code]
+			media, isJSON := resp.Content["application/json"]
+			d.Responses = append(d.Responses, apiDocResponse{
+				Status:      code,
+				Description: l.T(resp.Description),
+				Body:        docType(l, media.Schema),
+				HTML:        !isJSON,
+			})
+		}
+		data.Operations = append(data.Operations, d)
+	}
+
+	names := make([]string, 0, len(doc.Components.Schemas))
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s := doc.Components.Schemas[name]
+		required := map[string]bool{}
+		for _, r := range s.Required {
+			required[r] = true
+		}
+		schema := apiDocSchema{Name: name}
+		for _, f := range s.fields {
+			schema.Fields = append(schema.Fields, apiDocField{Name: f, Type: docType(l, s.Properties[f]), Required: required[f]})
+		}
+		data.Schemas = append(data.Schemas, schema)
+	}
+	return data
+}
+
+// handleAPIDocs renders the API reference from the OpenAPI document.
+func handleAPIDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	templates.ExecuteTemplate(w, "api_docs.html", apiDocsPage(r))
+}
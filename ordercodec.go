@@ -0,0 +1,470 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Compact binary encoding for OrderData, used as the plaintext of v2 order
+// tokens. It roughly halves token length compared to JSON.
+//
+//	uvarint   presence bitmap, bit i set when orderFields[i] is non-empty
+//	value...  one per present field, in orderFields order
+//
+// Each value starts with a kind byte. Kinds >= valSymbolBase are a single-byte
+// reference into orderSymbols; everything else is followed by a payload:
+//
+//	valRaw        uvarint len | bytes
+//	valHex0x      uvarint len | bytes                 "0x" + lowercase hex
+//	valHex0xCase  uvarint len | bytes | case mask     "0x" + mixed-case hex (EIP-55)
+//	valHex        uvarint len | bytes                 bare lowercase hex
+//	valBase58     uvarint len | bytes                 Bitcoin-alphabet base58
+//	valBech32     uvarint hrp len | hrp | uvarint n | 5-bit packed data
+//	valBase64URL  uvarint len | bytes                 unpadded base64url (TON)
+//	valBase64Std  uvarint len | bytes                 unpadded std base64 (TON)
+//	valUUID       16 bytes                            lowercase 8-4-4-4-12
+//	valDecimal    uvarint scale | uvarint len | big-endian mantissa
+//	valTimeSec    uvarint unix seconds                RFC 3339, UTC, no fraction
+//	valTimeMilli  uvarint unix milliseconds           RFC 3339, UTC, .000 fraction
+//
+// The encoder tries every kind that applies and keeps the shortest one that
+// decodes back to the exact input string, so any string round-trips.
+const (
+	valRaw byte = iota
+	valHex0x
+	valHex0xCase
+	valHex
+	valBase58
+	valBech32
+	valBase64URL
+	valBase64Std
+	valUUID
+	valDecimal
+	valTimeSec
+	valTimeMilli
+
+	valSymbolBase byte = 0x20
+)
+
+// orderSymbols is the shared ticker/network/keyword dictionary.
+// APPEND ONLY: the index is baked into every v2 token in circulation.
+var orderSymbols = []string{
+	// Tickers
+	"ETH", "BTC", "USDT", "USDC", "SOL", "NEAR", "TON", "TRX", "DOGE", "XRP",
+	"BNB", "POL", "AVAX", "ARB", "OP", "SUI", "APT", "LTC", "BCH", "XLM",
+	"ZEC", "ADA", "WBTC", "WETH", "DAI", "wNEAR", "AURORA", "BERA", "MON", "STRK",
+	"GNO", "XDAI", "CBBTC", "PEPE", "SHIB", "LINK", "UNI", "AAVE", "TRUMP", "USD1",
+	// Networks (API chain codes)
+	"eth", "btc", "sol", "base", "arb", "ton", "tron", "bsc", "pol", "op",
+	"avax", "near", "sui", "apt", "aptos", "doge", "ltc", "xrp", "bch", "xlm",
+	"stellar", "zec", "cardano", "starknet", "gnosis", "bera", "monad", "plasma", "xlayer", "aleo",
+	// Swap types and placeholder amounts
+	"FLEX_INPUT", "EXACT_INPUT", "EXACT_OUTPUT", "ANY_INPUT", "any", "market rate",
+	// Upstream endpoints
+	defaultNearIntentsURL,
+}
+
+var orderSymbolIndex = func() map[string]byte {
+	if len(orderSymbols) > 256-int(valSymbolBase) {
+		panic("orderSymbols: too many entries")
+	}
+	m := make(map[string]byte, len(orderSymbols))
+	for i, s := range orderSymbols {
+		m[s] = valSymbolBase + byte(i)
+	}
+	return m
+}()
+
+// orderFields lists OrderData fields in wire order.
+// APPEND ONLY: a field's position is its presence bit.
+var orderFields = []func(*OrderData) *string{
+	func(o *OrderData) *string { return &o.DepositAddr },
+	func(o *OrderData) *string { return &o.Memo },
+	func(o *OrderData) *string { return &o.FromTicker },
+	func(o *OrderData) *string { return &o.FromNet },
+	func(o *OrderData) *string { return &o.ToTicker },
+	func(o *OrderData) *string { return &o.ToNet },
+	func(o *OrderData) *string { return &o.AmountIn },
+	func(o *OrderData) *string { return &o.AmountOut },
+	func(o *OrderData) *string { return &o.Deadline },
+	func(o *OrderData) *string { return &o.CorrID },
+	func(o *OrderData) *string { return &o.RefundAddr },
+	func(o *OrderData) *string { return &o.RecvAddr },
+	func(o *OrderData) *string { return &o.SwapType },
+	func(o *OrderData) *string { return &o.Upstream },
+}
+
+var errShortOrder = errors.New("order data truncated")
+
+// encodeOrderBinary packs order data into the compact binary form.
+func encodeOrderBinary(o *OrderData) []byte {
+	var present uint64
+	for i, f := range orderFields {
+		if *f(o) != "" {
+			present |= 1 << i
+		}
+	}
+	buf := binary.AppendUvarint(nil, present)
+	for _, f := range orderFields {
+		if s := *f(o); s != "" {
+			buf = appendOrderValue(buf, s)
+		}
+	}
+	return buf
+}
+
+// decodeOrderBinary unpacks data produced by encodeOrderBinary.
+func decodeOrderBinary(b []byte) (*OrderData, error) {
+	r := &orderReader{b: b}
+	present := r.uvarint()
+	if present>>len(orderFields) != 0 {
+		return nil, fmt.Errorf("unknown order fields %#x", present)
+	}
+	var o OrderData
+	for i, f := range orderFields {
+		if present&(1<<i) != 0 {
+			*f(&o) = r.value()
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(r.b) != 0 {
+		return nil, fmt.Errorf("%d trailing bytes after order data", len(r.b))
+	}
+	return &o, nil
+}
+
+// appendOrderValue appends the shortest exact encoding of s.
+func appendOrderValue(buf []byte, s string) []byte {
+	if sym, ok := orderSymbolIndex[s]; ok {
+		return append(buf, sym)
+	}
+	best := appendBytes([]byte{valRaw}, []byte(s))
+	for _, enc := range orderValueEncoders {
+		v := enc(s)
+		if v == nil || len(v) >= len(best) {
+			continue
+		}
+		if got, err := (&orderReader{b: v}).valueChecked(); err == nil && got == s {
+			best = v
+		}
+	}
+	return append(buf, best...)
+}
+
+var (
+	decimalRe = regexp.MustCompile(`^(0|[1-9][0-9]*)(\.[0-9]+)?$`)
+	uuidRe    = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	bech32Re  = regexp.MustCompile(`^([a-z0-9]{1,83})1([qpzry9x8gf2tvdw0s3jn54khce6mua7l]{6,})$`)
+)
+
+const (
+	bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	iso8601Milli  = "2006-01-02T15:04:05.000Z07:00"
+)
+
+// orderValueEncoders are the candidate encodings tried by appendOrderValue.
+// Each returns nil when it does not apply; appendOrderValue verifies the
+// round trip, so encoders may be optimistic.
+var orderValueEncoders = []func(string) []byte{
+	func(s string) []byte {
+		rest, ok := strings.CutPrefix(s, "0x")
+		if !ok || rest != strings.ToLower(rest) {
+			return nil
+		}
+		b, err := hex.DecodeString(rest)
+		if err != nil {
+			return nil
+		}
+		return appendBytes([]byte{valHex0x}, b)
+	},
+	func(s string) []byte {
+		rest, ok := strings.CutPrefix(s, "0x")
+		if !ok || rest == strings.ToLower(rest) {
+			return nil
+		}
+		b, err := hex.DecodeString(rest)
+		if err != nil {
+			return nil
+		}
+		mask := make([]byte, (len(rest)+7)/8)
+		for i := 0; i < len(rest); i++ {
+			if rest[i] >= 'A' && rest[i] <= 'F' {
+				mask[i/8] |= 1 << (i % 8)
+			}
+		}
+		return append(appendBytes([]byte{valHex0xCase}, b), mask...)
+	},
+	func(s string) []byte {
+		if s != strings.ToLower(s) {
+			return nil
+		}
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return nil
+		}
+		return appendBytes([]byte{valHex}, b)
+	},
+	func(s string) []byte {
+		b, ok := base58Decode(s)
+		if !ok {
+			return nil
+		}
+		return appendBytes([]byte{valBase58}, b)
+	},
+	func(s string) []byte {
+		m := bech32Re.FindStringSubmatch(s)
+		if m == nil {
+			return nil
+		}
+		hrp, data := m[1], m[2]
+		out := appendBytes([]byte{valBech32}, []byte(hrp))
+		out = binary.AppendUvarint(out, uint64(len(data)))
+		var acc, bits uint
+		for i := 0; i < len(data); i++ {
+			acc = acc<<5 | uint(strings.IndexByte(bech32Charset, data[i]))
+			bits += 5
+			for bits >= 8 {
+				bits -= 8
+				out = append(out, byte(acc>>bits))
+			}
+		}
+		if bits > 0 {
+			out = append(out, byte(acc<<(8-bits)))
+		}
+		return out
+	},
+	func(s string) []byte {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return nil
+		}
+		return appendBytes([]byte{valBase64URL}, b)
+	},
+	func(s string) []byte {
+		b, err := base64.RawStdEncoding.DecodeString(s)
+		if err != nil {
+			return nil
+		}
+		return appendBytes([]byte{valBase64Std}, b)
+	},
+	func(s string) []byte {
+		if !uuidRe.MatchString(s) {
+			return nil
+		}
+		b, _ := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+		return append([]byte{valUUID}, b...)
+	},
+	func(s string) []byte {
+		m := decimalRe.FindStringSubmatch(s)
+		if m == nil {
+			return nil
+		}
+		frac := strings.TrimPrefix(m[2], ".")
+		mant, ok := new(big.Int).SetString(m[1]+frac, 10)
+		if !ok {
+			return nil
+		}
+		out := binary.AppendUvarint([]byte{valDecimal}, uint64(len(frac)))
+		return appendBytes(out, mant.Bytes())
+	},
+	func(s string) []byte {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil || t.Unix() < 0 {
+			return nil
+		}
+		return binary.AppendUvarint([]byte{valTimeSec}, uint64(t.Unix()))
+	},
+	func(s string) []byte {
+		t, err := time.Parse(iso8601Milli, s)
+		if err != nil || t.UnixMilli() < 0 {
+			return nil
+		}
+		return binary.AppendUvarint([]byte{valTimeMilli}, uint64(t.UnixMilli()))
+	},
+}
+
+func appendBytes(buf, b []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+// orderReader decodes values, remembering the first error.
+type orderReader struct {
+	b   []byte
+	err error
+}
+
+func (r *orderReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+	r.b = nil
+}
+
+func (r *orderReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.fail(errShortOrder)
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *orderReader) take(n uint64) []byte {
+	if uint64(len(r.b)) < n {
+		r.fail(errShortOrder)
+		return nil
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *orderReader) bytes() []byte {
+	return r.take(r.uvarint())
+}
+
+func (r *orderReader) valueChecked() (string, error) {
+	v := r.value()
+	if r.err == nil && len(r.b) != 0 {
+		r.err = errors.New("trailing bytes")
+	}
+	return v, r.err
+}
+
+func (r *orderReader) value() string {
+	kind := r.take(1)
+	if kind == nil {
+		return ""
+	}
+	if k := kind[0]; k >= valSymbolBase {
+		i := int(k - valSymbolBase)
+		if i >= len(orderSymbols) {
+			r.fail(fmt.Errorf("unknown symbol %d", i))
+			return ""
+		}
+		return orderSymbols[i]
+	}
+
+	switch kind[0] {
+	case valRaw:
+		return string(r.bytes())
+	case valHex0x:
+		return "0x" + hex.EncodeToString(r.bytes())
+	case valHex0xCase:
+		h := []byte(hex.EncodeToString(r.bytes()))
+		mask := r.take(uint64(len(h)+7) / 8)
+		for i := range h {
+			if mask != nil && mask[i/8]&(1<<(i%8)) != 0 {
+				h[i] = strings.ToUpper(string(h[i]))[0]
+			}
+		}
+		return "0x" + string(h)
+	case valHex:
+		return hex.EncodeToString(r.bytes())
+	case valBase58:
+		return base58Encode(r.bytes())
+	case valBech32:
+		hrp := string(r.bytes())
+		n := r.uvarint()
+		packed := r.take((n*5 + 7) / 8)
+		data := make([]byte, 0, n)
+		var acc, bits uint
+		for _, b := range packed {
+			acc = acc<<8 | uint(b)
+			bits += 8
+			for bits >= 5 && uint64(len(data)) < n {
+				bits -= 5
+				data = append(data, bech32Charset[(acc>>bits)&31])
+			}
+		}
+		return hrp + "1" + string(data)
+	case valBase64URL:
+		return base64.RawURLEncoding.EncodeToString(r.bytes())
+	case valBase64Std:
+		return base64.RawStdEncoding.EncodeToString(r.bytes())
+	case valUUID:
+		h := hex.EncodeToString(r.take(16))
+		if len(h) != 32 {
+			return ""
+		}
+		return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+	case valDecimal:
+		scale := int(r.uvarint())
+		digits := new(big.Int).SetBytes(r.bytes()).String()
+		if scale > 1<<10 {
+			r.fail(errors.New("decimal scale out of range"))
+			return ""
+		}
+		if len(digits) <= scale {
+			digits = strings.Repeat("0", scale-len(digits)+1) + digits
+		}
+		if scale == 0 {
+			return digits
+		}
+		return digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+	case valTimeSec:
+		return time.Unix(int64(r.uvarint()), 0).UTC().Format(time.RFC3339)
+	case valTimeMilli:
+		return time.UnixMilli(int64(r.uvarint())).UTC().Format(iso8601Milli)
+	}
+	r.fail(fmt.Errorf("unknown value kind %d", kind[0]))
+	return ""
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base58Encode encodes b with the Bitcoin alphabet.
+func base58Encode(b []byte) string {
+	zeros := 0
+	for zeros < len(b) && b[zeros] == 0 {
+		zeros++
+	}
+	n := new(big.Int).SetBytes(b)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for i := 0; i < zeros; i++ {
+		out = append(out, '1')
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// base58Decode decodes a Bitcoin-alphabet base58 string.
+func base58Decode(s string) ([]byte, bool) {
+	if s == "" {
+		return nil, false
+	}
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for i := 0; i < len(s); i++ {
+		d := strings.IndexByte(base58Alphabet, s[i])
+		if d < 0 {
+			return nil, false
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(d)))
+	}
+	zeros := 0
+	for zeros < len(s) && s[zeros] == '1' {
+		zeros++
+	}
+	return append(make([]byte, zeros), n.Bytes()...), true
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Live order status over Server-Sent Events.
+//
+// /order/{token}/events polls orderStatuses on the page's behalf and pushes
+// only what changed, so an open order page no longer reloads every 10 seconds:
+//
+//	event: status      {"status","step","label","terminal"} on every transition
+//	event: tx          {"chain","hash","url"} for each new origin/destination tx
+//	event: withdrawal  AnyInputWithdrawal, for each new ANY_INPUT swap
+//	event: end         the order reached a terminal status; stream closes
+//
+// Locked orders only get status events: the page hides their transactions.
+
+// Variables rather than constants so tests can shorten them.
+var (
+	orderEventInterval = 5 * time.Second  // 1Click poll interval per stream
+	orderEventMaxAge   = 15 * time.Minute // streams close after this; EventSource reconnects
+)
+
+// orderEventMaxStreams bounds concurrent streams across all clients.
+const orderEventMaxStreams = 500
+
+var orderEventStreams int64
+
+// orderStatusEvent is the data of a "status" event.
+type orderStatusEvent struct {
+	Status   string `json:"status"`
+	Step     int    `json:"step"`  // 0=pending, 1=processing, 2=complete
+	Label    string `json:"label"` // final step label: Complete, Refunded or Failed
+	Terminal bool   `json:"terminal"`
+}
+
+// orderTxEvent is the data of a "tx" event.
+type orderTxEvent struct {
+	Chain string `json:"chain"` // "origin" or "destination"
+	Hash  string `json:"hash"`
+	URL   string `json:"url"`
+}
+
+// orderStatusStep maps a 1Click status to the order page's stepper.
+func orderStatusStep(status string) (step int, terminal bool) {
+	switch status {
+	case "PROCESSING":
+		return 1, false
+	case "SUCCESS", "REFUNDED", "FAILED", "INCOMPLETE_DEPOSIT":
+		return 2, true
+	}
+	return 0, false
+}
+
+// orderStepLabel is the label of the stepper's last step for status.
+func orderStepLabel(status string) string {
+	switch status {
+	case "REFUNDED":
+		return "Refunded"
+	case "FAILED":
+		return "Failed"
+	}
+	return "Complete"
+}
+
+// streamOrderEvents serves /order/{token}/events for order.
+func streamOrderEvents(w http.ResponseWriter, r *http.Request, order *OrderData, locked bool) {
+	if !limiter.allowScoped("events", clientIP(r), 30, time.Minute) {
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+		return
+	}
+	if atomic.AddInt64(&orderEventStreams, 1) > orderEventMaxStreams {
+		atomic.AddInt64(&orderEventStreams, -1)
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, "Too many open streams", http.StatusServiceUnavailable)
+		return
+	}
+	defer atomic.AddInt64(&orderEventStreams, -1)
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no") // don't let a proxy buffer the stream
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", (10 * time.Second).Milliseconds())
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	send := func(event string, v interface{}) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	}
+
+	lastStatus := ""
+	seen := make(map[string]bool) // tx hashes and withdrawals already sent
+	ticker := time.NewTicker(orderEventInterval)
+	defer ticker.Stop()
+	deadline := time.After(orderEventMaxAge)
+
+	for {
+		if res, err := orderStatuses.get(r.Context(), order); err == nil {
+			if res.Status.Status != lastStatus {
+				lastStatus = res.Status.Status
+				step, terminal := orderStatusStep(lastStatus)
+				send("status", orderStatusEvent{Status: lastStatus, Step: step, Label: orderStepLabel(lastStatus), Terminal: terminal})
+			}
+			if d := res.Status.SwapDetails; d != nil && !locked {
+				for _, tx := range d.OriginTxs {
+					if !seen["tx:"+tx.Hash] {
+						seen["tx:"+tx.Hash] = true
+						send("tx", orderTxEvent{Chain: "origin", Hash: tx.Hash, URL: tx.ExplorerURL})
+					}
+				}
+				for _, tx := range d.DestTxs {
+					if !seen["tx:"+tx.Hash] {
+						seen["tx:"+tx.Hash] = true
+						send("tx", orderTxEvent{Chain: "destination", Hash: tx.Hash, URL: tx.ExplorerURL})
+					}
+				}
+			}
+			if res.Withdrawals != nil && !locked {
+				for i, wdl := range res.Withdrawals.Withdrawals {
+					key := "wd:" + wdl.Hash
+					if wdl.Hash == "" {
+						key = "wd#" + strconv.Itoa(i)
+					}
+					if !seen[key] {
+						seen[key] = true
+						send("withdrawal", wdl)
+					}
+				}
+			}
+		}
+		if _, terminal := orderStatusStep(lastStatus); terminal {
+			send("end", struct{}{})
+			rc.Flush()
+			return
+		}
+		fmt.Fprint(w, ": keepalive\n\n")
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-deadline:
+			return
+		case <-shuttingDown:
+			return
+		case <-ticker.C:
+		}
+	}
+}
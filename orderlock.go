@@ -0,0 +1,244 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Passphrase-locked order tokens.
+//
+// A locked token is a v3 token: the server-sealed plaintext holds a public
+// subset of the order (enough to poll status) plus the full OrderData sealed
+// a second time under a key derived from the user's passphrase:
+//
+//	uvarint len | encodeOrderBinary(public subset)
+//	log2 space | time | delta                 (KDF parameters, 1 byte each)
+//	salt (16) | IV (12) | AES-GCM(full order, additional data = salt)
+//
+// The public subset carries tickers, networks, amounts, deadline, swap type
+// and upstream endpoint, plus the deposit address and memo so the server can
+// still fetch status — those two are never rendered until the order is
+// unlocked.
+const orderTokenV3Locked byte = 0x03
+
+const orderLockSaltSize = 16
+
+// orderLockMinPassphrase is the shortest passphrase accepted for new locks.
+const orderLockMinPassphrase = 8
+
+// errWrongPassphrase is returned when the passphrase or unlock key does not
+// open the locked part of a token.
+var errWrongPassphrase = errors.New("wrong passphrase")
+
+// balloonParams are the Balloon hashing cost parameters. They travel in the
+// token, so raising them only affects new locks.
+type balloonParams struct {
+	LogSpace byte // buffer holds 2^LogSpace 32-byte blocks
+	Time     byte // mixing rounds over the buffer
+	Delta    byte // pseudo-random dependencies per block per round
+}
+
+// orderLockParams is the cost for new locks: a 4 MiB buffer, ~0.5s on
+// one core. A variable so tests can lower it.
+var orderLockParams = balloonParams{LogSpace: 17, Time: 2, Delta: 3}
+
+// orderLock is the sealed, passphrase-protected part of a locked token.
+type orderLock struct {
+	params balloonParams
+	salt   []byte
+	iv     []byte
+	sealed []byte
+}
+
+// Locked reports whether o came from a passphrase-locked token and has not
+// been unlocked; only the public subset of its fields is populated.
+func (o *OrderData) Locked() bool {
+	return o.lock != nil
+}
+
+// balloonKDF derives a 32-byte key with Balloon hashing (Boneh, Corrigan-Gibbs
+// and Schechter, 2016) over SHA-256. The buffer must be held in full for the
+// whole computation, which makes it memory-hard; memory access order depends
+// only on the salt.
+func balloonKDF(passphrase, salt []byte, p balloonParams) []byte {
+	const blockSize = sha256.Size
+	space := 1 << p.LogSpace
+	buf := make([]byte, space*blockSize)
+	block := func(i int) []byte { return buf[i*blockSize : (i+1)*blockSize] }
+
+	h := sha256.New()
+	var cnt uint64
+	var num [8]byte
+	hash := func(dst []byte, parts ...[]byte) {
+		h.Reset()
+		binary.LittleEndian.PutUint64(num[:], cnt)
+		cnt++
+		h.Write(num[:])
+		for _, part := range parts {
+			h.Write(part)
+		}
+		h.Sum(dst[:0])
+	}
+
+	// Expand
+	hash(block(0), passphrase, salt)
+	for m := 1; m < space; m++ {
+		hash(block(m), block(m-1))
+	}
+
+	// Mix
+	var idx [24]byte
+	var other [blockSize]byte
+	for t := 0; t < int(p.Time); t++ {
+		for m := 0; m < space; m++ {
+			hash(block(m), block((m+space-1)%space), block(m))
+			for i := 0; i < int(p.Delta); i++ {
+				binary.LittleEndian.PutUint64(idx[0:], uint64(t))
+				binary.LittleEndian.PutUint64(idx[8:], uint64(m))
+				binary.LittleEndian.PutUint64(idx[16:], uint64(i))
+				hash(other[:], salt, idx[:])
+				j := int(binary.LittleEndian.Uint64(other[:8]) % uint64(space))
+				hash(block(m), block(m), block(j))
+			}
+		}
+	}
+
+	// Domain-separate the output from the buffer contents.
+	key := sha256.Sum256(append([]byte("uswap-zero order lock v1\x00"), block(space-1)...))
+	return key[:]
+}
+
+// encryptLockedOrderData seals data into a v3 token whose address details
+// need passphrase to read. It also returns the derived unlock key so the
+// caller can show the unlocked order without running the KDF again.
+func encryptLockedOrderData(data *OrderData, passphrase string) (string, []byte, error) {
+	salt := make([]byte, orderLockSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", nil, fmt.Errorf("generate salt: %w", err)
+	}
+	params := orderLockParams
+	key := balloonKDF([]byte(passphrase), salt, params)
+
+	gcm, err := orderGCM(key)
+	if err != nil {
+		return "", nil, err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", nil, fmt.Errorf("generate iv: %w", err)
+	}
+
+	public := &OrderData{
+		DepositAddr: data.DepositAddr,
+		Memo:        data.Memo,
+		FromTicker:  data.FromTicker,
+		FromNet:     data.FromNet,
+		ToTicker:    data.ToTicker,
+		ToNet:       data.ToNet,
+		AmountIn:    data.AmountIn,
+		AmountOut:   data.AmountOut,
+		Deadline:    data.Deadline,
+		SwapType:    data.SwapType,
+		Upstream:    data.Upstream,
+	}
+
+	plaintext := appendBytes(nil, encodeOrderBinary(public))
+	plaintext = append(plaintext, params.LogSpace, params.Time, params.Delta)
+	plaintext = append(plaintext, salt...)
+	plaintext = append(plaintext, iv...)
+	plaintext = gcm.Seal(plaintext, iv, encodeOrderBinary(data), salt)
+
+	token, err := sealOrderToken(orderTokenV3Locked, plaintext)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, key, nil
+}
+
+// decodeLockedOrder parses the server-decrypted plaintext of a v3 token into
+// its public subset, with the sealed remainder attached as o.lock.
+func decodeLockedOrder(b []byte) (*OrderData, error) {
+	r := &orderReader{b: b}
+	publicBytes := r.bytes()
+	params := r.take(3)
+	salt := r.take(orderLockSaltSize)
+	iv := r.take(12)
+	if r.err != nil {
+		return nil, r.err
+	}
+	if params[0] < 10 || params[0] > 24 || params[1] == 0 {
+		return nil, fmt.Errorf("locked order: bad KDF parameters")
+	}
+
+	o, err := decodeOrderBinary(publicBytes)
+	if err != nil {
+		return nil, fmt.Errorf("locked order: %w", err)
+	}
+	o.lock = &orderLock{
+		params: balloonParams{LogSpace: params[0], Time: params[1], Delta: params[2]},
+		salt:   salt,
+		iv:     iv,
+		sealed: r.b,
+	}
+	return o, nil
+}
+
+// unlockOrderData derives the key from passphrase and opens a locked order.
+// It returns the full order and the key, which can be handed back to the
+// client (see unlockOrderDataWithKey) to avoid re-running the KDF.
+func unlockOrderData(o *OrderData, passphrase string) (*OrderData, []byte, error) {
+	if !o.Locked() {
+		return o, nil, nil
+	}
+	key := balloonKDF([]byte(passphrase), o.lock.salt, o.lock.params)
+	full, err := unlockOrderDataWithKey(o, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return full, key, nil
+}
+
+// unlockOrderDataWithKey opens a locked order with a previously derived key.
+func unlockOrderDataWithKey(o *OrderData, key []byte) (*OrderData, error) {
+	if !o.Locked() {
+		return o, nil
+	}
+	if len(key) != 32 {
+		return nil, errWrongPassphrase
+	}
+	gcm, err := orderGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, o.lock.iv, o.lock.sealed, o.lock.salt)
+	if err != nil {
+		return nil, errWrongPassphrase
+	}
+	full, err := decodeOrderBinary(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	// The outer layer is what the server polls; refuse a mismatched pair.
+	if subtle.ConstantTimeCompare([]byte(full.DepositAddr), []byte(o.DepositAddr)) != 1 {
+		return nil, fmt.Errorf("locked order: deposit address mismatch")
+	}
+	return full, nil
+}
+
+// encodeUnlockKey and decodeUnlockKey carry a derived key through forms.
+func encodeUnlockKey(key []byte) string {
+	return base64.RawURLEncoding.EncodeToString(key)
+}
+
+func decodeUnlockKey(s string) []byte {
+	key, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(key) != 32 {
+		return nil
+	}
+	return key
+}
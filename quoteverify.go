@@ -0,0 +1,174 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// Quote response verification.
+//
+// A real quote hands the user a deposit address. If the upstream (or a relay
+// in NEAR_INTENTS_API_URL) is compromised it could swap in its own address or
+// quietly change the recipient, so every real quote is checked against the
+// request before anything is shown.
+
+// quoteDeadlineSlack tolerates clock skew between us and 1Click.
+const quoteDeadlineSlack = 2 * time.Minute
+
+// nearQuoteKey, if set, is the Ed25519 key that must have signed every
+// quote. The signature is checked over the "quote" object exactly as it
+// arrived, so only set it for an upstream that signs that way.
+var nearQuoteKey ed25519.PublicKey
+
+// QuoteMismatchError reports a quote response that doesn't match what was
+// requested. Field names the offending part; values are never included, as
+// the error is logged.
+type QuoteMismatchError struct {
+	Field  string
+	Reason string
+}
+
+func (e *QuoteMismatchError) Error() string {
+	return fmt.Sprintf("quote rejected: %s %s", e.Field, e.Reason)
+}
+
+// initQuoteVerification loads NEAR_INTENTS_QUOTE_PUBKEY, either NEAR-style
+// "ed25519:<base58>" or 64 hex characters.
+func initQuoteVerification() {
+	s := os.Getenv("NEAR_INTENTS_QUOTE_PUBKEY")
+	if s == "" {
+		nearQuoteKey = nil
+		return
+	}
+	key, err := parseEd25519Key(s)
+	if err != nil {
+		log.Fatal("NEAR_INTENTS_QUOTE_PUBKEY: ", err)
+	}
+	nearQuoteKey = key
+}
+
+func parseEd25519Key(s string) (ed25519.PublicKey, error) {
+	var b []byte
+	if rest, ok := strings.CutPrefix(s, "ed25519:"); ok {
+		b, _ = base58Decode(rest)
+	} else {
+		b, _ = hex.DecodeString(s)
+	}
+	if len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("want ed25519:<base58> or 64 hex characters")
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// verifyQuoteResponse checks a real quote against the request it answers.
+// origin is the token being deposited; its chain decides the deposit address
+// format.
+func verifyQuoteResponse(req *QuoteRequest, resp *QuoteResponse, origin *TokenInfo) error {
+	mismatch := func(field, reason string) error {
+		return &QuoteMismatchError{Field: field, Reason: reason}
+	}
+
+	echo := resp.QuoteRequest
+	if echo == nil {
+		return mismatch("quoteRequest", "missing from response")
+	}
+	switch {
+	case echo.OriginAsset != req.OriginAsset:
+		return mismatch("originAsset", "differs from request")
+	case echo.DestinationAsset != req.DestinationAsset:
+		return mismatch("destinationAsset", "differs from request")
+	case echo.Recipient != req.Recipient:
+		return mismatch("recipient", "differs from request")
+	case echo.RefundTo != req.RefundTo:
+		return mismatch("refundTo", "differs from request")
+	case echo.SwapType != req.SwapType:
+		return mismatch("swapType", "differs from request")
+	case echo.Amount != req.Amount:
+		return mismatch("amount", "differs from request")
+	case len(echo.AppFees) != 0:
+		return mismatch("appFees", "not empty")
+	}
+
+	q := &resp.Quote
+	switch req.SwapType {
+	case "FLEX_INPUT", "EXACT_INPUT":
+		if !sameAtomic(q.AmountIn, req.Amount) {
+			return mismatch("amountIn", "differs from requested amount")
+		}
+	case "EXACT_OUTPUT":
+		if !sameAtomic(q.AmountOut, req.Amount) {
+			return mismatch("amountOut", "differs from requested amount")
+		}
+	}
+	if req.SwapType != "ANY_INPUT" {
+		if out, ok := new(big.Int).SetString(q.AmountOut, 10); !ok || out.Sign() <= 0 {
+			return mismatch("amountOut", "not a positive integer")
+		}
+	}
+
+	if err := checkQuoteDeadline(q.Deadline, req.Deadline); err != nil {
+		return mismatch("deadline", err.Error())
+	}
+
+	chain := ""
+	if origin != nil {
+		chain = strings.ToLower(origin.ChainName)
+	}
+	if err := validateAddress(chain, q.DepositAddress); err != nil {
+		return mismatch("depositAddress", "malformed for "+chain)
+	}
+
+	if nearQuoteKey != nil {
+		sig, err := parseQuoteSignature(resp.Signature)
+		if err != nil {
+			return mismatch("signature", err.Error())
+		}
+		if len(resp.rawQuote) == 0 || !ed25519.Verify(nearQuoteKey, resp.rawQuote, sig) {
+			return mismatch("signature", "does not verify")
+		}
+	}
+	return nil
+}
+
+// sameAtomic compares two atomic amounts numerically, so "0100" == "100".
+func sameAtomic(a, b string) bool {
+	x, ok1 := new(big.Int).SetString(a, 10)
+	y, ok2 := new(big.Int).SetString(b, 10)
+	return ok1 && ok2 && x.Cmp(y) == 0
+}
+
+// checkQuoteDeadline requires a deadline in the future and no later than
+// the one we asked for.
+func checkQuoteDeadline(got, requested string) error {
+	dl, err := time.Parse(time.RFC3339, got)
+	if err != nil {
+		return fmt.Errorf("unparseable")
+	}
+	if time.Until(dl) < -quoteDeadlineSlack {
+		return fmt.Errorf("already passed")
+	}
+	if want, err := time.Parse(time.RFC3339, requested); err == nil && dl.After(want.Add(quoteDeadlineSlack)) {
+		return fmt.Errorf("later than requested")
+	}
+	return nil
+}
+
+// parseQuoteSignature accepts "ed25519:<base58>" or bare hex.
+func parseQuoteSignature(s string) ([]byte, error) {
+	var b []byte
+	if rest, ok := strings.CutPrefix(s, "ed25519:"); ok {
+		b, _ = base58Decode(rest)
+	} else {
+		b, _ = hex.DecodeString(s)
+	}
+	if len(b) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("missing or malformed")
+	}
+	return b, nil
+}
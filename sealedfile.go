@@ -0,0 +1,223 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Encrypted state files.
+//
+// The server has no database, and by default every Telegram store lives in
+// memory only. An operator can name a file for a store (TG_ALERTS_FILE and
+// friends) to keep it across restarts. Each file holds one JSON document
+// sealed with AES-256-GCM under a key derived from the order key and the
+// store's purpose, so it is unreadable without ORDER_SECRET and one store's
+// file can't be loaded as another's. Files are rewritten whole, a few
+// seconds after a change and on shutdown. Stores that hold per-user data
+// also seal each chat's record on its own (sealRecord), bound to the chat ID,
+// so it stays encrypted in memory and one chat's record can't be swapped in
+// for another's.
+//
+//	0x01 | key ID | IV (12) | ciphertext+tag
+
+const sealedFileV1 byte = 0x01
+
+// sealedFileSyncInterval is how often changed stores are written out.
+var sealedFileSyncInterval = 10 * time.Second
+
+// sealedFile is the optional file behind one store.
+type sealedFile struct {
+	path     string
+	purpose  string
+	snapshot func() interface{} // the store's state to write; called without file locks
+	dirty    atomic.Bool
+	mu       sync.Mutex // serializes writes
+}
+
+var (
+	sealedFilesMu sync.Mutex
+	sealedFiles   []*sealedFile
+)
+
+// sealedFileKey derives the file key for purpose from an order key.
+func sealedFileKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("uswap-zero/sealed-file/" + purpose))
+	return mac.Sum(nil)
+}
+
+// seal encrypts plaintext for purpose under the active order key. record
+// names the chat a per-chat record belongs to; it is "" for whole files.
+func seal(purpose, record string, plaintext []byte) ([]byte, error) {
+	gcm, err := orderGCM(sealedFileKey(orderKey, purpose))
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	header := []byte{sealedFileV1, orderKeys.activeID}
+	packed := append(append([]byte{}, header...), iv...)
+	return gcm.Seal(packed, iv, plaintext, sealedAAD(header, purpose, record)), nil
+}
+
+// unseal reverses seal with whichever keyring key sealed packed.
+func unseal(purpose, record string, packed []byte) ([]byte, error) {
+	const nonceSize = 12
+	if len(packed) < 2+nonceSize || packed[0] != sealedFileV1 {
+		return nil, fmt.Errorf("not sealed %s data", purpose)
+	}
+	key, ok := orderKeys.keys[packed[1]]
+	if !ok {
+		return nil, fmt.Errorf("sealed with unknown key %d", packed[1])
+	}
+	gcm, err := orderGCM(sealedFileKey(key, purpose))
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, packed[2:2+nonceSize], packed[2+nonceSize:], sealedAAD(packed[:2], purpose, record))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: wrong ORDER_SECRET or damaged file")
+	}
+	return plaintext, nil
+}
+
+func sealedAAD(header []byte, purpose, record string) []byte {
+	aad := append([]byte{header[0], header[1]}, purpose...)
+	if record != "" {
+		aad = append(append(aad, '/'), record...)
+	}
+	return aad
+}
+
+// sealRecord encodes and seals v as chatID's record in a store.
+func sealRecord(purpose string, chatID int64, v interface{}) ([]byte, error) {
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return seal(purpose, strconv.FormatInt(chatID, 10), plaintext)
+}
+
+// openRecord decrypts chatID's record into v.
+func openRecord(purpose string, chatID int64, packed []byte, v interface{}) error {
+	plaintext, err := unseal(purpose, strconv.FormatInt(chatID, 10), packed)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(plaintext, v)
+}
+
+// openSealedFile returns the file named by env for purpose, or nil if env
+// is unset, and loads its contents into v. A missing file is not an error;
+// one that can't be opened is logged and left to be overwritten.
+func openSealedFile(env, purpose string, v interface{}, snapshot func() interface{}) *sealedFile {
+	path := os.Getenv(env)
+	if path == "" {
+		return nil
+	}
+	f := &sealedFile{path: path, purpose: purpose, snapshot: snapshot}
+	if err := f.load(v); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("WARNING: %s: %v — starting empty", env, err)
+	}
+	sealedFilesMu.Lock()
+	sealedFiles = append(sealedFiles, f)
+	sealedFilesMu.Unlock()
+	return f
+}
+
+// markDirty schedules a write. Safe to call on a nil file.
+func (f *sealedFile) markDirty() {
+	if f != nil {
+		f.dirty.Store(true)
+	}
+}
+
+// load decrypts the file into v with whichever keyring key sealed it.
+func (f *sealedFile) load(v interface{}) error {
+	packed, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	plaintext, err := unseal(f.purpose, "", packed)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(plaintext, v)
+}
+
+// save writes the store's current state if it changed since the last
+// write.
+func (f *sealedFile) save() error {
+	if f == nil || !f.dirty.Swap(false) {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	err := f.write()
+	if err != nil {
+		f.dirty.Store(true) // try again next time
+	}
+	return err
+}
+
+// write seals the snapshot and replaces the file atomically. Caller must
+// hold f.mu.
+func (f *sealedFile) write() error {
+	plaintext, err := json.Marshal(f.snapshot())
+	if err != nil {
+		return err
+	}
+	packed, err := seal(f.purpose, "", plaintext)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), ".sealed-*") // mode 0600
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(packed); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// saveSealedFiles writes every changed store.
+func saveSealedFiles() {
+	sealedFilesMu.Lock()
+	files := append([]*sealedFile(nil), sealedFiles...)
+	sealedFilesMu.Unlock()
+	for _, f := range files {
+		if err := f.save(); err != nil {
+			log.Printf("sealed %s file: %v", f.purpose, err)
+		}
+	}
+}
+
+// startSealedFileSync writes changed stores every sealedFileSyncInterval.
+func startSealedFileSync() {
+	go func() {
+		for {
+			time.Sleep(sealedFileSyncInterval)
+			saveSealedFiles()
+		}
+	}()
+}
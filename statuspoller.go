@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// Shared order status lookups.
+//
+// Every order page, /events stream, API status call and Telegram card asks
+// statusPoller instead of calling fetchStatus directly. Concurrent lookups
+// for the same deposit address + memo share one 1Click request, and results
+// are cached briefly while the swap is in flight and for good once it has
+// finished. Nothing is written to disk; entries are keyed by a hash of the
+// deposit address and memo.
+
+// Variables rather than constants so tests can change them.
+var (
+	statusTTLActive     = 4 * time.Second  // PENDING_DEPOSIT, KNOWN_DEPOSIT_TX, PROCESSING
+	statusTTLIncomplete = 30 * time.Second // INCOMPLETE_DEPOSIT can still be topped up
+	statusTTLAnyInput   = 30 * time.Second // finished ANY_INPUT addresses keep accepting deposits
+)
+
+// statusCacheMax bounds the cache. Expired entries go first, then the
+// oldest finished ones.
+const statusCacheMax = 20000
+
+// orderStatus is one status lookup, shared by every caller that asked for
+// it. Callers must not modify it.
+type orderStatus struct {
+	Status      *StatusResponse
+	Withdrawals *AnyInputWithdrawalsResponse // ANY_INPUT only; nil if unavailable
+}
+
+type statusEntry struct {
+	result  *orderStatus
+	fetched time.Time
+	expires time.Time // zero = never
+}
+
+type statusCall struct {
+	done   chan struct{}
+	result *orderStatus
+	err    error
+}
+
+type statusPoller struct {
+	mu       sync.Mutex
+	entries  map[[32]byte]*statusEntry
+	inflight map[[32]byte]*statusCall
+}
+
+var orderStatuses = newStatusPoller()
+
+func newStatusPoller() *statusPoller {
+	return &statusPoller{
+		entries:  make(map[[32]byte]*statusEntry),
+		inflight: make(map[[32]byte]*statusCall),
+	}
+}
+
+func statusKey(depositAddr, memo string) [32]byte {
+	return sha256.Sum256([]byte(depositAddr + "\x00" + memo))
+}
+
+// statusTTL returns how long a result may be served from cache; 0 means
+// forever.
+func statusTTL(status, swapType string) time.Duration {
+	switch status {
+	case "SUCCESS", "REFUNDED", "FAILED":
+		if swapType == "ANY_INPUT" {
+			return statusTTLAnyInput
+		}
+		return 0
+	case "INCOMPLETE_DEPOSIT":
+		return statusTTLIncomplete
+	}
+	return statusTTLActive
+}
+
+// get returns the status of order, and its withdrawals for ANY_INPUT
+// orders. The shared 1Click request is not tied to ctx, so one caller
+// going away doesn't fail the others; ctx only bounds this caller's wait.
+func (p *statusPoller) get(ctx context.Context, order *OrderData) (*orderStatus, error) {
+	key := statusKey(order.DepositAddr, order.Memo)
+
+	p.mu.Lock()
+	if e, ok := p.entries[key]; ok && (e.expires.IsZero() || time.Now().Before(e.expires)) {
+		p.mu.Unlock()
+		return e.result, nil
+	}
+	call, ok := p.inflight[key]
+	if !ok {
+		call = &statusCall{done: make(chan struct{})}
+		p.inflight[key] = call
+		go p.fetch(key, call, order.DepositAddr, order.Memo, order.SwapType)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.result, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetch runs the 1Click requests for one inflight call and caches the result.
+func (p *statusPoller) fetch(key [32]byte, call *statusCall, depositAddr, memo, swapType string) {
+	ctx := context.Background()
+	result := &orderStatus{}
+	var wg sync.WaitGroup
+	if swapType == "ANY_INPUT" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result.Withdrawals, _ = fetchAnyInputWithdrawals(ctx, depositAddr)
+		}()
+	}
+	result.Status, call.err = fetchStatus(ctx, depositAddr, memo)
+	wg.Wait()
+	if call.err == nil {
+		call.result = result
+	}
+
+	p.mu.Lock()
+	delete(p.inflight, key)
+	if call.err == nil {
+		now := time.Now()
+		e := &statusEntry{result: result, fetched: now}
+		ttl := statusTTL(result.Status.Status, swapType)
+		if swapType == "ANY_INPUT" && result.Withdrawals == nil {
+			ttl = statusTTLActive // try the withdrawals again soon
+		}
+		if ttl > 0 {
+			e.expires = now.Add(ttl)
+		}
+		p.makeRoom(now)
+		p.entries[key] = e
+	}
+	p.mu.Unlock()
+	close(call.done)
+}
+
+// makeRoom evicts entries once the cache is full. Caller must hold p.mu.
+func (p *statusPoller) makeRoom(now time.Time) {
+	if len(p.entries) < statusCacheMax {
+		return
+	}
+	var oldestKey [32]byte
+	var oldest time.Time
+	for k, e := range p.entries {
+		if !e.expires.IsZero() && now.After(e.expires) {
+			delete(p.entries, k)
+			continue
+		}
+		if oldest.IsZero() || e.fetched.Before(oldest) {
+			oldestKey, oldest = k, e.fetched
+		}
+	}
+	if len(p.entries) >= statusCacheMax {
+		delete(p.entries, oldestKey)
+	}
+}
+
+// startCleanup periodically drops expired entries.
+func (p *statusPoller) startCleanup() {
+	go func() {
+		for {
+			time.Sleep(5 * time.Minute)
+			p.mu.Lock()
+			now := time.Now()
+			for k, e := range p.entries {
+				if !e.expires.IsZero() && now.After(e.expires) {
+					delete(p.entries, k)
+				}
+			}
+			p.mu.Unlock()
+		}
+	}()
+}
@@ -2,9 +2,25 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestTruncAddr(t *testing.T) {
@@ -406,7 +422,7 @@ func TestRenderSwapCardMono(t *testing.T) {
 	sess := &tgSession{}
 	sess.reset()
 
-	card := renderSwapCardMono(sess)
+	card := renderSwapCardMono(defaultLocale, sess)
 	if card == "" {
 		t.Fatal("mono swap card should not be empty")
 	}
@@ -438,7 +454,7 @@ func TestRenderQuoteCardMono(t *testing.T) {
 		SpreadUSD:    "300.00",
 		SpreadPct:    "0.63",
 	}
-	card := renderQuoteCardMono(p)
+	card := renderQuoteCardMono(defaultLocale, p)
 	if card == "" {
 		t.Fatal("mono quote card should not be empty")
 	}
@@ -468,7 +484,7 @@ func TestRenderDepositCardMono(t *testing.T) {
 		RefundAddr: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
 		RecvAddr:   "0x1234567890abcdef1234567890abcdef12345678",
 	}
-	card := renderDepositCardMono(p)
+	card := renderDepositCardMono(defaultLocale, p)
 	if card == "" {
 		t.Fatal("mono deposit card should not be empty")
 	}
@@ -503,7 +519,7 @@ func TestRenderAnyInputDepositCardMono(t *testing.T) {
 		RefundAddr: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
 		RecvAddr:   "0x1234567890abcdef1234567890abcdef12345678",
 	}
-	card := renderAnyInputDepositCardMono(p)
+	card := renderAnyInputDepositCardMono(defaultLocale, p)
 	if card == "" {
 		t.Fatal("any_input deposit card should not be empty")
 	}
@@ -554,7 +570,7 @@ func TestRenderStatusCardMono(t *testing.T) {
 	// Use correct API status strings
 	status := &StatusResponse{Status: "PENDING_DEPOSIT"}
 
-	card := renderStatusCardMono(order, status)
+	card := renderStatusCardMono(defaultLocale, order, status)
 	if !strings.Contains(card, "STATUS") {
 		t.Error("status card should contain STATUS")
 	}
@@ -599,7 +615,7 @@ func TestRenderAnyStatusCardDispatch(t *testing.T) {
 		{"KNOWN_DEPOSIT_TX", "STATUS"},
 	}
 	for _, tc := range cases {
-		card := renderAnyStatusCard(order, &StatusResponse{Status: tc.status})
+		card := renderAnyStatusCard(defaultLocale, order, &StatusResponse{Status: tc.status})
 		if !strings.Contains(card, tc.wantStr) {
 			t.Errorf("renderAnyStatusCard(%q) missing %q", tc.status, tc.wantStr)
 		}
@@ -615,7 +631,7 @@ func TestRenderCompletionCardMono(t *testing.T) {
 	}
 	status := &StatusResponse{Status: "SUCCESS"}
 
-	card := renderCompletionCardMono(order, status)
+	card := renderCompletionCardMono(defaultLocale, order, status)
 	if !strings.Contains(card, "COMPLETE") {
 		t.Error("completion card should contain COMPLETE")
 	}
@@ -627,6 +643,126 @@ func TestRenderCompletionCardMono(t *testing.T) {
 	}
 }
 
+func TestCardTextWidth(t *testing.T) {
+	if got := padRight("日本", 5); got != "日本 " {
+		t.Errorf("padRight(日本, 5) = %q, want two wide runes and a space", got)
+	}
+	if got := truncWidth("日本語", 5); got != "日本" {
+		t.Errorf("truncWidth(日本語, 5) = %q, want 日本", got)
+	}
+	if got := textWidth("e\u0301"); got != 1 {
+		t.Errorf("combining accent width = %d, want 1", got)
+	}
+	for _, row := range []string{
+		cardRow(" 見積もり"),
+		cardRowKV("送金", "0.5 BTC"),
+		cardRowKV("受取アドレス", strings.Repeat("長", 20)),
+		cardRowCenter("完了"),
+		cardRowRight("~ $10 "),
+	} {
+		if w := textWidth(row); w != cardW {
+			t.Errorf("row width = %d, want %d: %q", w, cardW, row)
+		}
+	}
+}
+
+// tgCardLabels are the translated card labels, which must fit their
+// columns in every catalog.
+var tgCardLabels = []string{
+	"SEND", "RECEIVE", "AMOUNT", "SLIPPAGE", "REFUND", "RECEIVE ADDR",
+	"SEND (est.)", "RATE", "USWAP FEE", "PROTO FEE", "SPREAD", "FEES CHARGED",
+	"NETWORK", "DEADLINE", "SENT", "RECEIVED", "SWAP TO", "REASON",
+}
+
+func TestRenderCardsLocalized(t *testing.T) {
+	sess := &tgSession{}
+	sess.reset()
+	sess.Amount = "0.5"
+	sess.RefundAddr = "bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh"
+	sess.RecvAddr = "0x000000000000000000000000000000000000dEaD"
+	quote := QuoteCardData{
+		FromTicker: "BTC", ToTicker: "ETH", AmountIn: "0.5", AmountOut: "11.2",
+		AmountInUSD: "$30,000.00", AmountOutUSD: "$29,900.00", Rate: "1 BTC = 22.4 ETH",
+		SpreadUSD: "100.00", SpreadPct: "0.33", SwapType: "EXACT_OUTPUT",
+	}
+	deposit := DepositCardData{
+		FromTicker: "BTC", ToTicker: "ETH", AmountIn: "0.5", AmountOut: "11.2",
+		Network: "Bitcoin", Deadline: "23h 59m remaining",
+		RefundAddr: sess.RefundAddr, RecvAddr: sess.RecvAddr,
+	}
+	anyInput := AnyInputCardData{
+		FromTicker: "BTC", ToTicker: "ETH", Network: "Bitcoin",
+		RefundAddr: sess.RefundAddr, RecvAddr: sess.RecvAddr,
+	}
+	order := &OrderData{FromTicker: "BTC", ToTicker: "ETH", AmountIn: "0.5", AmountOut: "11.2"}
+	details := &SwapDetails{RefundReason: "Deposit below the minimum amount"}
+
+	for _, l := range localeList {
+		cards := []string{
+			renderSwapCardMono(l, sess),
+			renderQuoteCardMono(l, quote),
+			renderDepositCardMono(l, deposit),
+			renderAnyInputDepositCardMono(l, anyInput),
+		}
+		for _, status := range []string{"PENDING_DEPOSIT", "PROCESSING", "SUCCESS", "REFUNDED", "FAILED"} {
+			cards = append(cards, renderAnyStatusCard(l, order, &StatusResponse{Status: status, SwapDetails: details}))
+		}
+		for _, card := range cards {
+			for _, line := range strings.Split(card, "\n") {
+				if w := textWidth(line); w != cardW {
+					t.Errorf("%s card line width = %d, want %d: %q", l.Tag, w, cardW, line)
+				}
+			}
+		}
+
+		for _, label := range tgCardLabels {
+			if w := textWidth(l.T(label)); w > 12 {
+				t.Errorf("%s: card label %q is %d columns, want at most 12", l.Tag, l.T(label), w)
+			}
+		}
+		for _, title := range []string{"QUOTE", "ORDER", "QUICK SWAP", "STATUS", "REFUNDED", "FAILED", "COMPLETE"} {
+			if w := textWidth(l.T(title)); w > 13 {
+				t.Errorf("%s: card title %q is %d columns, want at most 13", l.Tag, l.T(title), w)
+			}
+		}
+	}
+
+	es := renderQuoteCardMono(locales["es"], quote)
+	for _, want := range []string{"COTIZACIÓN", "ENVÍO (est.)", "COMISIONES"} {
+		if !strings.Contains(es, want) {
+			t.Errorf("Spanish quote card should contain %q:\n%s", want, es)
+		}
+	}
+}
+
+func TestRenderSwapCardLanguage(t *testing.T) {
+	sess := &tgSession{}
+	sess.reset()
+	sess.Lang = "es-MX"
+
+	text, markup := renderSwapCard(sess)
+	if !strings.Contains(text, "Sin comisiones") {
+		t.Errorf("swap card should follow the session language:\n%s", text)
+	}
+	found := false
+	for _, row := range markup.InlineKeyboard {
+		for _, btn := range row {
+			if btn.CallbackData == "sr" && btn.Text == "Fijar dirección de reembolso" {
+				found = true
+			}
+		}
+	}
+	if !found {
+		t.Error("swap card buttons should follow the session language")
+	}
+
+	sess.Lang = "xx"
+	text, _ = renderSwapCard(sess)
+	if !strings.Contains(text, "Zero fees") {
+		t.Error("unknown languages should fall back to English")
+	}
+}
+
 func TestTokenLabel(t *testing.T) {
 	tests := []struct {
 		ticker, net, want string
@@ -990,3 +1126,1224 @@ func TestParseInlineQuery_StatusOnlyOneWord(t *testing.T) {
 		t.Errorf("lone 'status' kind = %q, want %q", p.kind, inlineKindSingle)
 	}
 }
+
+// fakeTelegram is a stand-in Bot API that records every call. getUpdates
+// serves the queued updates from the requested offset on.
+type fakeTelegram struct {
+	mu      sync.Mutex
+	calls   []fakeTGCall
+	nextID  int
+	updates []TGUpdate
+	replies map[string][]string // canned answers per method, used once each
+}
+
+type fakeTGCall struct {
+	Method  string
+	Payload map[string]interface{}
+}
+
+// withFakeTelegram points the bot at a fake Bot API for the test.
+func withFakeTelegram(t *testing.T) *fakeTelegram {
+	t.Helper()
+	fake := &fakeTelegram{nextID: 100, replies: make(map[string][]string)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		method := path.Base(r.URL.Path)
+		fake.mu.Lock()
+		fake.calls = append(fake.calls, fakeTGCall{Method: method, Payload: payload})
+		fake.nextID++
+		id := fake.nextID
+		var reply string
+		if r := fake.replies[method]; len(r) > 0 {
+			reply, fake.replies[method] = r[0], r[1:]
+		}
+		var pending []TGUpdate
+		if offset, _ := payload["offset"].(float64); method == "getUpdates" {
+			for _, u := range fake.updates {
+				if u.UpdateID >= int(offset) {
+					pending = append(pending, u)
+				}
+			}
+		}
+		fake.mu.Unlock()
+		if reply != "" {
+			fmt.Fprint(w, reply)
+			return
+		}
+		if method == "getUpdates" {
+			if len(pending) == 0 {
+				time.Sleep(10 * time.Millisecond) // a short long-poll
+			}
+			result, _ := json.Marshal(pending)
+			fmt.Fprintf(w, `{"ok":true,"result":%s}`, result)
+			return
+		}
+		fmt.Fprintf(w, `{"ok":true,"result":{"message_id":%d,"chat":{"id":1,"type":"private"}}}`, id)
+	}))
+	savedBase, savedUser, savedSeen, savedOutbox := tgAPIBase, tgBotUsername, tgSeenUpdates, tgOutbox
+	savedGlobal, savedPrivate, savedGroup := tgGlobalRate, tgPrivateRate, tgGroupRate
+	tgAPIBase, tgBotUsername = srv.URL+"/botTEST", "TestBot"
+	tgSeenUpdates = &tgUpdateSet{seen: make(map[int]bool)}
+	fast := tgRate{perSec: 1000, burst: 1000}
+	tgGlobalRate, tgPrivateRate, tgGroupRate = fast, fast, fast
+	tgOutbox = newTGOutbox()
+	tgOutbox.start()
+	t.Cleanup(func() {
+		srv.Close()
+		tgAPIBase, tgBotUsername, tgSeenUpdates, tgOutbox = savedBase, savedUser, savedSeen, savedOutbox
+		tgGlobalRate, tgPrivateRate, tgGroupRate = savedGlobal, savedPrivate, savedGroup
+	})
+	return fake
+}
+
+// take returns and clears the calls recorded so far.
+func (f *fakeTelegram) take() []fakeTGCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := f.calls
+	f.calls = nil
+	return calls
+}
+
+// texts returns the text of every call to method, in order.
+func (f *fakeTelegram) texts(calls []fakeTGCall, method string) []string {
+	var out []string
+	for _, c := range calls {
+		if c.Method == method {
+			s, _ := c.Payload["text"].(string)
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// placeFakeOrder places a 1 ETH → USDT order against the fake 1Click API
+// and returns its token.
+func placeFakeOrder(t *testing.T) string {
+	t.Helper()
+	w := postForm(handleSwapConfirm, "/swap", url.Values{
+		"csrf":          {generateCSRFToken("swap")},
+		"from":          {"ETH"},
+		"from_net":      {"eth"},
+		"to":            {"USDT"},
+		"to_net":        {"eth"},
+		"atomic_amount": {"1000000000000000000"},
+		"recipient":     {"0x000000000000000000000000000000000000dEaD"},
+		"refund_addr":   {"0x000000000000000000000000000000000000dEaD"},
+		"slippage_bps":  {"100"},
+		"swap_type":     {"FLEX_INPUT"},
+	})
+	if w.Code != http.StatusFound {
+		t.Fatalf("POST /swap: got %d, want 302\nBody: %s", w.Code, w.Body.String())
+	}
+	return strings.TrimPrefix(w.Header().Get("Location"), "/order/")
+}
+
+func TestTGWatchNotifies(t *testing.T) {
+	withFake1Click(t, fakeScenarios["success"])
+	tg := withFakeTelegram(t)
+	token := placeFakeOrder(t)
+
+	const chatID = 4201
+	sess := tgSessions.get(chatID)
+	sess.OrderToken, sess.CardMsgID = token, 7
+	t.Cleanup(func() {
+		tgWatches.remove(chatID)
+		tgSessions.mu.Lock()
+		delete(tgSessions.sessions, chatID)
+		tgSessions.mu.Unlock()
+	})
+
+	markup := &TGInlineKeyboardMarkup{}
+	addWatchButton(markup, chatID, sess, "PENDING_DEPOSIT")
+	if len(markup.InlineKeyboard) != 1 || markup.InlineKeyboard[0][0].Text != "🔔 Notify me" {
+		t.Fatalf("order card should offer 🔔 Notify me, got %+v", markup.InlineKeyboard)
+	}
+	// The toggle and the card redraw share one lookup, as they would in
+	// production; afterwards every check reaches the fake again.
+	statusTTLActive = time.Minute
+	handleTGToggleWatch(chatID, sess)
+	statusTTLActive, orderStatuses = time.Nanosecond, newStatusPoller()
+	if !tgWatches.watching(chatID, token) {
+		t.Fatal("toggle should start watching the order")
+	}
+	calls := tg.take()
+	if edits := tg.texts(calls, "editMessageText"); len(edits) != 1 {
+		t.Errorf("toggle should redraw the card once, got %d edits", len(edits))
+	}
+	if !strings.Contains(fmt.Sprint(calls), "Stop notifications") {
+		t.Error("redrawn card should offer 🔕 Stop notifications")
+	}
+
+	// PENDING_DEPOSIT was seen by the toggle; the fake now moves on.
+	tgWatches.check(chatID)
+	calls = tg.take()
+	if edits := tg.texts(calls, "editMessageText"); len(edits) != 1 {
+		t.Errorf("PROCESSING should edit the card in place, got %d edits", len(edits))
+	}
+	if sent := tg.texts(calls, "sendMessage"); len(sent) != 1 || !strings.Contains(sent[0], "processing") {
+		t.Errorf("PROCESSING should send one notice, got %q", sent)
+	}
+
+	tgWatches.check(chatID)
+	sent := tg.texts(tg.take(), "sendMessage")
+	if len(sent) != 1 || !strings.Contains(sent[0], "Swap complete: 1 ETH → 2997 USDT") {
+		t.Errorf("SUCCESS should send the completion notice, got %q", sent)
+	}
+	if tgWatches.watching(chatID, token) {
+		t.Error("watch should end on a terminal status")
+	}
+	if len(sess.OrderMsgIDs) != 2 {
+		t.Errorf("notices should be tracked for Clear, got %v", sess.OrderMsgIDs)
+	}
+
+	tgWatches.check(chatID)
+	if calls := tg.take(); len(calls) != 0 {
+		t.Errorf("ended watch should stay quiet, got %v", calls)
+	}
+}
+
+func TestTGAnyInputChecksQuote(t *testing.T) {
+	fake := withFake1Click(t, fakeScenarios["success"])
+	tg := withFakeTelegram(t)
+
+	sess := &tgSession{}
+	sess.reset()
+	sess.FromTicker, sess.FromNet = "ETH", "eth"
+	sess.ToTicker, sess.ToNet = "USDT", "eth"
+	sess.RefundAddr = "0x000000000000000000000000000000000000dEaD"
+	sess.RecvAddr = "0x000000000000000000000000000000000000dEaD"
+	sess.CardMsgID = 7
+	from, to := findToken("ETH", "eth"), findToken("USDT", "eth")
+
+	sc := fakeScenarios["success"]
+	sc.Tamper = "recipient"
+	fake.setScenario(sc)
+	handleTGAnyInputSwap(4301, sess, from, to)
+	edits := tg.texts(tg.take(), "editMessageText")
+	if sess.OrderToken != "" {
+		t.Error("tampered quote should not create an order")
+	}
+	if len(edits) == 0 || !strings.Contains(edits[len(edits)-1], "Quick swap refused") {
+		t.Errorf("tampered quote should be refused, got %q", edits)
+	}
+
+	fake.setScenario(fakeScenarios["success"])
+	handleTGAnyInputSwap(4301, sess, from, to)
+	if sess.OrderToken == "" {
+		t.Fatal("honest quote should create an order")
+	}
+	edits = tg.texts(tg.take(), "editMessageText")
+	if len(edits) == 0 || !strings.Contains(edits[len(edits)-1], "QUICK SWAP") {
+		t.Errorf("honest quote should show the deposit card, got %q", edits)
+	}
+	markup := &TGInlineKeyboardMarkup{}
+	addAttestationButton(markup, sess)
+	if len(markup.InlineKeyboard) != 1 {
+		t.Error("quick swap order should offer its signed quote")
+	}
+}
+
+func TestTGUnlockLimits(t *testing.T) {
+	withFastOrderLock(t)
+	tg := withFakeTelegram(t)
+	savedLimiter := limiter
+	limiter = &rateLimiter{counters: make(map[string]*rateBucket)}
+	t.Cleanup(func() { limiter = savedLimiter })
+
+	token, _, err := encryptLockedOrderData(sampleOrders["TON"], "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, _ := decryptOrderData(token)
+	const chatID = 4401
+	sess := &tgSession{}
+	sess.reset()
+	guess := func() string {
+		t.Helper()
+		handleTGUnlockInput(chatID, sess, &TGMessage{MessageID: 9, Text: "wrong guess"})
+		sent := tg.texts(tg.take(), "sendMessage")
+		if len(sent) != 1 {
+			t.Fatalf("each guess should get one reply, got %q", sent)
+		}
+		return sent[0]
+	}
+
+	// Asking for the prompt again must not restore the tries.
+	for i := 0; i < 5; i++ {
+		showTGOrder(chatID, sess, order, token)
+		tg.take()
+		guess()
+	}
+	if sess.UnlockTries != 5 {
+		t.Errorf("UnlockTries = %d after 5 re-prompted guesses, want 5", sess.UnlockTries)
+	}
+	for i := 5; i < tgUnlockLimit; i++ {
+		showTGOrder(chatID, sess, order, token)
+		tg.take()
+		if reply := guess(); !strings.Contains(reply, "Too many wrong passphrases") {
+			t.Errorf("guess %d after the tries ran out: got %q", i+1, reply)
+		}
+	}
+
+	showTGOrder(chatID, sess, order, token)
+	tg.take()
+	sess.UnlockTries = 0
+	if reply := guess(); !strings.Contains(reply, "Too many unlock attempts") {
+		t.Errorf("guess past the per-chat limit should be refused, got %q", reply)
+	}
+	if sess.UnlockTries != 0 {
+		t.Error("a refused guess should not run the KDF")
+	}
+}
+
+func TestContinueInTelegram(t *testing.T) {
+	withFake1Click(t, fakeScenarios["success"])
+	withFakeTelegram(t)
+	token := placeFakeOrder(t)
+
+	req := httptest.NewRequest("GET", "/order/"+token, nil)
+	w := httptest.NewRecorder()
+	handleOrder(w, req)
+	if !strings.Contains(w.Body.String(), `href="/order/`+token+`/telegram"`) {
+		t.Fatal("pending order page should link to Continue in Telegram")
+	}
+
+	req = httptest.NewRequest("GET", "/order/"+token+"/telegram", nil)
+	w = httptest.NewRecorder()
+	handleOrder(w, req)
+	loc := w.Header().Get("Location")
+	if w.Code != http.StatusSeeOther || !strings.HasPrefix(loc, "https://t.me/TestBot?start=watch_") {
+		t.Fatalf("GET /telegram: got %d %q, want 303 to the bot's deep link", w.Code, loc)
+	}
+	param := strings.TrimPrefix(loc, "https://t.me/TestBot?start=")
+	if len(param) > 64 {
+		t.Errorf("start parameter is %d characters; Telegram allows 64", len(param))
+	}
+
+	const chatID = 4202
+	t.Cleanup(func() {
+		tgWatches.remove(chatID)
+		tgSessions.mu.Lock()
+		delete(tgSessions.sessions, chatID)
+		tgSessions.mu.Unlock()
+	})
+	start := &TGMessage{Chat: TGChat{ID: chatID, Type: "private"}, Text: "/start " + param}
+	handleTGMessage(start)
+	if !tgWatches.watching(chatID, token) {
+		t.Fatal("/start watch_ should turn on notifications")
+	}
+	if sess := tgSessions.find(chatID); sess == nil || sess.OrderToken != token || sess.CardMsgID == 0 {
+		t.Error("/start watch_ should show the order card")
+	}
+
+	// Hand-off links work once.
+	tgWatches.remove(chatID)
+	handleTGMessage(start)
+	if tgWatches.watching(chatID, token) {
+		t.Error("a hand-off link should not work twice")
+	}
+}
+
+func TestTGPolling(t *testing.T) {
+	tg := withFakeTelegram(t)
+	savedTimeout := tgPollTimeout
+	tgPollTimeout = 0
+	t.Cleanup(func() { tgPollTimeout = savedTimeout })
+
+	private := TGChat{ID: 4203, Type: "private"}
+	tg.mu.Lock()
+	tg.updates = []TGUpdate{
+		{UpdateID: 500, Message: &TGMessage{Chat: private, Text: "/verify"}},
+		{UpdateID: 501, Message: &TGMessage{Chat: private, Text: "/status"}},
+	}
+	tg.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := startTGPolling(ctx)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		tg.mu.Lock()
+		var sent int
+		for _, c := range tg.calls {
+			if c.Method == "sendMessage" {
+				sent++
+			}
+		}
+		tg.mu.Unlock()
+		if sent == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("both updates should be handled once, got %d replies", sent)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("polling loop should stop when its context is cancelled")
+	}
+	drainTelegram(context.Background())
+
+	var offsets []float64
+	var replies int
+	for _, c := range tg.take() {
+		switch c.Method {
+		case "getUpdates":
+			offsets = append(offsets, c.Payload["offset"].(float64))
+		case "sendMessage":
+			replies++
+		}
+	}
+	if replies != 2 {
+		t.Errorf("each update should be handled exactly once, got %d replies", replies)
+	}
+	if len(offsets) < 2 || offsets[0] != 0 || offsets[len(offsets)-1] != 502 {
+		t.Errorf("getUpdates offsets = %v, want 0 first and a final confirm at 502", offsets)
+	}
+}
+
+func TestTGWebhookSecretToken(t *testing.T) {
+	tg := withFakeTelegram(t)
+	savedToken := tgSecretToken
+	tgSecretToken = deriveTGSecretToken("path-secret")
+	t.Cleanup(func() { tgSecretToken = savedToken })
+	if tgSecretToken == "path-secret" || len(tgSecretToken) != 64 {
+		t.Fatalf("secret_token should be derived from, not equal to, the path secret: %q", tgSecretToken)
+	}
+
+	post := func(token, body string) int {
+		req := httptest.NewRequest("POST", "/tg/webhook/path-secret", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("X-Telegram-Bot-Api-Secret-Token", token)
+		}
+		w := httptest.NewRecorder()
+		handleTelegramWebhook(w, req)
+		return w.Code
+	}
+	update := `{"update_id":700,"message":{"message_id":1,"chat":{"id":4204,"type":"private"},"text":"/verify"}}`
+
+	if code := post("", update); code != http.StatusForbidden {
+		t.Errorf("missing secret_token: got %d, want 403", code)
+	}
+	if code := post(tgSecretToken[:63]+"x", update); code != http.StatusForbidden {
+		t.Errorf("wrong secret_token: got %d, want 403", code)
+	}
+	big := `{"update_id":701,"message":{"text":"` + strings.Repeat("a", tgMaxUpdateBody) + `"}}`
+	if code := post(tgSecretToken, big); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body: got %d, want 413", code)
+	}
+
+	// Telegram retries an update it thinks was lost; it is handled once.
+	for i := 0; i < 3; i++ {
+		if code := post(tgSecretToken, update); code != 200 {
+			t.Fatalf("valid update: got %d, want 200", code)
+		}
+	}
+	drainTelegram(context.Background())
+	if sent := tg.texts(tg.take(), "sendMessage"); len(sent) != 1 {
+		t.Errorf("redelivered update should be handled once, got %d replies", len(sent))
+	}
+}
+
+func TestTGOutboxOrderAndMerge(t *testing.T) {
+	savedCap := tgQueueCap
+	tgQueueCap[tgPriorityMonitor] = 1
+	t.Cleanup(func() { tgQueueCap = savedCap })
+
+	o := newTGOutbox() // not started: calls stay queued until next
+	call := func(priority int, method, body string) *tgCall {
+		chatID, key := tgCallTarget(method, []byte(body))
+		return &tgCall{priority: priority, method: method, chatID: chatID, mergeKey: key, body: []byte(body)}
+	}
+	o.enqueue(call(tgPriorityMonitor, "sendMessage", `{"chat_id":-100,"text":"fee card"}`))
+	full := o.enqueue(call(tgPriorityMonitor, "sendMessage", `{"chat_id":-100,"text":"another"}`))
+	if res := <-full; res.err != errTGQueueFull {
+		t.Errorf("monitor queue over its cap: got %v, want errTGQueueFull", res.err)
+	}
+	o.enqueue(call(tgPriorityUser, "editMessageText", `{"chat_id":5,"message_id":9,"text":"PENDING"}`))
+	o.enqueue(call(tgPriorityUser, "editMessageText", `{"chat_id":5,"message_id":9,"text":"PROCESSING"}`))
+	o.enqueue(call(tgPriorityUser, "editMessageText", `{"chat_id":5,"message_id":10,"text":"other"}`))
+
+	st := o.status()
+	if st.UserQueued != 2 || st.MonitorQueued != 1 || st.Merged != 1 || st.Dropped != 1 {
+		t.Fatalf("status = %+v, want 2 user, 1 monitor, 1 merged, 1 dropped", st)
+	}
+	var sent []string
+	for {
+		o.mu.Lock()
+		c, _ := o.next(time.Now())
+		o.mu.Unlock()
+		if c == nil {
+			break
+		}
+		sent = append(sent, string(c.body))
+		if len(c.waiters) == 0 {
+			t.Errorf("call %s lost its waiters", c.body)
+		}
+		o.mu.Lock()
+		o.finished(c)
+		o.mu.Unlock()
+	}
+	want := []string{
+		`{"chat_id":5,"message_id":9,"text":"PROCESSING"}`,
+		`{"chat_id":5,"message_id":10,"text":"other"}`,
+		`{"chat_id":-100,"text":"fee card"}`,
+	}
+	if strings.Join(sent, "\n") != strings.Join(want, "\n") {
+		t.Errorf("send order:\n%s\nwant:\n%s", strings.Join(sent, "\n"), strings.Join(want, "\n"))
+	}
+
+	// A chat's next call waits until the one in flight has finished, even
+	// with tokens to spare, so its edits can't land out of order.
+	o.enqueue(call(tgPriorityUser, "editMessageText", `{"chat_id":5,"message_id":9,"text":"SUCCESS"}`))
+	o.enqueue(call(tgPriorityUser, "sendMessage", `{"chat_id":5,"text":"done"}`))
+	o.enqueue(call(tgPriorityUser, "editMessageText", `{"inline_message_id":"AB","text":"1"}`))
+	o.mu.Lock()
+	first, _ := o.next(time.Now())
+	inline, _ := o.next(time.Now())
+	o.mu.Unlock()
+	if first == nil || !strings.Contains(string(first.body), "SUCCESS") || inline == nil || inline.chatID != 0 {
+		t.Fatalf("first calls: got %v and %v", first, inline)
+	}
+	o.enqueue(call(tgPriorityUser, "editMessageText", `{"inline_message_id":"AB","text":"2"}`))
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if c, _ := o.next(time.Now()); c != nil {
+		t.Errorf("sent %s while an earlier call to its chat or message was in flight", c.body)
+	}
+	o.finished(first)
+	o.finished(inline)
+	for _, want := range []string{`"done"`, `"2"`} {
+		if c, _ := o.next(time.Now()); c == nil || !strings.Contains(string(c.body), want) {
+			t.Errorf("after the calls in flight finished: got %v, want %s", c, want)
+		}
+	}
+}
+
+func TestTGOutboxRetryAfter(t *testing.T) {
+	tg := withFakeTelegram(t)
+	savedUnit := tgRetryAfterUnit
+	tgRetryAfterUnit = 20 * time.Millisecond
+	t.Cleanup(func() { tgRetryAfterUnit = savedUnit })
+
+	tg.mu.Lock()
+	tg.replies["sendMessage"] = []string{
+		`{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 2","parameters":{"retry_after":2}}`,
+	}
+	tg.replies["editMessageText"] = []string{
+		`{"ok":false,"error_code":400,"description":"Bad Request: message to edit not found"}`,
+	}
+	tg.mu.Unlock()
+
+	start := time.Now()
+	msg, err := tgSendMessage(42, "hello", nil)
+	if err != nil || msg.MessageID == 0 {
+		t.Fatalf("sendMessage after a 429: %v", err)
+	}
+	if waited := time.Since(start); waited < 40*time.Millisecond {
+		t.Errorf("retried after %s, want at least retry_after (40ms)", waited)
+	}
+	if err := tgEditMessage(42, 7, "x", nil); err == nil || !strings.Contains(err.Error(), "message to edit not found") {
+		t.Errorf("a 400 should fail without retrying, got %v", err)
+	}
+	if sent := tg.texts(tg.take(), "sendMessage"); len(sent) != 2 {
+		t.Errorf("sendMessage attempts = %d, want 2", len(sent))
+	}
+	st := tgOutbox.status()
+	if st.RateLimited != 1 || st.Retried != 1 || st.Sent != 1 || st.Dropped != 0 {
+		t.Errorf("status = %+v, want 1 rate limited, 1 retried, 1 sent, 0 dropped", st)
+	}
+}
+
+func TestTGErrorCardEscapes(t *testing.T) {
+	tg := withFakeTelegram(t)
+	sess := &tgSession{CardMsgID: 7}
+	sess.reset()
+
+	showErrorAndCard(42, sess, `NEAR Intents rejected the request: amount must be < 1 & > 0`)
+	edits := tg.texts(tg.take(), "editMessageText")
+	if len(edits) != 1 || !strings.Contains(edits[0], "amount must be &lt; 1 &amp; &gt; 0") {
+		t.Errorf("error text should be HTML-escaped, got %q", edits)
+	}
+}
+
+func TestTGOutboxStalledChats(t *testing.T) {
+	// Telegram hangs on the first tgOutboxWorkers chats, enough to occupy
+	// every worker, and answers everyone else.
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			ChatID int64 `json:"chat_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		if payload.ChatID <= tgOutboxWorkers {
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+			return
+		}
+		fmt.Fprint(w, `{"ok":true,"result":{"message_id":1,"chat":{"id":1,"type":"private"}}}`)
+	}))
+	savedBase, savedOutbox, savedTimeout, savedBackoff := tgAPIBase, tgOutbox, tgCallTimeout, tgRetryBackoff
+	tgAPIBase, tgCallTimeout, tgRetryBackoff = srv.URL+"/botTEST", 50*time.Millisecond, time.Millisecond
+	tgOutbox = newTGOutbox()
+	tgOutbox.start()
+	var stalled sync.WaitGroup
+	t.Cleanup(func() {
+		close(release)
+		stalled.Wait() // their retries must not reach the next test's server
+		srv.Close()
+		tgAPIBase, tgOutbox, tgCallTimeout, tgRetryBackoff = savedBase, savedOutbox, savedTimeout, savedBackoff
+	})
+
+	for chat := int64(1); chat <= tgOutboxWorkers; chat++ {
+		stalled.Add(1)
+		go func(chat int64) {
+			defer stalled.Done()
+			tgSendMessage(chat, "stalls", nil)
+		}(chat)
+	}
+	time.Sleep(20 * time.Millisecond) // let the stalled calls take the workers
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := tgSendMessage(100, "hello", nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("message to a healthy chat: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("stalled chats blocked a message to another chat")
+	}
+}
+
+func TestTGGroupCommands(t *testing.T) {
+	withFake1Click(t, fakeScenarios["success"])
+	tg := withFakeTelegram(t)
+
+	const groupID = -1004242
+	group := func(text string) {
+		handleTGMessage(&TGMessage{MessageID: 1, Chat: TGChat{ID: groupID, Type: "supergroup"}, Text: text})
+	}
+	sent := func() []fakeTGCall {
+		var out []fakeTGCall
+		for _, c := range tg.take() {
+			if c.Method == "sendMessage" {
+				out = append(out, c)
+			}
+		}
+		return out
+	}
+	button := func(c fakeTGCall) string {
+		markup, _ := c.Payload["reply_markup"].(map[string]interface{})
+		rows, _ := markup["inline_keyboard"].([]interface{})
+		if len(rows) == 0 {
+			return ""
+		}
+		btn := rows[0].([]interface{})[0].(map[string]interface{})
+		return btn["text"].(string) + " " + btn["url"].(string)
+	}
+
+	group("/quote@TestBot 1 ETH USDT")
+	calls := sent()
+	if len(calls) != 1 {
+		t.Fatalf("/quote in a group: got %d messages, want 1", len(calls))
+	}
+	text, _ := calls[0].Payload["text"].(string)
+	if !strings.Contains(text, "QUOTE") || !strings.Contains(text, "2997") {
+		t.Errorf("group quote card should show the dry quote, got:\n%s", text)
+	}
+	if strings.Contains(text, tgGroupQuoteAccount) || strings.Contains(text, "0x") {
+		t.Errorf("group quote card must not show an address:\n%s", text)
+	}
+	if b := button(calls[0]); !strings.HasPrefix(b, "🔒 Swap privately") || !strings.Contains(b, "start=swap_ETH-eth_USDT-") {
+		t.Errorf("group quote should link to a private swap, got %q", b)
+	}
+
+	group("/price ETH")
+	if calls := sent(); len(calls) != 1 || !strings.Contains(calls[0].Payload["text"].(string), "PRICE") {
+		t.Errorf("/price in a group should post a price card, got %+v", calls)
+	}
+
+	// The private swap flow, other bots' commands and plain text stay
+	// silent in groups, and no session is started.
+	for _, text := range []string{"/start", "/status abc", "/quote@OtherBot 1 ETH USDT", "0x000000000000000000000000000000000000bEEF"} {
+		group(text)
+	}
+	for _, c := range sent() {
+		if text, _ := c.Payload["text"].(string); strings.Contains(text, "SWAP") || strings.Contains(text, "order") {
+			t.Errorf("group got a private-flow message: %s", text)
+		}
+	}
+	if tgSessions.find(groupID) != nil {
+		t.Error("group commands must not create a swap session")
+	}
+
+	// Per-group limit: one notice, then silence.
+	for i := 0; i < tgGroupQuoteLimit+3; i++ {
+		group("/quote 1 ETH USDT")
+	}
+	notices := 0
+	for _, text := range tg.texts(tg.take(), "sendMessage") {
+		if strings.Contains(text, "Too many requests") {
+			notices++
+		}
+	}
+	if notices != 1 {
+		t.Errorf("rate-limited group got %d notices, want 1", notices)
+	}
+}
+
+func TestTGAlerts(t *testing.T) {
+	withFake1Click(t, fakeScenarios["success"])
+	tg := withFakeTelegram(t)
+	savedAlerts, savedFiles := tgAlerts, sealedFiles
+	t.Cleanup(func() { tgAlerts, sealedFiles = savedAlerts, savedFiles })
+	t.Setenv("TG_ALERTS_FILE", filepath.Join(t.TempDir(), "alerts.enc"))
+	tgAlerts = newTGAlertStore()
+	initTGAlerts()
+
+	const chatID = 4205
+	t.Cleanup(func() {
+		tgSessions.mu.Lock()
+		delete(tgSessions.sessions, chatID)
+		tgSessions.mu.Unlock()
+	})
+	private := func(text string) string {
+		handleTGMessage(&TGMessage{MessageID: 1, Chat: TGChat{ID: chatID, Type: "private"}, Text: text})
+		sent := tg.texts(tg.take(), "sendMessage")
+		return strings.Join(sent, "\n")
+	}
+
+	if reply := private("/alert ETH below 100000"); !strings.Contains(reply, "Alert set") || !strings.Contains(reply, "$3,000") {
+		t.Errorf("/alert reply: %q", reply)
+	}
+	private("/alert ETH USDT below 5000")   // fake rate is 2997
+	private("/alert ETH USDT above 1000000") // not met
+	for _, bad := range []string{"/alert NOPE below 1", "/alert ETH sideways 5", "/alert ETH below -3", "/alert ETH ETH above 1"} {
+		if reply := private(bad); strings.Contains(reply, "Alert set") {
+			t.Errorf("%q should be refused", bad)
+		}
+	}
+	for len(tgAlerts.list(chatID)) < tgAlertMaxPerChat {
+		private("/alert BTC above 99999999")
+	}
+	if reply := private("/alert BTC above 1"); !strings.Contains(reply, "at most 10") {
+		t.Errorf("alert over the per-user cap: %q", reply)
+	}
+
+	// Alerts survive a restart when TG_ALERTS_FILE is set.
+	saveSealedFiles()
+	tgAlerts = newTGAlertStore()
+	initTGAlerts()
+	if n := len(tgAlerts.list(chatID)); n != tgAlertMaxPerChat {
+		t.Fatalf("reloaded %d alerts, want %d", n, tgAlertMaxPerChat)
+	}
+
+	tgAlerts.check()
+	calls := tg.take()
+	notices := 0
+	for _, text := range tg.texts(calls, "sendMessage") {
+		if strings.HasPrefix(text, "🔔 <b>ETH") {
+			notices++
+		}
+	}
+	if notices != 2 || len(tgAlerts.list(chatID)) != tgAlertMaxPerChat-2 {
+		t.Errorf("check fired %d alerts, %d left; want 2 fired", notices, len(tgAlerts.list(chatID)))
+	}
+	if sess := tgSessions.find(chatID); sess == nil || sess.FromTicker != "ETH" || sess.ToTicker != "USDT" {
+		t.Errorf("a fired alert should open a swap card for the pair, session %+v", sess)
+	}
+
+	// /alerts lists them with delete buttons.
+	handleTGAlerts(chatID)
+	list := tg.take()
+	markup, _ := list[0].Payload["reply_markup"].(map[string]interface{})
+	rows, _ := markup["inline_keyboard"].([]interface{})
+	if len(rows) != tgAlertMaxPerChat-2 {
+		t.Fatalf("/alerts shows %d buttons, want %d", len(rows), tgAlertMaxPerChat-2)
+	}
+	data := rows[0].([]interface{})[0].(map[string]interface{})["callback_data"].(string)
+	handleTGCallback(&TGCallbackQuery{ID: "cb", Data: data, Message: &TGMessage{MessageID: 55, Chat: TGChat{ID: chatID, Type: "private"}}})
+	if len(tgAlerts.list(chatID)) != tgAlertMaxPerChat-3 {
+		t.Error("delete button should remove the alert")
+	}
+	if edits := tg.texts(tg.take(), "editMessageText"); len(edits) != 1 {
+		t.Errorf("delete should redraw the list, got %d edits", len(edits))
+	}
+
+	// A chat in the middle of a swap keeps its card and gets a link.
+	sess := tgSessions.get(chatID)
+	sess.mu.Lock()
+	sess.RefundAddr = "0x000000000000000000000000000000000000bEEF"
+	sess.mu.Unlock()
+	private("/alert ETH above 1")
+	tgAlerts.check()
+	calls = tg.take()
+	if len(calls) != 1 || !strings.Contains(fmt.Sprint(calls[0].Payload["reply_markup"]), "start=swap_ETH-eth_USDT-") {
+		t.Errorf("busy chat should get one notice with a swap link, got %+v", calls)
+	}
+}
+
+func TestTGMessagesFollowLanguage(t *testing.T) {
+	withFake1Click(t, fakeScenarios["success"])
+	tg := withFakeTelegram(t)
+
+	const chatID = 4209
+	t.Cleanup(func() {
+		tgSessions.mu.Lock()
+		delete(tgSessions.sessions, chatID)
+		tgSessions.mu.Unlock()
+	})
+	send := func(chat TGChat, text string) string {
+		handleTGMessage(&TGMessage{MessageID: 1, Chat: chat, From: &TGUser{ID: chatID, LanguageCode: "es"}, Text: text})
+		return strings.Join(tg.texts(tg.take(), "sendMessage"), "\n")
+	}
+
+	private := TGChat{ID: chatID, Type: "private"}
+	if reply := send(private, "/alert"); !strings.Contains(reply, "Uso:") {
+		t.Errorf("/alert usage should follow the user's language, got %q", reply)
+	}
+	if reply := send(private, "/price NOPE"); !strings.Contains(reply, "Token desconocido NOPE.") {
+		t.Errorf("/price should follow the user's language, got %q", reply)
+	}
+	if reply := send(private, "/quote"); !strings.Contains(reply, "Uso: /quote 1 ETH USDT") {
+		t.Errorf("/quote should follow the user's language, got %q", reply)
+	}
+	// A group is read by everyone, so it stays in English.
+	group := TGChat{ID: -chatID, Type: "group"}
+	if reply := send(group, "/price NOPE"); !strings.Contains(reply, "Unknown token NOPE.") {
+		t.Errorf("group /price should be English, got %q", reply)
+	}
+	if reply := send(group, "/quote"); !strings.Contains(reply, "Usage: /quote 1 ETH USDT") {
+		t.Errorf("group /quote should be English, got %q", reply)
+	}
+}
+
+func TestTGSessionsFile(t *testing.T) {
+	savedSessions, savedFiles := tgSessions, sealedFiles
+	t.Cleanup(func() { tgSessions, sealedFiles = savedSessions, savedFiles })
+	t.Setenv("TG_SESSIONS_FILE", filepath.Join(t.TempDir(), "sessions.enc"))
+	newStore := func() {
+		tgSessions = &tgSessionStore{sessions: make(map[int64]*tgSession)}
+		sealedFiles = nil
+		initTGSessions()
+	}
+	newStore()
+
+	sess := tgSessions.get(4301)
+	sess.reset()
+	sess.State = stateEnterLockPass
+	sess.FromTicker, sess.FromNet = "ETH", "eth"
+	sess.RecvAddr = "0x2222222222222222222222222222222222222222"
+	sess.LockPassphrase = "correct horse"
+	sess.OrderKey = []byte("derived key")
+	tgSessions.get(4302).LastTouch = time.Now().Add(-tgSessionTTL - time.Minute)
+	saveSealedFiles()
+
+	raw, err := os.ReadFile(os.Getenv("TG_SESSIONS_FILE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("0x2222")) {
+		t.Error("sessions file is not encrypted")
+	}
+	for _, s := range tgSessions.snapshot().(map[int64]json.RawMessage) {
+		if bytes.Contains(s, []byte("correct horse")) || bytes.Contains(s, []byte("OrderKey")) {
+			t.Errorf("lock secrets written to the sessions file: %s", s)
+		}
+	}
+
+	newStore()
+	got := tgSessions.find(4301)
+	if got == nil || got.FromTicker != "ETH" || got.RecvAddr != sess.RecvAddr || got.Slippage != "1" {
+		t.Fatalf("restored session: %+v", got)
+	}
+	if got.State != stateQuoteConfirm || got.LockPassphrase != "" || got.OrderKey != nil {
+		t.Errorf("restored lock state: state %d, passphrase %q, key %v", got.State, got.LockPassphrase, got.OrderKey)
+	}
+	if tgSessions.find(4302) != nil {
+		t.Error("a session idle past the TTL should not be restored")
+	}
+}
+
+func TestTGAddressBook(t *testing.T) {
+	withFake1Click(t, fakeScenarios["success"])
+	tg := withFakeTelegram(t)
+	savedBooks, savedFiles := tgAddrBooks, sealedFiles
+	t.Cleanup(func() { tgAddrBooks, sealedFiles = savedBooks, savedFiles })
+	t.Setenv("TG_ADDRESSES_FILE", filepath.Join(t.TempDir(), "addresses.enc"))
+	newStore := func() {
+		tgAddrBooks = newTGAddrBookStore()
+		sealedFiles = nil
+		initTGAddrBooks()
+	}
+	newStore()
+
+	const chatID = 4401
+	const addr = "0x000000000000000000000000000000000000dEaD"
+	t.Cleanup(func() {
+		tgSessions.mu.Lock()
+		delete(tgSessions.sessions, chatID)
+		tgSessions.mu.Unlock()
+	})
+	private := func(text string) string {
+		handleTGMessage(&TGMessage{MessageID: 1, Chat: TGChat{ID: chatID, Type: "private"}, Text: text})
+		return strings.Join(tg.texts(tg.take(), "sendMessage"), "\n")
+	}
+	callback := func(data string) []fakeTGCall {
+		handleTGCallback(&TGCallbackQuery{ID: "cb", Data: data, Message: &TGMessage{MessageID: 56, Chat: TGChat{ID: chatID, Type: "private"}}})
+		return tg.take()
+	}
+	promptMarkup := func(data string) string {
+		for _, c := range callback(data) {
+			if c.Method == "sendMessage" {
+				return fmt.Sprint(c.Payload["reply_markup"])
+			}
+		}
+		return ""
+	}
+
+	// A completed swap offers to save its addresses; that is the opt-in.
+	token := placeFakeOrder(t)
+	order, _ := decryptOrderData(token)
+	markup := &TGInlineKeyboardMarkup{}
+	addSaveAddressesButton(defaultLocale, markup, chatID, order, "PROCESSING")
+	if len(markup.InlineKeyboard) != 0 {
+		t.Error("an unfinished swap should not offer 📒 Save addresses")
+	}
+	addSaveAddressesButton(defaultLocale, markup, chatID, order, "SUCCESS")
+	if len(markup.InlineKeyboard) != 1 || markup.InlineKeyboard[0][0].CallbackData != "ab:s" {
+		t.Fatalf("a completed swap should offer 📒 Save addresses, got %+v", markup.InlineKeyboard)
+	}
+	if _, ok := tgAddrBooks.books[chatID]; ok {
+		t.Fatal("nothing should be stored before the user saves an address")
+	}
+	sess := tgSessions.get(chatID)
+	sess.OrderToken, sess.CardMsgID = token, 9
+	if sent := tg.texts(callback("ab:s"), "sendMessage"); len(sent) != 1 || !strings.Contains(sent[0], "Saved 1") {
+		t.Errorf("save from order: %q", sent)
+	}
+	if entries := tgAddrBooks.get(chatID).Entries; len(entries) != 1 || entries[0].Address != addr || entries[0].Label != "ETH refund" {
+		t.Fatalf("saved entries: %+v", entries)
+	}
+	if bytes.Contains(tgAddrBooks.books[chatID], []byte("dEaD")) {
+		t.Error("the book should be sealed in memory")
+	}
+
+	for _, bad := range []string{"/addresses add eth 0x1234", "/addresses add nope " + addr, "/addresses add btc " + addr} {
+		if reply := private(bad); strings.Contains(reply, "Saved") {
+			t.Errorf("%q should be refused, got %q", bad, reply)
+		}
+	}
+	if reply := private("/addresses add base " + addr + " Cold wallet"); !strings.Contains(reply, "Saved <b>Cold wallet</b>") {
+		t.Errorf("relabel: %q", reply)
+	}
+	if entries := tgAddrBooks.get(chatID).Entries; len(entries) != 1 || entries[0].Label != "Cold wallet" {
+		t.Errorf("an EVM address saved again should be relabelled, got %+v", entries)
+	}
+
+	// The refund prompt offers saved addresses for the network being sent.
+	sess.mu.Lock()
+	sess.reset()
+	sess.FromTicker, sess.FromNet = "USDC", "arb"
+	sess.mu.Unlock()
+	if prompt := promptMarkup("sr"); !strings.Contains(prompt, "ab:p:1") {
+		t.Fatalf("refund prompt should list the saved address, got %s", prompt)
+	}
+	callback("ab:p:1")
+	if sess.RefundAddr != addr || sess.State != stateSwapCard {
+		t.Errorf("picking a saved address: refund %q, state %d", sess.RefundAddr, sess.State)
+	}
+	sess.mu.Lock()
+	sess.ToTicker, sess.ToNet = "BTC", "btc"
+	sess.mu.Unlock()
+	if prompt := promptMarkup("sp"); !strings.Contains(prompt, "force_reply") || strings.Contains(prompt, "ab:p:") {
+		t.Errorf("no saved address fits btc, so the prompt should be a plain reply, got %s", prompt)
+	}
+
+	// Books survive a restart when TG_ADDRESSES_FILE is set, and /forget
+	// removes them from memory and the file.
+	saveSealedFiles()
+	newStore()
+	if n := len(tgAddrBooks.get(chatID).Entries); n != 1 {
+		t.Fatalf("reloaded %d addresses, want 1", n)
+	}
+	if reply := private("/forget"); !strings.Contains(reply, "Deleted") {
+		t.Errorf("/forget: %q", reply)
+	}
+	saveSealedFiles()
+	newStore()
+	if len(tgAddrBooks.books) != 0 {
+		t.Error("/forget should delete the book from the file")
+	}
+	if reply := private("/addresses"); !strings.Contains(reply, "Nothing is stored") {
+		t.Errorf("/addresses with no book: %q", reply)
+	}
+}
+
+func TestTGHistory(t *testing.T) {
+	withFake1Click(t, fakeScenarios["success"])
+	tg := withFakeTelegram(t)
+	savedHistory, savedFiles, savedRetention := tgHistory, sealedFiles, tgHistoryRetention
+	t.Cleanup(func() { tgHistory, sealedFiles, tgHistoryRetention = savedHistory, savedFiles, savedRetention })
+	t.Setenv("TG_HISTORY_FILE", filepath.Join(t.TempDir(), "history.enc"))
+	t.Setenv("TG_HISTORY_DAYS", "2")
+	newStore := func() {
+		tgHistory = newTGHistoryStore()
+		sealedFiles = nil
+		initTGHistory()
+	}
+	newStore()
+	if tgHistoryRetention != 48*time.Hour {
+		t.Errorf("TG_HISTORY_DAYS=2 gave retention %v", tgHistoryRetention)
+	}
+
+	const chatID = 4501
+	t.Cleanup(func() {
+		tgSessions.mu.Lock()
+		delete(tgSessions.sessions, chatID)
+		tgSessions.mu.Unlock()
+	})
+	callback := func(data string) []fakeTGCall {
+		handleTGCallback(&TGCallbackQuery{ID: "cb", Data: data, Message: &TGMessage{MessageID: 57, Chat: TGChat{ID: chatID, Type: "private"}}})
+		return tg.take()
+	}
+	first, token := placeFakeOrder(t), placeFakeOrder(t)
+
+	// Nothing is recorded until the user turns history on.
+	tgHistory.record(chatID, first)
+	handleTGHistory(chatID)
+	if sent := tg.texts(tg.take(), "sendMessage"); len(sent) != 1 || !strings.Contains(sent[0], "Turn on history") {
+		t.Fatalf("/history before opting in: %q", sent)
+	}
+	if tgHistory.entries(chatID) != nil {
+		t.Fatal("an order was recorded before history was turned on")
+	}
+	callback("oh:on")
+	tgHistory.record(chatID, first)
+	for i := 0; i < tgHistoryMax; i++ {
+		tgHistory.record(chatID, token)
+	}
+	entries := tgHistory.entries(chatID)
+	if len(entries) != tgHistoryMax || entries[len(entries)-1].Token != token {
+		t.Fatalf("history should keep the newest %d orders, got %d", tgHistoryMax, len(entries))
+	}
+	if bytes.Contains(tgHistory.lists[chatID], []byte(token)) {
+		t.Error("the history should be sealed in memory")
+	}
+
+	handleTGHistory(chatID)
+	list := tg.take()
+	if text := tg.texts(list, "sendMessage"); len(text) != 1 || !strings.Contains(text[0], "1 ETH → USDT") || !strings.Contains(text[0], "just now") {
+		t.Fatalf("/history list: %q", text)
+	}
+	markup := fmt.Sprint(list[0].Payload["reply_markup"])
+	if strings.Count(markup, "callback_data:oh:") != tgHistoryMax+1 {
+		t.Errorf("/history should have a button per order plus turn off, got %s", markup)
+	}
+
+	// A button reopens the order card.
+	callback("oh:" + strconv.Itoa(entries[0].ID))
+	if sess := tgSessions.find(chatID); sess == nil || sess.OrderToken != token || sess.State != stateOrderActive {
+		t.Errorf("history button should open the order card, session %+v", sess)
+	}
+
+	// Orders past the retention period are purged.
+	tgHistory.mu.Lock()
+	l := tgHistory.open(chatID)
+	for i := range l.Entries[:4] {
+		l.Entries[i].Added = time.Now().Add(-49 * time.Hour)
+	}
+	tgHistory.seal(chatID, l)
+	tgHistory.mu.Unlock()
+	tgHistory.purge()
+	if n := len(tgHistory.entries(chatID)); n != tgHistoryMax-4 {
+		t.Errorf("after purge %d orders left, want %d", n, tgHistoryMax-4)
+	}
+
+	// Histories survive a restart when TG_HISTORY_FILE is set; /forget
+	// deletes them and turns history off.
+	saveSealedFiles()
+	newStore()
+	if n := len(tgHistory.entries(chatID)); n != tgHistoryMax-4 {
+		t.Fatalf("reloaded %d orders, want %d", n, tgHistoryMax-4)
+	}
+	handleTGForget(chatID)
+	if sent := tg.texts(tg.take(), "sendMessage"); len(sent) != 1 || !strings.Contains(sent[0], "order history") {
+		t.Errorf("/forget: %q", sent)
+	}
+	tgHistory.record(chatID, token)
+	saveSealedFiles()
+	newStore()
+	if tgHistory.enabled(chatID) {
+		t.Error("/forget should turn history off and delete it from the file")
+	}
+}
+
+// signTGInitData signs fields the way Telegram signs Mini App launch data.
+func signTGInitData(fields url.Values) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	lines := make([]string, len(keys))
+	for i, k := range keys {
+		lines[i] = k + "=" + fields.Get(k)
+	}
+	secret := hmac.New(sha256.New, []byte("WebAppData"))
+	secret.Write([]byte(tgBotToken))
+	mac := hmac.New(sha256.New, secret.Sum(nil))
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	signed := url.Values{}
+	for k := range fields {
+		signed.Set(k, fields.Get(k))
+	}
+	signed.Set("hash", hex.EncodeToString(mac.Sum(nil)))
+	return signed.Encode()
+}
+
+func TestTGApp(t *testing.T) {
+	withFake1Click(t, fakeScenarios["success"])
+	tg := withFakeTelegram(t)
+	savedToken := tgBotToken
+	tgBotToken = "123:TEST"
+	t.Cleanup(func() { tgBotToken = savedToken })
+
+	launch := func(authDate time.Time, queryID string) string {
+		return signTGInitData(url.Values{
+			"auth_date": {strconv.FormatInt(authDate.Unix(), 10)},
+			"query_id":  {queryID},
+			"user":      {`{"id":4601,"first_name":"Ann","language_code":"en"}`},
+		})
+	}
+	fresh := launch(time.Now(), "AAQ1")
+
+	// The launch page is served to anyone; it only forwards the fragment.
+	req := httptest.NewRequest("GET", "/tg/app?from=ETH", nil)
+	w := httptest.NewRecorder()
+	handleTGApp(w, req)
+	if w.Code != 200 || !strings.Contains(w.Body.String(), "tgWebAppData") {
+		t.Fatalf("GET /tg/app: got %d\n%s", w.Code, w.Body.String())
+	}
+
+	tampered := strings.Replace(fresh, "4601", "4602", 1)
+	for name, data := range map[string]string{
+		"unsigned": "auth_date=1&user=%7B%22id%22%3A4601%7D",
+		"tampered": tampered,
+		"stale":    launch(time.Now().Add(-2*time.Hour), "AAQ1"),
+		"future":   launch(time.Now().Add(time.Hour), "AAQ1"),
+		"missing":  "",
+	} {
+		w := postForm(handleTGApp, "/tg/app", url.Values{"tg_init_data": {data}})
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s initData: got %d, want 403", name, w.Code)
+		}
+	}
+
+	theme := `{"bg_color":"#17212b","text_color":"#f5f5f5","button_color":"red;}body{"}`
+	w = postForm(handleTGApp, "/tg/app?from=ETH&from_net=eth&to=USDT&to_net=eth", url.Values{
+		"tg_init_data": {fresh},
+		"tg_theme":     {theme},
+	})
+	body := w.Body.String()
+	if w.Code != 200 {
+		t.Fatalf("POST /tg/app: got %d\n%s", w.Code, body)
+	}
+	for _, want := range []string{`action="/tg/app/quote"`, `name="tg_init_data"`, "--bg:#17212b", "--fg-a:245, 245, 245"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("swap page in the app should contain %q", want)
+		}
+	}
+	if css := string(tgThemeCSS(theme)); strings.Contains(css, "red") {
+		t.Errorf("theme params that aren't colors should be dropped, got %q", css)
+	}
+
+	w = postForm(handleTGApp, "/tg/app?modal=to&from=ETH&from_net=eth", url.Values{"tg_init_data": {fresh}})
+	if w.Code != 200 || !strings.Contains(w.Body.String(), `class="token-card"`) || strings.Contains(w.Body.String(), `href="/?`) {
+		t.Errorf("token picker in the app should link inside /tg/app, got %d", w.Code)
+	}
+
+	w = postForm(handleTGApp, "/tg/app/quote", url.Values{
+		"tg_init_data": {fresh},
+		"csrf":         {generateCSRFToken("quote")},
+		"from":         {"ETH"},
+		"from_net":     {"eth"},
+		"to":           {"USDT"},
+		"to_net":       {"eth"},
+		"amount":       {"1"},
+		"recipient":    {"0x000000000000000000000000000000000000dEaD"},
+		"refund_addr":  {"0x000000000000000000000000000000000000dEaD"},
+		"slippage":     {"1"},
+	})
+	if w.Code != 200 || !strings.Contains(w.Body.String(), `action="/tg/app/swap"`) {
+		t.Fatalf("POST /tg/app/quote: got %d, want the quote page posting to /tg/app/swap", w.Code)
+	}
+
+	tg.take()
+	w = postForm(handleTGApp, "/tg/app/swap", url.Values{
+		"tg_init_data":  {fresh},
+		"csrf":          {generateCSRFToken("swap")},
+		"from":          {"ETH"},
+		"from_net":      {"eth"},
+		"to":            {"USDT"},
+		"to_net":        {"eth"},
+		"atomic_amount": {"1000000000000000000"},
+		"recipient":     {"0x000000000000000000000000000000000000dEaD"},
+		"refund_addr":   {"0x000000000000000000000000000000000000dEaD"},
+		"slippage_bps":  {"100"},
+		"swap_type":     {"FLEX_INPUT"},
+	})
+	if w.Code != http.StatusFound {
+		t.Fatalf("POST /tg/app/swap: got %d, want 302\n%s", w.Code, w.Body.String())
+	}
+	token := strings.TrimPrefix(w.Header().Get("Location"), "/order/")
+	var answer map[string]interface{}
+	for _, c := range tg.take() {
+		if c.Method == "answerWebAppQuery" {
+			answer = c.Payload
+		}
+	}
+	if answer == nil || answer["web_app_query_id"] != "AAQ1" {
+		t.Fatalf("the order should be handed back with answerWebAppQuery, got %v", answer)
+	}
+	result, _ := answer["result"].(map[string]interface{})
+	content, _ := result["input_message_content"].(map[string]interface{})
+	if text, _ := content["message_text"].(string); !strings.Contains(text, "/status "+token) {
+		t.Errorf("hand-off should carry the order token, got %q", text)
+	}
+
+	// Without a query to answer, the order goes to the user's private chat.
+	postForm(handleTGApp, "/tg/app/swap", url.Values{
+		"tg_init_data":  {launch(time.Now(), "")},
+		"csrf":          {generateCSRFToken("swap")},
+		"from":          {"ETH"},
+		"from_net":      {"eth"},
+		"to":            {"USDT"},
+		"to_net":        {"eth"},
+		"atomic_amount": {"1000000000000000000"},
+		"recipient":     {"0x000000000000000000000000000000000000dEaD"},
+		"refund_addr":   {"0x000000000000000000000000000000000000dEaD"},
+		"slippage_bps":  {"100"},
+		"swap_type":     {"FLEX_INPUT"},
+	})
+	calls := tg.take()
+	if texts := tg.texts(calls, "sendMessage"); len(texts) != 1 || calls[0].Payload["chat_id"] != float64(4601) {
+		t.Errorf("hand-off without query_id should message the user, got %v", calls)
+	}
+
+	// Orders placed on the website don't reach Telegram.
+	placeFakeOrder(t)
+	if calls := tg.take(); len(calls) != 0 {
+		t.Errorf("website orders should not reach Telegram, got %v", calls)
+	}
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Address book.
+//
+// A chat can keep labelled refund and receive addresses and pick them from
+// the address prompts instead of pasting them again. Nothing is stored
+// until the user saves an address, with /addresses add or the 📒 button on
+// a completed swap, and /forget deletes the whole book. Each chat's book is
+// sealed on its own (see sealedfile.go), in memory and in TG_ADDRESSES_FILE
+// if the operator sets one.
+
+const (
+	tgAddrBookMaxPerChat = 20
+	tgAddrBookMaxChats   = 20000
+	tgAddrLabelMax       = 24 // runes
+	tgAddrBookPurpose    = "tg-addresses"
+)
+
+var (
+	errTGAddrBookChatLimit = fmt.Errorf("your address book is full (%d addresses); delete one with /addresses", tgAddrBookMaxPerChat)
+	errTGAddrBookFull      = errors.New("the address book is full right now; please try again later")
+)
+
+type tgAddrEntry struct {
+	ID      int       `json:"id"`
+	Label   string    `json:"label"`
+	Chain   string    `json:"chain"`
+	Address string    `json:"address"`
+	Added   time.Time `json:"added"`
+}
+
+// tgAddrBook is one chat's book, the plaintext of its sealed record.
+type tgAddrBook struct {
+	Entries []tgAddrEntry `json:"entries"`
+	NextID  int           `json:"nextId"`
+}
+
+// put adds an entry, or relabels the entry that already holds the address.
+func (b *tgAddrBook) put(label, chain, addr string) error {
+	for i, e := range b.Entries {
+		if e.Address == addr && tgAddrFits(e.Chain, chain) {
+			if label != "" {
+				b.Entries[i].Label = label
+			}
+			return nil
+		}
+	}
+	if len(b.Entries) >= tgAddrBookMaxPerChat {
+		return errTGAddrBookChatLimit
+	}
+	b.NextID++
+	b.Entries = append(b.Entries, tgAddrEntry{ID: b.NextID, Label: label, Chain: chain, Address: addr, Added: time.Now()})
+	return nil
+}
+
+// has reports whether addr is saved for a network it fits.
+func (b *tgAddrBook) has(chain, addr string) bool {
+	for _, e := range b.Entries {
+		if e.Address == addr && tgAddrFits(e.Chain, chain) {
+			return true
+		}
+	}
+	return false
+}
+
+// tgAddrFits reports whether an address saved for saved can be used on
+// chain. EVM addresses are the same on every EVM network.
+func tgAddrFits(saved, chain string) bool {
+	return saved == chain || evmChains[saved] && evmChains[chain]
+}
+
+type tgAddrBookStore struct {
+	mu    sync.Mutex
+	books map[int64][]byte // sealed tgAddrBook per chat
+	file  *sealedFile      // nil when books are only kept in memory
+}
+
+var tgAddrBooks = newTGAddrBookStore()
+
+func newTGAddrBookStore() *tgAddrBookStore {
+	return &tgAddrBookStore{books: make(map[int64][]byte)}
+}
+
+// initTGAddrBooks loads address books from TG_ADDRESSES_FILE, if set. The
+// file keeps each book sealed as it is in memory.
+func initTGAddrBooks() {
+	var books map[int64][]byte
+	f := openSealedFile("TG_ADDRESSES_FILE", tgAddrBookPurpose, &books, tgAddrBooks.snapshot)
+	if f == nil {
+		return
+	}
+	tgAddrBooks.mu.Lock()
+	defer tgAddrBooks.mu.Unlock()
+	tgAddrBooks.file = f
+	for chatID, sealed := range books {
+		tgAddrBooks.books[chatID] = sealed
+	}
+}
+
+func (s *tgAddrBookStore) snapshot() interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	books := make(map[int64][]byte, len(s.books))
+	for chatID, sealed := range s.books {
+		books[chatID] = sealed
+	}
+	return books
+}
+
+// open decrypts chatID's book. Caller must hold s.mu.
+func (s *tgAddrBookStore) open(chatID int64) *tgAddrBook {
+	book := &tgAddrBook{}
+	sealed, ok := s.books[chatID]
+	if !ok {
+		return book
+	}
+	if err := openRecord(tgAddrBookPurpose, chatID, sealed, book); err != nil {
+		log.Printf("tg address book: %v", err)
+		return &tgAddrBook{}
+	}
+	return book
+}
+
+// get returns chatID's book; an empty one if it has none.
+func (s *tgAddrBookStore) get(chatID int64) *tgAddrBook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.open(chatID)
+}
+
+// update applies fn to chatID's book and seals the result. A book left
+// empty is deleted.
+func (s *tgAddrBookStore) update(chatID int64, fn func(*tgAddrBook) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.books[chatID]; !ok && len(s.books) >= tgAddrBookMaxChats {
+		return errTGAddrBookFull
+	}
+	book := s.open(chatID)
+	if err := fn(book); err != nil {
+		return err
+	}
+	if len(book.Entries) == 0 {
+		delete(s.books, chatID)
+		s.file.markDirty()
+		return nil
+	}
+	sealed, err := sealRecord(tgAddrBookPurpose, chatID, book)
+	if err != nil {
+		return err
+	}
+	s.books[chatID] = sealed
+	s.file.markDirty()
+	return nil
+}
+
+// forget deletes chatID's book and returns how many addresses it held.
+func (s *tgAddrBookStore) forget(chatID int64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.books[chatID]; !ok {
+		return 0
+	}
+	n := len(s.open(chatID).Entries)
+	delete(s.books, chatID)
+	s.file.markDirty()
+	return n
+}
+
+// isKnownChain reports whether any listed token is on chain.
+func isKnownChain(chain string) bool {
+	tokens, _ := getTokens()
+	for _, t := range tokens {
+		if strings.EqualFold(t.ChainName, chain) {
+			return true
+		}
+	}
+	return false
+}
+
+// tgAddrBookUsage explains how to add addresses.
+func tgAddrBookUsage(l *locale) string {
+	return l.T("Add one with\n<code>/addresses add eth 0x… Ledger</code>\n— network, address, then a label — or tap 📒 Save addresses on a completed swap.")
+}
+
+// handleTGAddresses handles /addresses (the list) and
+// /addresses add <network> <address> [label].
+func handleTGAddresses(chatID int64, args string) {
+	l := tgSessions.locFor(chatID)
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		text, markup := renderTGAddrBook(l, chatID)
+		tgSendMessage(chatID, text, markup)
+		return
+	}
+	if strings.ToLower(fields[0]) != "add" || len(fields) < 3 {
+		tgSendMessage(chatID, l.T("Usage: /addresses add eth 0x… Ledger"), nil)
+		return
+	}
+
+	chain, addr := strings.ToLower(fields[1]), fields[2]
+	if !isKnownChain(chain) {
+		tgSendMessage(chatID, l.T("Unknown network %s. Use a network code such as eth, btc, sol or tron.", html.EscapeString(fields[1])), nil)
+		return
+	}
+	if err := validateAddress(chain, addr); err != nil {
+		tgSendMessage(chatID, "❌ "+html.EscapeString(err.Error()), nil)
+		return
+	}
+	label := safeRunes(strings.Join(fields[3:], " "), tgAddrLabelMax)
+	if label == "" {
+		label = networkDisplayName(chain)
+	}
+	if err := tgAddrBooks.update(chatID, func(b *tgAddrBook) error { return b.put(label, chain, addr) }); err != nil {
+		tgSendMessage(chatID, "❌ "+html.EscapeString(tgErrorText(l, err)), nil)
+		return
+	}
+	tgSendMessage(chatID, l.T("📒 Saved <b>%s</b>.", html.EscapeString(label))+" "+tgAddrBookNotice(l), nil)
+}
+
+// tgAddrBookNotice follows every save.
+func tgAddrBookNotice(l *locale) string {
+	return l.T("Your address book is encrypted and only used in this chat; /forget deletes it.")
+}
+
+// handleTGForget handles /forget: the chat's address book and order
+// history are deleted.
+func handleTGForget(chatID int64) {
+	l := tgSessions.locFor(chatID)
+	n := tgAddrBooks.forget(chatID)
+	history := tgHistory.forget(chatID)
+	switch {
+	case n > 0 && history:
+		tgSendMessage(chatID, l.N("🗑 Deleted your address book (%d addresses) and your order history, which stays off until you turn it on again with /history.", n), nil)
+	case n > 0:
+		tgSendMessage(chatID, l.N("🗑 Deleted your address book (%d addresses).", n), nil)
+	case history:
+		tgSendMessage(chatID, l.T("🗑 Deleted your order history, which stays off until you turn it on again with /history."), nil)
+	default:
+		tgSendMessage(chatID, l.T("You have no saved addresses or order history."), nil)
+	}
+}
+
+// handleTGDeleteAddress deletes an entry from the /addresses list message.
+func handleTGDeleteAddress(l *locale, chatID int64, msgID int, idStr string) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return
+	}
+	tgAddrBooks.update(chatID, func(b *tgAddrBook) error {
+		for i, e := range b.Entries {
+			if e.ID == id {
+				b.Entries = append(b.Entries[:i:i], b.Entries[i+1:]...)
+				break
+			}
+		}
+		return nil
+	})
+	text, markup := renderTGAddrBook(l, chatID)
+	tgEditMessage(chatID, msgID, text, markup)
+}
+
+func renderTGAddrBook(l *locale, chatID int64) (string, *TGInlineKeyboardMarkup) {
+	book := tgAddrBooks.get(chatID)
+	if len(book.Entries) == 0 {
+		return "<b>📒 " + l.T("Address book") + "</b>\n\n" +
+			l.T("Save refund and receive addresses to pick them on the swap card instead of pasting them. Nothing is stored until you save one.") +
+			"\n\n" + tgAddrBookUsage(l), nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<b>📒 %s</b> (%d/%d)\n\n", l.T("Address book"), len(book.Entries), tgAddrBookMaxPerChat)
+	markup := &TGInlineKeyboardMarkup{}
+	for i, e := range book.Entries {
+		fmt.Fprintf(&sb, "%d. <b>%s</b> · %s\n<code>%s</code>\n", i+1,
+			html.EscapeString(e.Label), networkDisplayName(e.Chain), html.EscapeString(e.Address))
+		markup.InlineKeyboard = append(markup.InlineKeyboard, []TGInlineKeyboardButton{
+			{Text: "🗑 " + e.Label, CallbackData: "ab:d:" + strconv.Itoa(e.ID)},
+		})
+	}
+	sb.WriteString("\n" + l.T("Tap an address to delete it.") + " " + tgAddrBookUsage(l) + "\n\n" + l.T("/forget deletes the whole book."))
+	return sb.String(), markup
+}
+
+// tgAddrPicker lists the chat's saved addresses that fit chain as buttons
+// for an address prompt, or returns nil if there are none.
+func tgAddrPicker(l *locale, chatID int64, chain string) *TGInlineKeyboardMarkup {
+	var rows [][]TGInlineKeyboardButton
+	for _, e := range tgAddrBooks.get(chatID).Entries {
+		if !tgAddrFits(e.Chain, chain) {
+			continue
+		}
+		rows = append(rows, []TGInlineKeyboardButton{
+			{Text: "📒 " + e.Label + " · " + truncAddr(e.Address), CallbackData: "ab:p:" + strconv.Itoa(e.ID)},
+		})
+	}
+	if rows == nil {
+		return nil
+	}
+	rows = append(rows, []TGInlineKeyboardButton{{Text: l.T("← Back"), CallbackData: "bk"}})
+	return &TGInlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// handleTGPickAddress fills the prompted refund or receive address from
+// the address book.
+func handleTGPickAddress(chatID int64, sess *tgSession, idStr string) {
+	id, _ := strconv.Atoi(idStr)
+	var entry *tgAddrEntry
+	book := tgAddrBooks.get(chatID)
+	for i := range book.Entries {
+		if book.Entries[i].ID == id {
+			entry = &book.Entries[i]
+		}
+	}
+	if entry == nil {
+		return
+	}
+
+	switch sess.State {
+	case stateEnterRefund:
+		if problem := addressProblem("refund", sess.FromNet, entry.Address); problem != "" {
+			tgSendMessage(chatID, "❌ "+problem, nil)
+			return
+		}
+		sess.RefundAddr = entry.Address
+	case stateEnterRecv:
+		if problem := addressProblem("recipient", sess.ToNet, entry.Address); problem != "" {
+			tgSendMessage(chatID, "❌ "+problem, nil)
+			return
+		}
+		sess.RecvAddr = entry.Address
+	default:
+		return
+	}
+	sess.State = stateSwapCard
+	cleanupPromptReply(chatID, sess, 0)
+	updateSwapCard(chatID, sess)
+}
+
+// addSaveAddressesButton offers to save a completed swap's addresses that
+// aren't in the chat's address book yet.
+func addSaveAddressesButton(l *locale, markup *TGInlineKeyboardMarkup, chatID int64, order *OrderData, status string) {
+	if markup == nil || strings.ToUpper(status) != "SUCCESS" {
+		return
+	}
+	book := tgAddrBooks.get(chatID)
+	if book.has(order.FromNet, order.RefundAddr) && book.has(order.ToNet, order.RecvAddr) {
+		return
+	}
+	markup.InlineKeyboard = append(markup.InlineKeyboard, []TGInlineKeyboardButton{
+		{Text: l.T("📒 Save addresses"), CallbackData: "ab:s"},
+	})
+}
+
+// handleTGSaveOrderAddresses saves the refund and receive addresses of the
+// session's order, labelled after the tokens they were used for.
+func handleTGSaveOrderAddresses(chatID int64, sess *tgSession) {
+	l := sess.loc()
+	order, err := decryptOrderData(sess.OrderToken)
+	if err != nil {
+		return
+	}
+	if order.Locked() {
+		if order, err = unlockOrderDataWithKey(order, sess.OrderKey); err != nil {
+			tgSendMessage(chatID, l.T("🔒 This order is locked. Use /status with its token to unlock it first."), nil)
+			return
+		}
+	}
+
+	saved := 0
+	err = tgAddrBooks.update(chatID, func(b *tgAddrBook) error {
+		for _, a := range []struct{ label, chain, addr string }{
+			{order.FromTicker + " refund", order.FromNet, order.RefundAddr},
+			{order.ToTicker + " receive", order.ToNet, order.RecvAddr},
+		} {
+			if a.addr == "" || b.has(a.chain, a.addr) || validateAddress(a.chain, a.addr) != nil {
+				continue
+			}
+			if err := b.put(a.label, a.chain, a.addr); err != nil {
+				return err
+			}
+			saved++
+		}
+		return nil
+	})
+	switch {
+	case err != nil:
+		tgSendMessage(chatID, "❌ "+html.EscapeString(tgErrorText(l, err)), nil)
+	case saved == 0:
+		tgSendMessage(chatID, l.T("📒 These addresses are already in your address book."), nil)
+	default:
+		tgSendMessage(chatID, l.N("📒 Saved %d addresses. Rename or delete them with /addresses.", saved)+" "+tgAddrBookNotice(l), nil)
+	}
+	handleTGRefreshStatus(chatID, sess)
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Price alerts.
+//
+// /alert BTC below 60000 watches a token's USD price from the token list.
+// /alert ETH USDT above 4000 watches a pair rate, read from a dry quote for
+// one unit of the first token, or from the token list prices when 1Click
+// has no quote. An alert fires once: the chat gets a notice and a swap card
+// pre-filled with the pair, and the alert is deleted. Alerts are kept in
+// memory unless TG_ALERTS_FILE names an encrypted file to keep them in.
+
+// tgAlertInterval is how often alerts are checked. A variable so tests can
+// change it.
+var tgAlertInterval = time.Minute
+
+const (
+	tgAlertMaxPerChat = 10
+	tgAlertMax        = 20000
+)
+
+var (
+	errTGAlertChatLimit = fmt.Errorf("you can have at most %d alerts; delete one with /alerts", tgAlertMaxPerChat)
+	errTGAlertFull      = errors.New("alerts are full right now; please try again later")
+)
+
+type tgAlert struct {
+	ID      int       `json:"id"`
+	From    string    `json:"from"`
+	FromNet string    `json:"fromNet"`
+	To      string    `json:"to,omitempty"` // empty for a USD price alert
+	ToNet   string    `json:"toNet,omitempty"`
+	Above   bool      `json:"above"`
+	Target  float64   `json:"target"`
+	Created time.Time `json:"created"`
+}
+
+// tgAlertState is what TG_ALERTS_FILE holds.
+type tgAlertState struct {
+	Alerts map[int64][]*tgAlert `json:"alerts"`
+	NextID int                  `json:"nextId"`
+}
+
+type tgAlertStore struct {
+	mu     sync.Mutex
+	alerts map[int64][]*tgAlert
+	count  int
+	nextID int
+	file   *sealedFile // nil when alerts are only kept in memory
+}
+
+var tgAlerts = newTGAlertStore()
+
+func newTGAlertStore() *tgAlertStore {
+	return &tgAlertStore{alerts: make(map[int64][]*tgAlert)}
+}
+
+// initTGAlerts loads alerts from TG_ALERTS_FILE, if set.
+func initTGAlerts() {
+	var state tgAlertState
+	f := openSealedFile("TG_ALERTS_FILE", "tg-alerts", &state, tgAlerts.snapshot)
+	if f == nil {
+		return
+	}
+	tgAlerts.mu.Lock()
+	defer tgAlerts.mu.Unlock()
+	tgAlerts.file = f
+	for chatID, list := range state.Alerts {
+		tgAlerts.alerts[chatID] = list
+		tgAlerts.count += len(list)
+	}
+	tgAlerts.nextID = state.NextID
+}
+
+func (s *tgAlertStore) snapshot() interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := tgAlertState{Alerts: make(map[int64][]*tgAlert, len(s.alerts)), NextID: s.nextID}
+	for chatID, list := range s.alerts {
+		state.Alerts[chatID] = append([]*tgAlert(nil), list...)
+	}
+	return state
+}
+
+// add stores a for chatID and assigns its ID.
+func (s *tgAlertStore) add(chatID int64, a *tgAlert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.alerts[chatID]) >= tgAlertMaxPerChat {
+		return errTGAlertChatLimit
+	}
+	if s.count >= tgAlertMax {
+		return errTGAlertFull
+	}
+	s.nextID++
+	a.ID = s.nextID
+	s.alerts[chatID] = append(s.alerts[chatID], a)
+	s.count++
+	s.file.markDirty()
+	return nil
+}
+
+// list returns a copy of chatID's alerts, oldest first.
+func (s *tgAlertStore) list(chatID int64) []tgAlert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]tgAlert, 0, len(s.alerts[chatID]))
+	for _, a := range s.alerts[chatID] {
+		out = append(out, *a)
+	}
+	return out
+}
+
+// remove deletes one alert and reports whether it existed.
+func (s *tgAlertStore) remove(chatID int64, id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.alerts[chatID]
+	for i, a := range list {
+		if a.ID == id {
+			list = append(list[:i:i], list[i+1:]...)
+			if len(list) == 0 {
+				delete(s.alerts, chatID)
+			} else {
+				s.alerts[chatID] = list
+			}
+			s.count--
+			s.file.markDirty()
+			return true
+		}
+	}
+	return false
+}
+
+// start checks every alert each tgAlertInterval.
+func (s *tgAlertStore) start() {
+	go func() {
+		for {
+			time.Sleep(tgAlertInterval)
+			s.check()
+		}
+	}()
+}
+
+// check evaluates every alert once and fires the ones that are met. Each
+// pair is quoted once per check, however many alerts watch it.
+func (s *tgAlertStore) check() {
+	type watched struct {
+		chatID int64
+		alert  tgAlert
+	}
+	var all []watched
+	pairs := make(map[string]float64)
+	s.mu.Lock()
+	for chatID, list := range s.alerts {
+		for _, a := range list {
+			all = append(all, watched{chatID, *a})
+			if a.To != "" {
+				pairs[a.pairKey()] = 0
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 4)
+	for key := range pairs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(key string) {
+			defer func() { <-sem; wg.Done() }()
+			parts := strings.Split(key, "|")
+			rate := tgPairRate(findToken(parts[0], parts[1]), findToken(parts[2], parts[3]))
+			mu.Lock()
+			pairs[key] = rate
+			mu.Unlock()
+		}(key)
+	}
+	wg.Wait()
+
+	for _, w := range all {
+		var value float64
+		if w.alert.To == "" {
+			if t := findToken(w.alert.From, w.alert.FromNet); t != nil {
+				value = t.Price
+			}
+		} else {
+			value = pairs[w.alert.pairKey()]
+		}
+		if value <= 0 || !w.alert.met(value) {
+			continue
+		}
+		if s.remove(w.chatID, w.alert.ID) { // not deleted meanwhile
+			notifyTGAlert(w.chatID, &w.alert, value)
+		}
+	}
+}
+
+func (a *tgAlert) pairKey() string {
+	return a.From + "|" + a.FromNet + "|" + a.To + "|" + a.ToNet
+}
+
+func (a *tgAlert) met(value float64) bool {
+	if a.Above {
+		return value >= a.Target
+	}
+	return value <= a.Target
+}
+
+// describe renders the alert as "ETH above $4,000" or "ETH/USDT below 3,000 USDT".
+func (a *tgAlert) describe(l *locale) string {
+	if a.Above {
+		return l.T("%s above %s", a.subject(), a.format(a.Target))
+	}
+	return l.T("%s below %s", a.subject(), a.format(a.Target))
+}
+
+func (a *tgAlert) subject() string {
+	if a.To == "" {
+		return a.From
+	}
+	return a.From + "/" + a.To
+}
+
+// format renders a price or rate in the alert's unit.
+func (a *tgAlert) format(v float64) string {
+	if a.To == "" {
+		return formatUSD(v)
+	}
+	return formatRate(v) + " " + a.To
+}
+
+// tgPairRate is how much of to one unit of from buys, from a dry quote or,
+// failing that, the token list prices. 0 means unknown.
+func tgPairRate(from, to *TokenInfo) float64 {
+	if from == nil || to == nil {
+		return 0
+	}
+	one, err := humanToAtomic("1", from.Decimals)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		dryResp, err := requestTGDryQuote(ctx, from, to, one)
+		cancel()
+		if err == nil {
+			out, _ := strconv.ParseFloat(dryResp.Quote.AmountOutFormatted, 64)
+			if in, _ := strconv.ParseFloat(dryResp.Quote.AmountInFormatted, 64); in > 0 && out > 0 {
+				return out / in
+			}
+		}
+	}
+	if from.Price > 0 && to.Price > 0 {
+		return from.Price / to.Price
+	}
+	return 0
+}
+
+// parseTGAlert parses the arguments of /alert: "<token> [<token>]
+// above|below <target>". On failure it returns a message for the user.
+func parseTGAlert(l *locale, args string) (*tgAlert, string) {
+	fields := strings.Fields(args)
+	if len(fields) != 3 && len(fields) != 4 {
+		return nil, tgAlertUsage(l)
+	}
+	a := &tgAlert{Created: time.Now()}
+	switch strings.ToLower(fields[len(fields)-2]) {
+	case "above", "over", ">":
+		a.Above = true
+	case "below", "under", "<":
+	default:
+		return nil, tgAlertUsage(l)
+	}
+	target, err := strconv.ParseFloat(strings.NewReplacer("$", "", ",", "").Replace(fields[len(fields)-1]), 64)
+	if err != nil || target <= 0 || math.IsInf(target, 0) {
+		return nil, l.T("The target must be a positive number, e.g. %s.", "60000")
+	}
+	a.Target = target
+
+	from := resolveTGToken(strings.ToUpper(fields[0]))
+	if from == nil {
+		return nil, l.T("Unknown token %s.", html.EscapeString(fields[0]))
+	}
+	a.From, a.FromNet = from.Ticker, from.ChainName
+	if len(fields) == 4 {
+		to := resolveTGToken(strings.ToUpper(fields[1]))
+		if to == nil {
+			return nil, l.T("Unknown token %s.", html.EscapeString(fields[1]))
+		}
+		if to.DefuseAssetID == from.DefuseAssetID {
+			return nil, l.T("Pick two different tokens.")
+		}
+		a.To, a.ToNet = to.Ticker, to.ChainName
+	}
+	return a, ""
+}
+
+// tgAlertUsage explains /alert and /alerts.
+func tgAlertUsage(l *locale) string {
+	return l.T("Usage:\n/alert BTC below 60000 — USD price\n/alert ETH USDT above 4000 — pair rate\n/alerts — list and delete alerts")
+}
+
+// handleTGAlert handles /alert.
+func handleTGAlert(chatID int64, args string) {
+	l := tgSessions.locFor(chatID)
+	a, problem := parseTGAlert(l, args)
+	if a == nil {
+		tgSendMessage(chatID, problem, nil)
+		return
+	}
+	if err := tgAlerts.add(chatID, a); err != nil {
+		tgSendMessage(chatID, "❌ "+tgErrorText(l, err), nil)
+		return
+	}
+	text := "🔔 " + l.T("Alert set:") + " <b>" + html.EscapeString(a.describe(l)) + "</b>"
+	if a.To == "" {
+		if t := findToken(a.From, a.FromNet); t != nil && t.Price > 0 {
+			text += "\n" + l.T("Now %s.", formatUSD(t.Price))
+		}
+	}
+	tgSendMessage(chatID, text+"\n\n"+l.T("It fires once. See /alerts to delete it."), nil)
+}
+
+// handleTGAlerts handles /alerts: the chat's alerts with delete buttons.
+func handleTGAlerts(chatID int64) {
+	text, markup := renderTGAlertList(tgSessions.locFor(chatID), chatID)
+	tgSendMessage(chatID, text, markup)
+}
+
+// handleTGDeleteAlert deletes an alert from the /alerts list message.
+func handleTGDeleteAlert(l *locale, chatID int64, msgID int, idStr string) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return
+	}
+	tgAlerts.remove(chatID, id)
+	text, markup := renderTGAlertList(l, chatID)
+	tgEditMessage(chatID, msgID, text, markup)
+}
+
+func renderTGAlertList(l *locale, chatID int64) (string, *TGInlineKeyboardMarkup) {
+	alerts := tgAlerts.list(chatID)
+	if len(alerts) == 0 {
+		return l.T("You have no price alerts.") + "\n\n" + tgAlertUsage(l), nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<b>🔔 %s</b> (%d/%d)\n\n", l.T("Price alerts"), len(alerts), tgAlertMaxPerChat)
+	markup := &TGInlineKeyboardMarkup{}
+	for i, a := range alerts {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, html.EscapeString(a.describe(l)))
+		markup.InlineKeyboard = append(markup.InlineKeyboard, []TGInlineKeyboardButton{
+			{Text: "🗑 " + a.describe(l), CallbackData: "al:" + strconv.Itoa(a.ID)},
+		})
+	}
+	sb.WriteString("\n" + l.T("Tap an alert to delete it."))
+	return sb.String(), markup
+}
+
+// notifyTGAlert tells the chat an alert fired and opens a swap card for the
+// pair. A chat in the middle of a swap keeps its card and gets a link
+// instead.
+func notifyTGAlert(chatID int64, a *tgAlert, value float64) {
+	l := tgSessions.locFor(chatID)
+	text := "🔔 <b>" + html.EscapeString(a.describe(l)) + "</b>\n" + l.T("Now %s.", html.EscapeString(a.format(value)))
+
+	// Pair alerts swap the pair; a token that rose is offered for sale and
+	// one that fell for purchase, against USDT.
+	var pair []string // from ticker, from network, to ticker, to network
+	if a.To != "" {
+		pair = []string{a.From, a.FromNet, a.To, a.ToNet}
+	} else if usdt := resolveTGToken("USDT"); usdt != nil && usdt.Ticker != a.From {
+		if a.Above {
+			pair = []string{a.From, a.FromNet, usdt.Ticker, usdt.ChainName}
+		} else {
+			pair = []string{usdt.Ticker, usdt.ChainName, a.From, a.FromNet}
+		}
+	}
+
+	sess := tgSessions.get(chatID)
+	sess.mu.Lock()
+	busy := sess.OrderToken != "" || sess.RefundAddr != "" || sess.RecvAddr != "" ||
+		(sess.State != stateIdle && sess.State != stateSwapCard)
+	sess.mu.Unlock()
+
+	if busy || pair == nil {
+		var markup *TGInlineKeyboardMarkup
+		if pair != nil {
+			markup = &TGInlineKeyboardMarkup{InlineKeyboard: [][]TGInlineKeyboardButton{
+				{{Text: "💱 " + l.T("Swap %s → %s", pair[0], pair[2]), URL: buildDeepLink(pair[0], pair[1], pair[2], pair[3], "")}},
+			}}
+		}
+		tgSendMessage(chatID, text, markup)
+		return
+	}
+	tgSendMessage(chatID, text, nil)
+	handleTGStart(chatID, swapStartParam(pair[0], pair[1], pair[2], pair[3], ""))
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"html"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Telegram Mini App.
+//
+// /tg/app runs the web swap flow inside Telegram. Telegram opens the app
+// with signed launch data (initData) in the URL fragment; a small page at
+// GET /tg/app reads it, together with the chat's theme colors, and posts
+// both back. Every POST under /tg/app must carry initData whose hash
+// checks out against the bot token and that is at most tgInitDataMaxAge
+// old, and is then served by the same handlers as the website, with links
+// and forms kept inside /tg/app. When an order is created the token is sent
+// back to the chat with answerWebAppQuery.
+//
+// No script is loaded from telegram.org: the fragment is all the app needs.
+
+// tgInitDataMaxAge is how long launch data is accepted after Telegram
+// signed it. Forms in the app are good for as long.
+const tgInitDataMaxAge = time.Hour
+
+var (
+	errTGInitUnsigned = errors.New("initData is not signed")
+	errTGInitBadHash  = errors.New("initData signature does not match")
+	errTGInitStale    = errors.New("initData is too old")
+)
+
+// tgAppLaunch is verified launch data.
+type tgAppLaunch struct {
+	InitData string // as signed, to pass along with each form
+	User     TGUser
+	QueryID  string // for answerWebAppQuery; empty when not launched from a button
+	AuthDate time.Time
+}
+
+// TGAppPage is what templates need inside the Mini App.
+type TGAppPage struct {
+	InitData  string
+	ThemeJSON string       // Telegram's theme params, passed along unchanged
+	Theme     template.CSS // the theme as CSS variables
+}
+
+type tgAppContextKey struct{}
+
+// verifyTGInitData checks raw initData as Telegram documents it: the hash is
+// HMAC-SHA256 of the other fields, sorted and joined with newlines, keyed
+// with HMAC-SHA256("WebAppData", bot token).
+func verifyTGInitData(raw string, now time.Time) (*tgAppLaunch, error) {
+	vals, err := url.ParseQuery(raw)
+	if err != nil || vals.Get("hash") == "" {
+		return nil, errTGInitUnsigned
+	}
+	keys := make([]string, 0, len(vals))
+	for k := range vals {
+		if k != "hash" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	lines := make([]string, len(keys))
+	for i, k := range keys {
+		lines[i] = k + "=" + vals.Get(k)
+	}
+
+	secret := hmac.New(sha256.New, []byte("WebAppData"))
+	secret.Write([]byte(tgBotToken))
+	mac := hmac.New(sha256.New, secret.Sum(nil))
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	got, err := hex.DecodeString(vals.Get("hash"))
+	if err != nil || !hmac.Equal(got, mac.Sum(nil)) {
+		return nil, errTGInitBadHash
+	}
+
+	authUnix, _ := strconv.ParseInt(vals.Get("auth_date"), 10, 64)
+	authDate := time.Unix(authUnix, 0)
+	if authUnix == 0 || now.Sub(authDate) > tgInitDataMaxAge || authDate.Sub(now) > time.Minute {
+		return nil, errTGInitStale
+	}
+
+	launch := &tgAppLaunch{InitData: raw, QueryID: vals.Get("query_id"), AuthDate: authDate}
+	if err := json.Unmarshal([]byte(vals.Get("user")), &launch.User); err != nil || launch.User.ID == 0 {
+		return nil, errTGInitUnsigned
+	}
+	return launch, nil
+}
+
+// tgThemeColor matches the #rrggbb colors Telegram sends.
+var tgThemeColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// tgThemeCSS maps Telegram's theme params onto the stylesheet's variables.
+// Anything that isn't a plain color is dropped.
+func tgThemeCSS(themeJSON string) template.CSS {
+	var params map[string]string
+	if json.Unmarshal([]byte(themeJSON), &params) != nil {
+		return ""
+	}
+	var css strings.Builder
+	if c := params["bg_color"]; tgThemeColor.MatchString(c) {
+		css.WriteString("--bg:" + c + ";")
+	}
+	if c := params["text_color"]; tgThemeColor.MatchString(c) {
+		css.WriteString("--fg:" + c + ";--fg-a:" + hexToRGB(c) + ";")
+	}
+	return template.CSS(css.String())
+}
+
+// tgAppFromContext returns the verified launch data of a Mini App request,
+// or nil for the website.
+func tgAppFromContext(ctx context.Context) *tgAppLaunch {
+	launch, _ := ctx.Value(tgAppContextKey{}).(*tgAppLaunch)
+	return launch
+}
+
+// inTGApp points a page's links and forms at the Mini App when r came
+// through it.
+func (p *PageData) inTGApp(r *http.Request) {
+	launch := tgAppFromContext(r.Context())
+	if launch == nil {
+		return
+	}
+	theme := r.FormValue("tg_theme")
+	p.App = "/tg/app"
+	p.TGApp = &TGAppPage{InitData: launch.InitData, ThemeJSON: theme, Theme: tgThemeCSS(theme)}
+}
+
+// handleTGApp serves /tg/app. GET loads the launch page; POSTs are checked
+// and passed to the website's handlers.
+func handleTGApp(w http.ResponseWriter, r *http.Request) {
+	route := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/tg/app"), "/")
+	if r.Method != http.MethodPost {
+		if route != "" {
+			http.Redirect(w, r, "/tg/app", http.StatusSeeOther)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		templates.ExecuteTemplate(w, "tg_app.html", newPageData(r, "uSwap Zero"))
+		return
+	}
+
+	launch, err := verifyTGInitData(r.FormValue("tg_init_data"), time.Now())
+	if err != nil {
+		msg := "Open the swap from the uSwap Zero bot in Telegram."
+		if errors.Is(err, errTGInitStale) {
+			msg = "This session has expired. Close the app and open it again from the bot."
+		}
+		renderError(w, r, http.StatusForbidden, "Not Opened From Telegram", msg, "Reopen", "/tg/app")
+		return
+	}
+	r = r.WithContext(context.WithValue(r.Context(), tgAppContextKey{}, launch))
+
+	switch route {
+	case "":
+		data := swapPageFromQuery(r)
+		data.inTGApp(r)
+		renderSwapPage(w, http.StatusOK, data)
+	case "/quote":
+		handleQuote(w, r)
+	case "/swap":
+		handleSwapConfirm(w, r)
+	default:
+		renderError(w, r, 404, "Not Found", "Page not found.", "Back to Swap", "/tg/app")
+	}
+}
+
+// tgAppOrderPlaced hands a new order back to the chat the Mini App was
+// opened from, and adds it to the user's /history if they keep one. It
+// does nothing for website orders.
+func tgAppOrderPlaced(r *http.Request, token string, order *OrderData) {
+	launch := tgAppFromContext(r.Context())
+	if launch == nil {
+		return
+	}
+	tgHistory.record(launch.User.ID, token)
+	l, _ := requestLocale(r)
+
+	pair := order.FromTicker + " → " + order.ToTicker
+	if order.SwapType != "ANY_INPUT" {
+		pair = order.AmountIn + " " + order.FromTicker + " → " + order.AmountOut + " " + order.ToTicker
+	}
+	text := "🧾 <b>" + l.T("Swap created in the app") + "</b>\n" + html.EscapeString(pair) +
+		"\n\n" + l.T("Follow it here with") + "\n<code>/status " + token + "</code>"
+	markup := &TGInlineKeyboardMarkup{InlineKeyboard: [][]TGInlineKeyboardButton{
+		{{Text: l.T("📱 Open Order"), URL: tgAppURL + "/order/" + token}},
+	}}
+
+	if launch.QueryID == "" {
+		// Opened without a query to answer (e.g. from a keyboard button):
+		// the user's private chat with the bot has their user ID.
+		if _, err := tgSendMessage(launch.User.ID, text, markup); err != nil {
+			log.Printf("tg app hand-off: %v", err)
+		}
+		return
+	}
+	_, err := tgRequest("answerWebAppQuery", map[string]interface{}{
+		"web_app_query_id": launch.QueryID,
+		"result": map[string]interface{}{
+			"type":  "article",
+			"id":    strconv.FormatInt(time.Now().UnixNano(), 36),
+			"title": l.T("Swap created"),
+			"input_message_content": map[string]interface{}{
+				"message_text": text,
+				"parse_mode":   "HTML",
+			},
+			"reply_markup": markup,
+		},
+	})
+	if err != nil {
+		log.Printf("tg app answerWebAppQuery: %v", err)
+	}
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -12,19 +15,22 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Telegram bot configuration
 var (
 	tgBotToken      string
 	tgWebhookSecret string
+	tgSecretToken   string // expected X-Telegram-Bot-Api-Secret-Token, derived from tgWebhookSecret
 	tgAppURL        string
 	tgAPIBase       string
 	tgBotUsername   string
 	tgHTTPClient    = &http.Client{}
 )
 
-// initTelegramBot reads env vars and registers the webhook.
+// initTelegramBot reads env vars and registers the webhook, or removes it
+// when TG_MODE=polling.
 // Returns true if the bot is enabled (TG_BOT_TOKEN is set).
 func initTelegramBot() bool {
 	tgBotToken = os.Getenv("TG_BOT_TOKEN")
@@ -33,24 +39,40 @@ func initTelegramBot() bool {
 	}
 
 	tgAPIBase = "https://api.telegram.org/bot" + tgBotToken
-
-	tgWebhookSecret = os.Getenv("TG_WEBHOOK_SECRET")
-	if tgWebhookSecret == "" {
-		b := make([]byte, 16)
-		rand.Read(b)
-		tgWebhookSecret = hex.EncodeToString(b)
-		log.Printf("TG_WEBHOOK_SECRET auto-generated: %s", tgWebhookSecret)
-	}
+	tgOutbox.start()
 
 	tgAppURL = os.Getenv("TG_APP_URL")
 	if tgAppURL == "" {
 		tgAppURL = "https://zero.uswap.net"
 	}
 
-	// Register webhook
-	appURL := tgAppURL + "/tg/webhook/" + tgWebhookSecret
-	if err := tgSetWebhook(appURL); err != nil {
-		log.Printf("WARNING: Failed to set Telegram webhook: %v", err)
+	switch tgMode = strings.ToLower(os.Getenv("TG_MODE")); tgMode {
+	case tgModePolling:
+		// Updates are pulled by startTGPolling; a leftover webhook would
+		// make getUpdates fail.
+		if err := tgDeleteWebhook(); err != nil {
+			log.Printf("WARNING: Failed to delete Telegram webhook: %v", err)
+		}
+	default:
+		if tgMode != "" && tgMode != tgModeWebhook {
+			log.Printf("WARNING: unknown TG_MODE %q, using webhook", tgMode)
+		}
+		tgMode = tgModeWebhook
+
+		tgWebhookSecret = os.Getenv("TG_WEBHOOK_SECRET")
+		if tgWebhookSecret == "" {
+			b := make([]byte, 16)
+			rand.Read(b)
+			tgWebhookSecret = hex.EncodeToString(b)
+			log.Printf("TG_WEBHOOK_SECRET not set — generated a random one for this run")
+		}
+		tgSecretToken = deriveTGSecretToken(tgWebhookSecret)
+
+		// Register webhook
+		appURL := tgAppURL + "/tg/webhook/" + tgWebhookSecret
+		if err := tgSetWebhook(appURL, tgSecretToken); err != nil {
+			log.Printf("WARNING: Failed to set Telegram webhook: %v", err)
+		}
 	}
 
 	// Fetch bot info (needed for deep links)
@@ -124,9 +146,10 @@ type TGChat struct {
 
 // TGUser represents a Telegram user.
 type TGUser struct {
-	ID        int64  `json:"id"`
-	FirstName string `json:"first_name"`
-	Username  string `json:"username,omitempty"`
+	ID           int64  `json:"id"`
+	FirstName    string `json:"first_name"`
+	Username     string `json:"username,omitempty"`
+	LanguageCode string `json:"language_code,omitempty"` // IETF tag of the user's app language
 }
 
 // TGCallbackQuery represents a callback from an inline button press.
@@ -166,9 +189,28 @@ type TGForceReply struct {
 
 // TGAPIResponse is the generic Telegram API response wrapper.
 type TGAPIResponse struct {
-	OK          bool            `json:"ok"`
-	Description string          `json:"description,omitempty"`
-	Result      json.RawMessage `json:"result,omitempty"`
+	OK          bool                  `json:"ok"`
+	ErrorCode   int                   `json:"error_code,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Result      json.RawMessage       `json:"result,omitempty"`
+	Parameters  *TGResponseParameters `json:"parameters,omitempty"`
+}
+
+// TGResponseParameters explains why a request failed. RetryAfter is set on
+// 429 Too Many Requests: the seconds to wait before trying again.
+type TGResponseParameters struct {
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+// tgAPIError is a request Telegram answered with ok=false.
+type tgAPIError struct {
+	Code        int
+	Description string
+	RetryAfter  int // seconds; set on 429
+}
+
+func (e *tgAPIError) Error() string {
+	return "tg API error: " + e.Description
 }
 
 // TGSentMessage is the result of sendMessage/editMessage.
@@ -179,14 +221,53 @@ type TGSentMessage struct {
 
 // --- Telegram API Methods ---
 
-// tgRequest makes a JSON POST to the Telegram Bot API.
+// tgRequest makes a JSON POST to the Telegram Bot API through the outbound
+// queue, as user-facing traffic.
 func tgRequest(method string, payload interface{}) (json.RawMessage, error) {
+	return tgQueuedRequest(tgPriorityUser, method, payload)
+}
+
+// tgMonitorRequest is tgRequest for reseller monitor posts, which yield to
+// user-facing traffic.
+func tgMonitorRequest(method string, payload interface{}) (json.RawMessage, error) {
+	return tgQueuedRequest(tgPriorityMonitor, method, payload)
+}
+
+func tgQueuedRequest(priority int, method string, payload interface{}) (json.RawMessage, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("tg marshal: %w", err)
+	}
+	chatID, mergeKey := tgCallTarget(method, body)
+	return tgOutbox.do(&tgCall{
+		priority:    priority,
+		method:      method,
+		chatID:      chatID,
+		mergeKey:    mergeKey,
+		contentType: "application/json",
+		body:        body,
+	})
+}
+
+// tgRequestContext makes a JSON POST straight to the Bot API, bypassing
+// the queue. Only getUpdates uses it: a long poll must not hold a slot.
+func tgRequestContext(ctx context.Context, method string, payload interface{}) (json.RawMessage, error) {
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("tg marshal: %w", err)
 	}
+	return tgPost(ctx, method, "application/json", body)
+}
 
-	resp, err := tgHTTPClient.Post(tgAPIBase+"/"+method, "application/json", bytes.NewReader(body))
+// tgPost sends one Bot API request. A request Telegram refuses comes back
+// as *tgAPIError.
+func tgPost(ctx context.Context, method, contentType string, body []byte) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", tgAPIBase+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("tg request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := tgHTTPClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("tg request: %w", err)
 	}
@@ -199,14 +280,38 @@ func tgRequest(method string, payload interface{}) (json.RawMessage, error) {
 
 	var apiResp TGAPIResponse
 	if err := json.Unmarshal(data, &apiResp); err != nil {
-		return nil, fmt.Errorf("tg parse: %w", err)
+		return nil, fmt.Errorf("tg parse: %w (HTTP %d)", err, resp.StatusCode)
 	}
 	if !apiResp.OK {
-		return nil, fmt.Errorf("tg API error: %s", apiResp.Description)
+		apiErr := &tgAPIError{Code: apiResp.ErrorCode, Description: apiResp.Description}
+		if apiErr.Code == 0 {
+			apiErr.Code = resp.StatusCode
+		}
+		if apiResp.Parameters != nil {
+			apiErr.RetryAfter = apiResp.Parameters.RetryAfter
+		}
+		return nil, apiErr
 	}
 	return apiResp.Result, nil
 }
 
+// tgUpload sends a multipart request through the outbound queue.
+func tgUpload(method string, chatID int64, contentType string, body []byte) (*TGSentMessage, error) {
+	result, err := tgOutbox.do(&tgCall{
+		priority:    tgPriorityUser,
+		method:      method,
+		chatID:      chatID,
+		contentType: contentType,
+		body:        body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var msg TGSentMessage
+	json.Unmarshal(result, &msg)
+	return &msg, nil
+}
+
 // tgSendMessage sends a text message with optional reply markup.
 // Link previews are always disabled — the bot sends informational cards,
 // not content where previews add value.
@@ -291,28 +396,34 @@ func tgSendPhoto(chatID int64, pngData []byte, caption string, markup *TGInlineK
 	part.Write(pngData)
 	w.Close()
 
-	resp, err := tgHTTPClient.Post(tgAPIBase+"/sendPhoto", w.FormDataContentType(), &buf)
+	msg, err := tgUpload("sendPhoto", chatID, w.FormDataContentType(), buf.Bytes())
 	if err != nil {
 		return nil, fmt.Errorf("tg send photo: %w", err)
 	}
-	defer resp.Body.Close()
+	return msg, nil
+}
 
-	data, err := io.ReadAll(resp.Body)
+// tgSendDocument sends a file with an HTML caption.
+func tgSendDocument(chatID int64, filename string, data []byte, caption string) (*TGSentMessage, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	w.WriteField("chat_id", strconv.FormatInt(chatID, 10))
+	w.WriteField("caption", caption)
+	w.WriteField("parse_mode", "HTML")
+
+	part, err := w.CreateFormFile("document", filename)
 	if err != nil {
-		return nil, fmt.Errorf("tg read photo resp: %w", err)
+		return nil, fmt.Errorf("tg create form file: %w", err)
 	}
+	part.Write(data)
+	w.Close()
 
-	var apiResp TGAPIResponse
-	if err := json.Unmarshal(data, &apiResp); err != nil {
-		return nil, fmt.Errorf("tg parse photo resp: %w", err)
-	}
-	if !apiResp.OK {
-		return nil, fmt.Errorf("tg sendPhoto error: %s", apiResp.Description)
+	msg, err := tgUpload("sendDocument", chatID, w.FormDataContentType(), buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("tg send document: %w", err)
 	}
-
-	var msg TGSentMessage
-	json.Unmarshal(apiResp.Result, &msg)
-	return &msg, nil
+	return msg, nil
 }
 
 // tgAnswerInlineQuery responds to an inline query with a list of results.
@@ -338,29 +449,73 @@ func tgGetMe() {
 	}
 }
 
-// tgSetWebhook registers the webhook URL with Telegram.
-func tgSetWebhook(url string) error {
+// tgSetWebhook registers the webhook URL with Telegram. Telegram sends
+// secretToken back in the X-Telegram-Bot-Api-Secret-Token header of every
+// update.
+func tgSetWebhook(url, secretToken string) error {
 	payload := map[string]interface{}{
 		"url":             url,
-		"allowed_updates": []string{"message", "callback_query", "inline_query"},
+		"secret_token":    secretToken,
+		"allowed_updates": tgAllowedUpdates,
 	}
 	_, err := tgRequest("setWebhook", payload)
 	if err != nil {
 		return err
 	}
-	log.Printf("Telegram webhook set to: %s", url)
+	log.Printf("Telegram webhook set to: %s/tg/webhook/…", tgAppURL)
 	return nil
 }
 
-// tgSetCommands registers the bot's command list.
+// deriveTGSecretToken derives the webhook's secret_token from its path
+// secret. Telegram only allows [A-Za-z0-9_-] there, and a path that leaks
+// into a proxy log doesn't give the header away.
+func deriveTGSecretToken(webhookSecret string) string {
+	mac := hmac.New(sha256.New, []byte(webhookSecret))
+	mac.Write([]byte("telegram secret_token"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// tgDeleteWebhook removes any registered webhook so getUpdates works.
+// Pending updates are kept and picked up by the first poll.
+func tgDeleteWebhook() error {
+	_, err := tgRequest("deleteWebhook", map[string]interface{}{"drop_pending_updates": false})
+	return err
+}
+
+// tgSetCommands registers the bot's command list, once per language.
+// Telegram shows the list matching the user's app language and falls back
+// to the one registered without a language_code.
 func tgSetCommands() {
-	commands := []map[string]string{
-		{"command": "start", "description": "Start a new swap"},
-		{"command": "verify", "description": "Verify deployment integrity"},
-		{"command": "status", "description": "Check order status"},
+	for _, l := range localeList {
+		commands := []map[string]string{
+			{"command": "start", "description": l.T("Start a new swap")},
+			{"command": "verify", "description": l.T("Verify deployment integrity")},
+			{"command": "status", "description": l.T("Check order status")},
+			{"command": "price", "description": l.T("Token price in USD")},
+			{"command": "quote", "description": l.T("Quote a swap, e.g. /quote 1 ETH USDT")},
+			{"command": "alert", "description": l.T("Price alert, e.g. /alert BTC below 60000")},
+			{"command": "alerts", "description": l.T("List and delete price alerts")},
+			{"command": "addresses", "description": l.T("Saved refund and receive addresses")},
+			{"command": "history", "description": l.T("Your recent orders")},
+			{"command": "forget", "description": l.T("Delete your saved addresses and order history")},
+		}
+		payload := map[string]interface{}{
+			"commands": commands,
+		}
+		if l != defaultLocale {
+			payload["language_code"] = l.Tag
+		}
+		tgRequest("setMyCommands", payload)
 	}
-	payload := map[string]interface{}{
-		"commands": commands,
-	}
-	tgRequest("setMyCommands", payload)
+
+	// Groups only get the commands that never involve an address. Their
+	// replies are in English, so the menu is too.
+	tgRequest("setMyCommands", map[string]interface{}{
+		"commands": []map[string]string{
+			{"command": "price", "description": "Token price in USD"},
+			{"command": "quote", "description": "Quote a swap, e.g. /quote 1 ETH USDT"},
+			{"command": "help", "description": "How to swap privately"},
+		},
+		"scope": map[string]string{"type": "all_group_chats"},
+	})
 }
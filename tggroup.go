@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Group chat commands.
+//
+// In groups the bot only answers /price and /quote, plus a short /help.
+// Everything it posts there is public, so the group commands never ask for
+// or show an address: prices come from the token list and quotes are dry
+// quotes against a placeholder account. Each card links to the bot's
+// private chat, where the swap itself happens. Other commands, callbacks
+// and plain messages in groups are ignored.
+//
+// /price and /quote also work in a private chat. Replies use
+// tgSessions.locFor: a private chat gets its user's language, while a group
+// has no session and so gets English, since the whole chat reads it.
+
+// tgGroupQuoteAccount stands in for the refund and receive addresses of dry
+// quotes made without a user's addresses (group quotes, price alerts). Dry
+// quotes never produce a deposit address, so nothing can be sent to it; it
+// only lets 1Click price the route.
+const tgGroupQuoteAccount = "intents.near"
+
+// Per-chat limits for the group commands. Quotes call 1Click; prices are
+// served from the token cache.
+const (
+	tgGroupQuoteLimit = 5
+	tgGroupPriceLimit = 10
+	tgGroupWindow     = time.Minute
+)
+
+// isTGGroupChat reports whether a chat type is a group or supergroup.
+func isTGGroupChat(chatType string) bool {
+	return chatType == "group" || chatType == "supergroup"
+}
+
+// handleTGGroupMessage answers the group-safe commands. Commands addressed
+// to another bot (/price@OtherBot) are left alone.
+func handleTGGroupMessage(msg *TGMessage) {
+	text := strings.TrimSpace(msg.Text)
+	if !strings.HasPrefix(text, "/") {
+		return
+	}
+	cmd := strings.SplitN(text, " ", 2)
+	name := strings.ToLower(cmd[0])
+	if at := strings.Index(name, "@"); at >= 0 {
+		if !strings.EqualFold(name[at+1:], botUsername()) {
+			return
+		}
+		name = name[:at]
+	}
+	args := ""
+	if len(cmd) > 1 {
+		args = strings.TrimSpace(cmd[1])
+	}
+
+	switch name {
+	case "/price":
+		handleTGPrice(msg.Chat.ID, args)
+	case "/quote":
+		handleTGGroupQuote(msg.Chat.ID, args)
+	case "/help", "/start":
+		tgSendMessage(msg.Chat.ID, tgGroupHelp, tgPrivateSwapMarkup(defaultLocale, ""))
+	}
+}
+
+const tgGroupHelp = "<b>Ø uSwap Zero</b> — zero-fee swaps\n\n" +
+	"/price ETH — USD price on each network\n" +
+	"/quote 1 ETH USDT — live quote, no fees\n\n" +
+	"Swaps happen in a private chat, so no address is ever posted here."
+
+// tgGroupAllow applies a per-chat limit. The first refusal in a window gets
+// a short notice; later ones are dropped so the bot can't be used to flood
+// the group.
+func tgGroupAllow(chatID int64, scope string, limit int) bool {
+	id := strconv.FormatInt(chatID, 10)
+	if limiter.allowScoped(scope, id, limit, tgGroupWindow) {
+		return true
+	}
+	if limiter.allowScoped(scope+"-notice", id, 1, tgGroupWindow) {
+		tgSendMessage(chatID, tgSessions.locFor(chatID).T("⏳ Too many requests in this chat. Please wait a minute."), nil)
+	}
+	return false
+}
+
+// resolveTGToken finds a token by ticker, or ticker-network such as
+// USDT-tron. A bare ticker picks its most popular network.
+func resolveTGToken(arg string) *TokenInfo {
+	if ticker, network, ok := strings.Cut(arg, "-"); ok {
+		return findToken(ticker, network)
+	}
+	if variants := findAllTokenNetworks(arg); len(variants) > 0 {
+		return &variants[0]
+	}
+	return nil
+}
+
+// handleTGPrice handles /price <ticker>: the token's USD price on each
+// network it is listed on.
+func handleTGPrice(chatID int64, args string) {
+	l := tgSessions.locFor(chatID)
+	fields := strings.Fields(args)
+	if len(fields) != 1 {
+		tgSendMessage(chatID, l.T("Usage: /price ETH"), nil)
+		return
+	}
+	if !tgGroupAllow(chatID, "tgprice", tgGroupPriceLimit) {
+		return
+	}
+	variants := findAllTokenNetworks(strings.ToUpper(fields[0]))
+	if len(variants) == 0 {
+		tgSendMessage(chatID, l.T("Unknown token %s.", html.EscapeString(fields[0])), nil)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(cardTop() + "\n")
+	sb.WriteString(cardRow(" Ø USWAP ZERO — PRICE") + "\n")
+	sb.WriteString(cardMid() + "\n")
+	shown := 0
+	for _, t := range variants {
+		if t.Price == 0 || shown == 6 {
+			continue
+		}
+		shown++
+		label := safeRunes(t.Ticker+" "+networkDisplayName(t.ChainName), 16)
+		sb.WriteString(cardRowKV(label, formatUSD(t.Price)) + "\n")
+	}
+	if shown == 0 {
+		tgSendMessage(chatID, l.T("No price available for %s right now.", html.EscapeString(variants[0].Ticker)), nil)
+		return
+	}
+	sb.WriteString(cardBot())
+
+	tgSendMessage(chatID, "<pre>"+sb.String()+"</pre>", tgPrivateSwapMarkup(l, ""))
+}
+
+// handleTGGroupQuote handles /quote <amount> <from> <to> with a dry quote.
+func handleTGGroupQuote(chatID int64, args string) {
+	l := tgSessions.locFor(chatID)
+	parsed := parseInlineQuery(args)
+	if parsed.kind != inlineKindPairAmt {
+		tgSendMessage(chatID, l.T("Usage: /quote 1 ETH USDT"), nil)
+		return
+	}
+	if !tgGroupAllow(chatID, "tgquote", tgGroupQuoteLimit) {
+		return
+	}
+	from := resolveTGToken(parsed.from)
+	to := resolveTGToken(parsed.to)
+	if from == nil || to == nil {
+		tgSendMessage(chatID, l.T("Unknown token. Try /quote 1 ETH USDT or a network suffix like USDT-tron."), nil)
+		return
+	}
+	atomic, err := humanToAtomic(parsed.amount, from.Decimals)
+	if err != nil {
+		tgSendMessage(chatID, l.T("Invalid amount: %s", html.EscapeString(err.Error())), nil)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	dryResp, err := requestTGDryQuote(ctx, from, to, atomic)
+	if err != nil {
+		_, msg := describeAPIError(err, from)
+		tgSendMessage(chatID, l.T("Quote failed: %s", html.EscapeString(l.T(msg))), nil)
+		return
+	}
+	if dryResp.Quote.AmountOut == "" || dryResp.Quote.AmountOut == "0" {
+		tgSendMessage(chatID, l.T("No market makers are offering a rate for this pair and amount right now."), nil)
+		return
+	}
+
+	card := "<pre>" + renderQuoteCardMono(l, quoteCardData(dryResp, from.Ticker, to.Ticker, "FLEX_INPUT")) + "</pre>"
+	link := buildDeepLink(from.Ticker, from.ChainName, to.Ticker, to.ChainName, parsed.amount)
+	tgSendMessage(chatID, card, tgPrivateSwapMarkup(l, link))
+}
+
+// requestTGDryQuote prices amount (atomic units of from) without any user
+// address, for cards that may be seen by others.
+func requestTGDryQuote(ctx context.Context, from, to *TokenInfo, atomic string) (*DryQuoteResponse, error) {
+	return requestDryQuote(ctx, &QuoteRequest{
+		SwapType:           "FLEX_INPUT",
+		SlippageTolerance:  100,
+		OriginAsset:        from.DefuseAssetID,
+		DepositType:        "ORIGIN_CHAIN",
+		DestinationAsset:   to.DefuseAssetID,
+		Amount:             atomic,
+		RefundTo:           tgGroupQuoteAccount,
+		RefundType:         "INTENTS",
+		Recipient:          tgGroupQuoteAccount,
+		RecipientType:      "INTENTS",
+		Deadline:           buildDeadline(time.Hour),
+		QuoteWaitingTimeMs: 8000,
+		AppFees:            []struct{}{},
+	})
+}
+
+// tgPrivateSwapMarkup is the "Swap privately" button under group cards. An
+// empty link opens the bot's private chat without a pre-filled swap.
+func tgPrivateSwapMarkup(l *locale, link string) *TGInlineKeyboardMarkup {
+	if link == "" {
+		link = tgAppURL
+		if tgBotUsername != "" {
+			link = "https://t.me/" + tgBotUsername + "?start"
+		}
+	}
+	return &TGInlineKeyboardMarkup{
+		InlineKeyboard: [][]TGInlineKeyboardButton{
+			{{Text: l.T("🔒 Swap privately →"), URL: link}},
+		},
+	}
+}
@@ -1,23 +1,41 @@
 package main
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// tgMaxUpdateBody caps a webhook request body. Updates are a few KB.
+const tgMaxUpdateBody = 1 << 20
+
+// tgUnlockLimit is the number of passphrase tries a chat gets per minute,
+// the same as an IP gets on the web order page.
+const tgUnlockLimit = 10
+
 // handleTelegramWebhook processes incoming updates from Telegram.
+// Besides the secret path, every request must carry the secret_token the
+// webhook was registered with.
 func handleTelegramWebhook(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	got := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+	if tgSecretToken == "" || subtle.ConstantTimeCompare([]byte(got), []byte(tgSecretToken)) != 1 {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
 
-	body, err := io.ReadAll(r.Body)
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, tgMaxUpdateBody))
 	if err != nil {
-		http.Error(w, "read error", http.StatusBadRequest)
+		http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
 		return
 	}
 
@@ -30,30 +48,24 @@ func handleTelegramWebhook(w http.ResponseWriter, r *http.Request) {
 	// Always respond 200 to acknowledge the update
 	w.WriteHeader(http.StatusOK)
 
-	// Route to handler
-	if update.InlineQuery != nil {
-		go handleTGInlineQuery(update.InlineQuery)
-		return
-	}
-	if update.CallbackQuery != nil {
-		go handleTGCallback(update.CallbackQuery)
-		return
-	}
-	if update.Message != nil {
-		go handleTGMessage(update.Message)
-		return
-	}
+	dispatchTGUpdate(&update)
 }
 
 // handleTGMessage routes text messages and commands.
 func handleTGMessage(msg *TGMessage) {
-	// Only handle private chats
+	// Groups get the group-safe commands only
 	if msg.Chat.Type != "private" {
+		if isTGGroupChat(msg.Chat.Type) {
+			handleTGGroupMessage(msg)
+		}
 		return
 	}
 
 	chatID := msg.Chat.ID
 	text := strings.TrimSpace(msg.Text)
+	if msg.From != nil && msg.From.LanguageCode != "" {
+		tgSessions.setLang(chatID, msg.From.LanguageCode)
+	}
 
 	// Handle commands
 	if strings.HasPrefix(text, "/") {
@@ -64,17 +76,35 @@ func handleTGMessage(msg *TGMessage) {
 			if len(cmd) > 1 {
 				startParam = strings.TrimSpace(cmd[1])
 			}
+			if strings.HasPrefix(startParam, "watch_") {
+				handleTGWatchStart(chatID, startParam[6:])
+				return
+			}
 			handleTGStart(chatID, startParam)
 		case "/verify":
 			handleTGVerify(chatID)
+		case "/price":
+			handleTGPrice(chatID, strings.TrimSpace(strings.TrimPrefix(text, cmd[0])))
+		case "/quote":
+			handleTGGroupQuote(chatID, strings.TrimSpace(strings.TrimPrefix(text, cmd[0])))
+		case "/alert":
+			handleTGAlert(chatID, strings.TrimSpace(strings.TrimPrefix(text, cmd[0])))
+		case "/alerts":
+			handleTGAlerts(chatID)
+		case "/addresses":
+			handleTGAddresses(chatID, strings.TrimSpace(strings.TrimPrefix(text, cmd[0])))
+		case "/history":
+			handleTGHistory(chatID)
+		case "/forget":
+			handleTGForget(chatID)
 		case "/status":
 			if len(cmd) > 1 {
 				handleTGStatus(chatID, strings.TrimSpace(cmd[1]))
 			} else {
-				tgSendMessage(chatID, "Usage: /status <order_token>", nil)
+				tgSendMessage(chatID, tgSessions.locFor(chatID).T("Usage: /status <order_token>"), nil)
 			}
 		default:
-			tgSendMessage(chatID, "Unknown command. Use /start to begin a swap.", nil)
+			tgSendMessage(chatID, tgSessions.locFor(chatID).T("Unknown command. Use /start to begin a swap."), nil)
 		}
 		return
 	}
@@ -96,6 +126,10 @@ func handleTGMessage(msg *TGMessage) {
 	case statePickToken:
 		// Token search by typing
 		handleTGTokenSearch(chatID, sess, msg)
+	case stateEnterLockPass:
+		handleTGLockInput(chatID, sess, msg)
+	case stateEnterUnlock:
+		handleTGUnlockInput(chatID, sess, msg)
 	default:
 		// Ignore unexpected text
 	}
@@ -120,6 +154,10 @@ func handleTGCallback(cb *TGCallbackQuery) {
 	sess := tgSessions.get(chatID)
 	sess.mu.Lock()
 	defer sess.mu.Unlock()
+	if cb.From.LanguageCode != "" {
+		sess.Lang = cb.From.LanguageCode
+	}
+	l := sess.loc()
 
 	// Acknowledge callback
 	switch {
@@ -130,7 +168,7 @@ func handleTGCallback(cb *TGCallbackQuery) {
 		tgAnswerCallback(cb.ID, "")
 		handleTGPickToken(chatID, sess, "to")
 	case data == "sw":
-		tgAnswerCallback(cb.ID, "Swapped!")
+		tgAnswerCallback(cb.ID, l.T("Swapped!"))
 		handleTGSwapDirection(chatID, sess)
 	case data == "sa":
 		tgAnswerCallback(cb.ID, "")
@@ -145,7 +183,7 @@ func handleTGCallback(cb *TGCallbackQuery) {
 		tgAnswerCallback(cb.ID, "")
 		handleTGPromptRecv(chatID, sess)
 	case strings.HasPrefix(data, "sl:"):
-		tgAnswerCallback(cb.ID, "Slippage: "+data[3:]+"%")
+		tgAnswerCallback(cb.ID, l.T("Slippage: %s%%", data[3:]))
 		handleTGSetSlippage(chatID, sess, data[3:])
 	case strings.HasPrefix(data, "ts:"):
 		tgAnswerCallback(cb.ID, "")
@@ -157,26 +195,50 @@ func handleTGCallback(cb *TGCallbackQuery) {
 		tgAnswerCallback(cb.ID, "")
 		handleTGTokenPage(chatID, sess, data[3:])
 	case data == "gq":
-		tgAnswerCallback(cb.ID, "Loading quote...")
+		tgAnswerCallback(cb.ID, l.T("Loading quote..."))
 		handleTGGetQuote(chatID, sess)
 	case data == "cs":
-		tgAnswerCallback(cb.ID, "Confirming swap...")
+		tgAnswerCallback(cb.ID, l.T("Confirming swap..."))
 		handleTGConfirmSwap(chatID, sess)
 	case data == "cq":
-		tgAnswerCallback(cb.ID, "Cancelled")
+		tgAnswerCallback(cb.ID, l.T("Cancelled"))
 		handleTGCancelQuote(chatID, sess)
+	case data == "lp":
+		tgAnswerCallback(cb.ID, "")
+		handleTGToggleLock(chatID, sess)
+	case data == "ad":
+		tgAnswerCallback(cb.ID, "")
+		handleTGSendAttestation(chatID, sess)
 	case data == "bk":
 		tgAnswerCallback(cb.ID, "")
 		handleTGBackToCard(chatID, sess)
 	case data == "rs":
-		tgAnswerCallback(cb.ID, "Refreshing...")
+		tgAnswerCallback(cb.ID, l.T("Refreshing..."))
 		handleTGRefreshStatus(chatID, sess)
+	case data == "nw":
+		tgAnswerCallback(cb.ID, "")
+		handleTGToggleWatch(chatID, sess)
 	case data == "dm":
-		tgAnswerCallback(cb.ID, "Messages deleted")
+		tgAnswerCallback(cb.ID, l.T("Messages deleted"))
 		handleTGDeleteMessages(chatID, sess)
 	case data == "ns":
 		tgAnswerCallback(cb.ID, "")
 		handleTGNewSwap(chatID, sess)
+	case strings.HasPrefix(data, "al:"):
+		tgAnswerCallback(cb.ID, l.T("Alert deleted"))
+		handleTGDeleteAlert(l, chatID, cb.Message.MessageID, data[3:])
+	case strings.HasPrefix(data, "ab:p:"):
+		tgAnswerCallback(cb.ID, "")
+		handleTGPickAddress(chatID, sess, data[5:])
+	case strings.HasPrefix(data, "ab:d:"):
+		tgAnswerCallback(cb.ID, l.T("Address deleted"))
+		handleTGDeleteAddress(l, chatID, cb.Message.MessageID, data[5:])
+	case data == "ab:s":
+		tgAnswerCallback(cb.ID, "")
+		handleTGSaveOrderAddresses(chatID, sess)
+	case strings.HasPrefix(data, "oh:"):
+		tgAnswerCallback(cb.ID, "")
+		handleTGHistoryCallback(chatID, sess, cb.Message.MessageID, data[3:])
 	default:
 		tgAnswerCallback(cb.ID, "")
 	}
@@ -212,10 +274,11 @@ func handleTGStart(chatID int64, startParam string) {
 
 // handleTGVerify shows commit hash and link to verify page.
 func handleTGVerify(chatID int64) {
-	text := "<b>Ø uSwap Zero — 🔍 Verify</b>\n\n" +
-		"Commit: <code>" + commitHash + "</code>\n" +
-		"Build: " + buildTime + "\n\n" +
-		"<a href=\"" + tgAppURL + "/verify\">Verify source →</a>"
+	l := tgSessions.locFor(chatID)
+	text := "<b>Ø uSwap Zero — 🔍 " + l.T("Verify") + "</b>\n\n" +
+		l.T("Commit:") + " <code>" + commitHash + "</code>\n" +
+		l.T("Build:") + " " + buildTime + "\n\n" +
+		"<a href=\"" + tgAppURL + "/verify\">" + l.T("Verify source →") + "</a>"
 	tgSendMessage(chatID, text, nil)
 }
 
@@ -240,24 +303,96 @@ func handleTGNewSwap(chatID int64, sess *tgSession) {
 
 // handleTGStatus looks up an order by token, sends the unified order card,
 // and wires it into the session so Refresh/Clear/New Swap buttons work.
+// Locked tokens first prompt for their passphrase.
 func handleTGStatus(chatID int64, token string) {
 	order, err := decryptOrderData(token)
 	if err != nil {
-		tgSendMessage(chatID, "Invalid order token.", nil)
+		tgSendMessage(chatID, tgSessions.locFor(chatID).T("Invalid order token."), nil)
+		return
+	}
+
+	sess := tgSessions.get(chatID)
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	showTGOrder(chatID, sess, order, token)
+}
+
+// showTGOrder sends the order card for token, or the passphrase prompt if
+// it is locked. Caller must hold sess.mu.
+func showTGOrder(chatID int64, sess *tgSession, order *OrderData, token string) {
+	if order.Locked() {
+		l := sess.loc()
+		sess.State = stateEnterUnlock
+		sess.PendingToken = token
+		msg, err := tgSendMessage(chatID, l.T("🔒 This order is locked. Reply with its passphrase:"), &TGForceReply{
+			ForceReply:            true,
+			Selective:             true,
+			InputFieldPlaceholder: l.T("Passphrase..."),
+		})
+		if err == nil {
+			sess.PromptMsgID = msg.MessageID
+		}
+		return
+	}
+
+	sendTGStatusCard(chatID, sess, order, token, nil)
+}
+
+// handleTGUnlockInput opens a locked /status token with the replied passphrase.
+// The reply is deleted straight away; five wrong answers abandon the unlock.
+// Tries count until one succeeds, and each chat gets tgUnlockLimit a minute,
+// as each one costs a full passphrase KDF run.
+func handleTGUnlockInput(chatID int64, sess *tgSession, msg *TGMessage) {
+	l := sess.loc()
+	tgDeleteMessage(chatID, msg.MessageID)
+	if !limiter.allowScoped("tgunlock", strconv.FormatInt(chatID, 10), tgUnlockLimit, time.Minute) {
+		tgSendMessage(chatID, l.T("⏳ Too many unlock attempts. Please wait a minute."), nil)
 		return
 	}
 
-	status, err := fetchStatus(order.DepositAddr, order.Memo)
+	order, err := decryptOrderData(sess.PendingToken)
+	if err != nil {
+		sess.State = stateIdle
+		return
+	}
+	full, key, err := unlockOrderData(order, strings.TrimSpace(msg.Text))
 	if err != nil {
-		tgSendMessage(chatID, "❌ Status check failed: "+err.Error(), nil)
+		sess.UnlockTries++
+		if sess.UnlockTries >= 5 {
+			cleanupPromptReply(chatID, sess, 0)
+			sess.State = stateIdle
+			sess.PendingToken = ""
+			tgSendMessage(chatID, l.T("❌ Too many wrong passphrases. Send /status again to retry."), nil)
+			return
+		}
+		tgSendMessage(chatID, l.T("❌ Wrong passphrase. Please try again."), nil)
 		return
 	}
 
-	cardText, markup := buildOrderCard(order, status, token)
+	token := sess.PendingToken
+	sess.PendingToken = ""
+	sess.UnlockTries = 0
+	cleanupPromptReply(chatID, sess, 0)
+	sendTGStatusCard(chatID, sess, full, token, key)
+}
 
-	sess := tgSessions.get(chatID)
-	sess.mu.Lock()
-	defer sess.mu.Unlock()
+// sendTGStatusCard fetches status and sends a fresh order card, replacing the
+// session's current card. orderKey is the unlock key for locked tokens.
+// Caller must hold sess.mu.
+func sendTGStatusCard(chatID int64, sess *tgSession, order *OrderData, token string, orderKey []byte) {
+	res, err := orderStatuses.get(context.Background(), order)
+	if err != nil {
+		tgSendMessage(chatID, sess.loc().T("❌ Status check failed: %s", err.Error()), nil)
+		return
+	}
+
+	l := sess.loc()
+	cardText, markup := buildOrderCard(l, order, res.Status, token)
+	sess.OrderToken = token
+	sess.OrderKey = orderKey
+	addAttestationButton(markup, sess)
+	addWatchButton(markup, chatID, sess, res.Status.Status)
+	addSaveAddressesButton(l, markup, chatID, order, res.Status.Status)
 
 	// Replace any existing card
 	if sess.CardMsgID != 0 {
@@ -270,7 +405,6 @@ func handleTGStatus(chatID int64, token string) {
 		return
 	}
 	sess.CardMsgID = msg.MessageID
-	sess.OrderToken = token
 	sess.State = stateOrderActive
 }
 
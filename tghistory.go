@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Order history.
+//
+// /history lists a chat's last orders with their live status and a button
+// that reopens each order card, so an order isn't lost once its card is
+// cleared or the session expires. It is off until the user turns it on;
+// from then on every order placed in the chat is recorded. Only the order
+// tokens are kept, in a list sealed per chat like the address book, and
+// each is dropped after the retention period (TG_HISTORY_DAYS, 30 days by
+// default). /forget or turning history off deletes the list.
+
+const (
+	tgHistoryMax       = 10
+	tgHistoryMaxChats  = 20000
+	tgHistoryPurpose   = "tg-history"
+	tgHistoryDefault   = 30 * 24 * time.Hour
+	tgHistoryPurgeTick = time.Hour
+)
+
+// tgHistoryRetention is how long an order stays in a history. Set from
+// TG_HISTORY_DAYS.
+var tgHistoryRetention = tgHistoryDefault
+
+var errTGHistoryFull = errors.New("order history is full right now; please try again later")
+
+type tgHistoryEntry struct {
+	ID    int       `json:"id"`
+	Token string    `json:"token"`
+	Added time.Time `json:"added"`
+}
+
+// tgHistoryList is one chat's history, newest first: the plaintext of its
+// sealed record.
+type tgHistoryList struct {
+	Entries []tgHistoryEntry `json:"entries"`
+	NextID  int              `json:"nextId"`
+}
+
+// purge drops entries past the retention period and reports whether any
+// were dropped.
+func (l *tgHistoryList) purge(now time.Time) bool {
+	kept := l.Entries[:0]
+	for _, e := range l.Entries {
+		if now.Sub(e.Added) < tgHistoryRetention {
+			kept = append(kept, e)
+		}
+	}
+	dropped := len(kept) != len(l.Entries)
+	l.Entries = kept
+	return dropped
+}
+
+type tgHistoryStore struct {
+	mu    sync.Mutex
+	lists map[int64][]byte // sealed tgHistoryList per chat that turned history on
+	file  *sealedFile      // nil when histories are only kept in memory
+}
+
+var tgHistory = newTGHistoryStore()
+
+func newTGHistoryStore() *tgHistoryStore {
+	return &tgHistoryStore{lists: make(map[int64][]byte)}
+}
+
+// initTGHistory reads TG_HISTORY_DAYS and loads histories from
+// TG_HISTORY_FILE, if set.
+func initTGHistory() {
+	if v := os.Getenv("TG_HISTORY_DAYS"); v != "" {
+		if days, err := strconv.Atoi(v); err == nil && days > 0 {
+			tgHistoryRetention = time.Duration(days) * 24 * time.Hour
+		} else {
+			log.Printf("WARNING: TG_HISTORY_DAYS=%q is not a positive number of days — using %d", v, int(tgHistoryDefault.Hours()/24))
+		}
+	}
+
+	var lists map[int64][]byte
+	f := openSealedFile("TG_HISTORY_FILE", tgHistoryPurpose, &lists, tgHistory.snapshot)
+	if f == nil {
+		return
+	}
+	tgHistory.mu.Lock()
+	defer tgHistory.mu.Unlock()
+	tgHistory.file = f
+	for chatID, sealed := range lists {
+		tgHistory.lists[chatID] = sealed
+	}
+}
+
+func (s *tgHistoryStore) snapshot() interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lists := make(map[int64][]byte, len(s.lists))
+	for chatID, sealed := range s.lists {
+		lists[chatID] = sealed
+	}
+	return lists
+}
+
+// open decrypts chatID's list. Caller must hold s.mu.
+func (s *tgHistoryStore) open(chatID int64) *tgHistoryList {
+	list := &tgHistoryList{}
+	if err := openRecord(tgHistoryPurpose, chatID, s.lists[chatID], list); err != nil {
+		log.Printf("tg history: %v", err)
+		return &tgHistoryList{}
+	}
+	return list
+}
+
+// seal stores list as chatID's record. Caller must hold s.mu.
+func (s *tgHistoryStore) seal(chatID int64, list *tgHistoryList) error {
+	sealed, err := sealRecord(tgHistoryPurpose, chatID, list)
+	if err != nil {
+		return err
+	}
+	s.lists[chatID] = sealed
+	s.file.markDirty()
+	return nil
+}
+
+// enabled reports whether chatID has turned history on.
+func (s *tgHistoryStore) enabled(chatID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.lists[chatID]
+	return ok
+}
+
+// enable turns history on for chatID with an empty list.
+func (s *tgHistoryStore) enable(chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.lists[chatID]; ok {
+		return nil
+	}
+	if len(s.lists) >= tgHistoryMaxChats {
+		return errTGHistoryFull
+	}
+	return s.seal(chatID, &tgHistoryList{})
+}
+
+// record adds an order to chatID's history if the chat turned it on.
+func (s *tgHistoryStore) record(chatID int64, token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.lists[chatID]; !ok {
+		return
+	}
+	list := s.open(chatID)
+	list.NextID++
+	list.Entries = append([]tgHistoryEntry{{ID: list.NextID, Token: token, Added: time.Now()}}, list.Entries...)
+	if len(list.Entries) > tgHistoryMax {
+		list.Entries = list.Entries[:tgHistoryMax]
+	}
+	if err := s.seal(chatID, list); err != nil {
+		log.Printf("tg history: %v", err)
+	}
+}
+
+// entries returns chatID's orders within the retention period, newest
+// first.
+func (s *tgHistoryStore) entries(chatID int64) []tgHistoryEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.lists[chatID]; !ok {
+		return nil
+	}
+	list := s.open(chatID)
+	list.purge(time.Now())
+	return list.Entries
+}
+
+// forget deletes chatID's history and turns it off. It reports whether
+// history was on.
+func (s *tgHistoryStore) forget(chatID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.lists[chatID]; !ok {
+		return false
+	}
+	delete(s.lists, chatID)
+	s.file.markDirty()
+	return true
+}
+
+// start drops expired orders from every history each tgHistoryPurgeTick.
+func (s *tgHistoryStore) start() {
+	go func() {
+		for {
+			time.Sleep(tgHistoryPurgeTick)
+			s.purge()
+		}
+	}()
+}
+
+func (s *tgHistoryStore) purge() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for chatID := range s.lists {
+		if list := s.open(chatID); list.purge(now) {
+			s.seal(chatID, list)
+		}
+	}
+}
+
+// tgStatusBadge is the emoji shown for an order status in /history.
+func tgStatusBadge(status string) string {
+	switch strings.ToUpper(status) {
+	case "PENDING_DEPOSIT", "KNOWN_DEPOSIT_TX":
+		return "🕓"
+	case "PROCESSING":
+		return "⏳"
+	case "SUCCESS":
+		return "✅"
+	case "REFUNDED":
+		return "↩️"
+	case "INCOMPLETE_DEPOSIT":
+		return "⚠️"
+	case "FAILED":
+		return "❌"
+	}
+	return "❔"
+}
+
+// tgAge formats how long ago t was, coarsely.
+func tgAge(l *locale, t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return l.T("just now")
+	case d < time.Hour:
+		return l.T("%dm ago", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return l.T("%dh ago", int(d.Hours()))
+	}
+	return l.T("%dd ago", int(d.Hours()/24))
+}
+
+// handleTGHistory handles /history.
+func handleTGHistory(chatID int64) {
+	text, markup := renderTGHistory(tgSessions.locFor(chatID), chatID)
+	tgSendMessage(chatID, text, markup)
+}
+
+// renderTGHistory lists the chat's orders with their current status, or
+// offers to turn history on.
+func renderTGHistory(l *locale, chatID int64) (string, *TGInlineKeyboardMarkup) {
+	title := "<b>🧾 " + l.T("Order history") + "</b>\n\n"
+	if !tgHistory.enabled(chatID) {
+		text := title +
+			l.T("Turn on history to find your last %d orders here after their cards are cleared.", tgHistoryMax) + " " +
+			l.N("Only the order links are kept, encrypted, for %d days.", int(tgHistoryRetention.Hours()/24)) + " " +
+			l.T("/forget deletes them.")
+		return text, &TGInlineKeyboardMarkup{InlineKeyboard: [][]TGInlineKeyboardButton{
+			{{Text: l.T("✅ Keep my order history"), CallbackData: "oh:on"}},
+		}}
+	}
+
+	off := []TGInlineKeyboardButton{{Text: l.T("🗑 Turn off and delete"), CallbackData: "oh:off", Style: "danger"}}
+	entries := tgHistory.entries(chatID)
+	if len(entries) == 0 {
+		return title + l.T("No orders yet. Orders you place from now on will show up here."),
+			&TGInlineKeyboardMarkup{InlineKeyboard: [][]TGInlineKeyboardButton{off}}
+	}
+
+	orders := make([]*OrderData, len(entries))
+	statuses := make([]string, len(entries))
+	ctx, cancel := context.WithTimeout(context.Background(), nearTimeoutStatus)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i, e := range entries {
+		order, err := decryptOrderData(e.Token)
+		if err != nil {
+			continue
+		}
+		orders[i] = order
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if res, err := orderStatuses.get(ctx, orders[i]); err == nil {
+				statuses[i] = res.Status.Status
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var sb strings.Builder
+	sb.WriteString(title)
+	markup := &TGInlineKeyboardMarkup{}
+	for i, e := range entries {
+		order := orders[i]
+		if order == nil {
+			continue
+		}
+		pair := order.FromTicker + " → " + order.ToTicker
+		amount := pair
+		if order.AmountIn != "" {
+			amount = trimAmount(order.AmountIn, 6) + " " + pair
+		}
+		lock := ""
+		if order.Locked() {
+			lock = " 🔒"
+		}
+		fmt.Fprintf(&sb, "%s %s%s · %s\n", tgStatusBadge(statuses[i]), html.EscapeString(amount), lock, tgAge(l, e.Added))
+		markup.InlineKeyboard = append(markup.InlineKeyboard, []TGInlineKeyboardButton{
+			{Text: tgStatusBadge(statuses[i]) + " " + amount, CallbackData: "oh:" + strconv.Itoa(e.ID)},
+		})
+	}
+	sb.WriteString("\n" + l.T("Tap an order to open it."))
+	markup.InlineKeyboard = append(markup.InlineKeyboard, off)
+	return sb.String(), markup
+}
+
+// handleTGHistoryCallback handles the /history buttons: turning history on
+// or off, and opening an order.
+func handleTGHistoryCallback(chatID int64, sess *tgSession, msgID int, data string) {
+	l := sess.loc()
+	switch data {
+	case "on":
+		if err := tgHistory.enable(chatID); err != nil {
+			tgSendMessage(chatID, "❌ "+tgErrorText(l, err), nil)
+			return
+		}
+	case "off":
+		tgHistory.forget(chatID)
+	default:
+		id, _ := strconv.Atoi(data)
+		for _, e := range tgHistory.entries(chatID) {
+			if e.ID != id {
+				continue
+			}
+			if order, err := decryptOrderData(e.Token); err == nil {
+				showTGOrder(chatID, sess, order, e.Token)
+			}
+			return
+		}
+		tgSendMessage(chatID, l.T("That order is no longer in your history."), nil)
+		return
+	}
+	text, markup := renderTGHistory(l, chatID)
+	tgEditMessage(chatID, msgID, text, markup)
+}
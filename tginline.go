@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -336,12 +337,12 @@ func buildStatusResults(token string) []interface{} {
 		return nil
 	}
 
-	status, err := fetchStatus(order.DepositAddr, order.Memo)
+	res, err := orderStatuses.get(context.Background(), order)
 	if err != nil {
 		return nil
 	}
 
-	displayStatus := statusDisplayName(status.Status)
+	displayStatus := statusDisplayName(res.Status.Status)
 	title := fmt.Sprintf("Order: %s → %s — %s", order.FromTicker, order.ToTicker, displayStatus)
 	desc := fmt.Sprintf("%s %s → %s %s", order.AmountIn, order.FromTicker, order.AmountOut, order.ToTicker)
 	msgText := fmt.Sprintf(
@@ -442,13 +443,19 @@ func buildDeepLink(fromTicker, fromNet, toTicker, toNet, amount string) string {
 		}
 		return u
 	}
+	return "https://t.me/" + tgBotUsername + "?start=" + swapStartParam(fromTicker, fromNet, toTicker, toNet, amount)
+}
+
+// swapStartParam is the /start parameter that pre-fills a swap card; see
+// parseSwapStartParam.
+func swapStartParam(fromTicker, fromNet, toTicker, toNet, amount string) string {
 	param := "swap_" +
 		strings.ToUpper(fromTicker) + "-" + strings.ToLower(fromNet) +
 		"_" + strings.ToUpper(toTicker) + "-" + strings.ToLower(toNet)
 	if amount != "" {
 		param += "_" + amount
 	}
-	return "https://t.me/" + tgBotUsername + "?start=" + param
+	return param
 }
 
 // parseSwapStartParam pre-fills a session from a deep link start parameter.
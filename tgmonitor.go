@@ -111,7 +111,7 @@ func postMonitorCard(groupID, threadID int64, resellerName string, tx ExplorerTx
 		"parse_mode":        "HTML",
 		"link_preview_options": map[string]bool{"is_disabled": true},
 	}
-	if _, err := tgRequest("sendMessage", payload); err != nil {
+	if _, err := tgMonitorRequest("sendMessage", payload); err != nil {
 		// Don't log every error during backfill to avoid spam
 		_ = err
 	}
@@ -126,7 +126,7 @@ func updateMonitorThreadTitle(groupID, threadID int64, resellerDisplay string, t
 		"message_thread_id": threadID,
 		"name":              title,
 	}
-	tgRequest("editForumTopic", payload)
+	tgMonitorRequest("editForumTopic", payload)
 }
 
 // updateMainChatDescription updates the main chat description, replacing $ with the total.
@@ -136,7 +136,7 @@ func updateMainChatDescription() {
 	}
 
 	// Get current description
-	result, err := tgRequest("getChat", map[string]interface{}{
+	result, err := tgMonitorRequest("getChat", map[string]interface{}{
 		"chat_id": monitorMainChatID,
 	})
 	if err != nil {
@@ -157,7 +157,7 @@ func updateMainChatDescription() {
 	total := monitorTotalFeeUSD()
 	newDesc := strings.Replace(chatInfo.Description, "$", formatUSD(total), 1)
 
-	tgRequest("setChatDescription", map[string]interface{}{
+	tgMonitorRequest("setChatDescription", map[string]interface{}{
 		"chat_id":     monitorMainChatID,
 		"description": newDesc,
 	})
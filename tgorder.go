@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"html"
 	"log"
 	"strconv"
 	"strings"
@@ -14,11 +16,22 @@ func handleTGGetQuote(chatID int64, sess *tgSession) {
 	if !sess.isComplete() {
 		return
 	}
+	l := sess.loc()
 
 	fromToken := findToken(sess.FromTicker, sess.FromNet)
 	toToken := findToken(sess.ToTicker, sess.ToNet)
 	if fromToken == nil || toToken == nil {
-		showErrorAndCard(chatID, sess, "Token not found. Please reselect.")
+		showErrorAndCard(chatID, sess, l.T("Token not found. Please reselect."))
+		return
+	}
+
+	// The tokens may have changed since the addresses were entered.
+	if problem := addressProblem("recipient", toToken.ChainName, sess.RecvAddr); problem != "" {
+		showErrorAndCard(chatID, sess, problem)
+		return
+	}
+	if problem := addressProblem("refund", fromToken.ChainName, sess.RefundAddr); problem != "" {
+		showErrorAndCard(chatID, sess, problem)
 		return
 	}
 
@@ -31,18 +44,20 @@ func handleTGGetQuote(chatID int64, sess *tgSession) {
 	}
 
 	// Show loading state
-	tgEditMessage(chatID, sess.CardMsgID, "⏳ Fetching quote...\n<i>(may take up to 24s)</i>", nil)
+	tgEditMessage(chatID, sess.CardMsgID, l.T("⏳ Fetching quote...")+"\n<i>"+l.T("(may take up to 24s)")+"</i>", nil)
 
 	// Determine amount + swap type for the API.
 	var atomic string
 	var err error
+	amountToken := fromToken
 	if swapType == "EXACT_OUTPUT" {
+		amountToken = toToken
 		atomic, err = humanToAtomic(sess.AmountOut, toToken.Decimals)
 	} else {
 		atomic, err = humanToAtomic(sess.Amount, fromToken.Decimals)
 	}
 	if err != nil {
-		showErrorAndCard(chatID, sess, "Invalid amount: "+err.Error())
+		showErrorAndCard(chatID, sess, l.T("Invalid amount: %s", err.Error()))
 		return
 	}
 
@@ -65,30 +80,44 @@ func handleTGGetQuote(chatID int64, sess *tgSession) {
 		AppFees:            []struct{}{},
 	}
 
-	dryResp, err := requestDryQuote(req)
+	dryResp, err := requestDryQuote(context.Background(), req)
 	if err != nil {
-		showErrorAndCard(chatID, sess, "Quote failed: "+err.Error())
+		_, msg := describeAPIError(err, amountToken)
+		showErrorAndCard(chatID, sess, l.T("Quote failed: %s", l.T(msg)))
 		return
 	}
 
 	sess.DryQuote = dryResp
 	sess.State = stateQuoteConfirm
 
-	// Parse display values
-	amountInUSD := ""
-	amountOutUSD := ""
-	spreadUSD := ""
-	spreadPct := ""
-	rate := ""
+	cardText := "<pre>" + renderQuoteCardMono(sess.loc(), quoteCardData(dryResp, sess.FromTicker, sess.ToTicker, swapType)) + "</pre>"
+
+	sess.QuoteCardText = cardText
+	sess.LockPassphrase = ""
+
+	if err := tgEditMessage(chatID, sess.CardMsgID, cardText, quoteConfirmMarkup(sess)); err != nil {
+		log.Printf("tg edit quote card error: %v", err)
+	}
+}
+
+// quoteCardData turns a dry quote into the values shown on the quote card.
+func quoteCardData(dryResp *DryQuoteResponse, fromTicker, toTicker, swapType string) QuoteCardData {
+	p := QuoteCardData{
+		FromTicker: fromTicker,
+		ToTicker:   toTicker,
+		AmountIn:   dryResp.Quote.AmountInFormatted,
+		AmountOut:  dryResp.Quote.AmountOutFormatted,
+		SwapType:   swapType,
+	}
 
 	if dryResp.Quote.AmountInUSD != "" {
 		if v, err := strconv.ParseFloat(dryResp.Quote.AmountInUSD, 64); err == nil {
-			amountInUSD = formatUSD(v)
+			p.AmountInUSD = formatUSD(v)
 		}
 	}
 	if dryResp.Quote.AmountOutUSD != "" {
 		if v, err := strconv.ParseFloat(dryResp.Quote.AmountOutUSD, 64); err == nil {
-			amountOutUSD = formatUSD(v)
+			p.AmountOutUSD = formatUSD(v)
 		}
 	}
 
@@ -98,8 +127,8 @@ func handleTGGetQuote(chatID int64, sess *tgSession) {
 		if inUSD > 0 {
 			diff := inUSD - outUSD
 			pct := (diff / inUSD) * 100
-			spreadUSD = fmt.Sprintf("%.2f", diff)
-			spreadPct = fmt.Sprintf("%.2f", pct)
+			p.SpreadUSD = fmt.Sprintf("%.2f", diff)
+			p.SpreadPct = fmt.Sprintf("%.2f", pct)
 		}
 	}
 
@@ -108,40 +137,77 @@ func handleTGGetQuote(chatID int64, sess *tgSession) {
 		outVal, _ := strconv.ParseFloat(dryResp.Quote.AmountOutFormatted, 64)
 		if inVal > 0 {
 			r := outVal / inVal
-			rate = fmt.Sprintf("1 %s = %s %s", sess.FromTicker, formatRate(r), sess.ToTicker)
+			p.Rate = fmt.Sprintf("1 %s = %s %s", fromTicker, formatRate(r), toTicker)
 		}
 	}
+	return p
+}
 
-	cardText := "<pre>" + renderQuoteCardMono(QuoteCardData{
-		FromTicker:   sess.FromTicker,
-		ToTicker:     sess.ToTicker,
-		AmountIn:     dryResp.Quote.AmountInFormatted,
-		AmountOut:    dryResp.Quote.AmountOutFormatted,
-		AmountInUSD:  amountInUSD,
-		AmountOutUSD: amountOutUSD,
-		Rate:         rate,
-		SpreadUSD:    spreadUSD,
-		SpreadPct:    spreadPct,
-		SwapType:     swapType,
-	}) + "</pre>"
-
-	markup := &TGInlineKeyboardMarkup{
+// quoteConfirmMarkup is the keyboard under the quote card.
+func quoteConfirmMarkup(sess *tgSession) *TGInlineKeyboardMarkup {
+	l := sess.loc()
+	lock := TGInlineKeyboardButton{Text: l.T("🔒 Lock with passphrase"), CallbackData: "lp"}
+	if sess.LockPassphrase != "" {
+		lock = TGInlineKeyboardButton{Text: l.T("🔓 Remove passphrase lock"), CallbackData: "lp"}
+	}
+	return &TGInlineKeyboardMarkup{
 		InlineKeyboard: [][]TGInlineKeyboardButton{
 			{
-				{Text: "✅ Confirm Swap", CallbackData: "cs", Style: "success"},
-				{Text: "❌ Cancel", CallbackData: "cq", Style: "danger"},
+				{Text: l.T("✅ Confirm Swap"), CallbackData: "cs", Style: "success"},
+				{Text: l.T("❌ Cancel"), CallbackData: "cq", Style: "danger"},
 			},
+			{lock},
 		},
 	}
+}
 
-	if err := tgEditMessage(chatID, sess.CardMsgID, cardText, markup); err != nil {
-		log.Printf("tg edit quote card error: %v", err)
+// handleTGToggleLock asks for a passphrase to lock the order link, or removes
+// a lock chosen earlier.
+func handleTGToggleLock(chatID int64, sess *tgSession) {
+	if sess.State != stateQuoteConfirm && sess.State != stateEnterLockPass {
+		return
+	}
+	if sess.LockPassphrase != "" {
+		sess.LockPassphrase = ""
+		tgEditMessage(chatID, sess.CardMsgID, sess.QuoteCardText, quoteConfirmMarkup(sess))
+		return
+	}
+
+	l := sess.loc()
+	sess.State = stateEnterLockPass
+	prompt := l.T("Choose a passphrase (at least %d characters). The order link and /status will ask for it before showing addresses. It cannot be recovered.", orderLockMinPassphrase)
+	msg, err := tgSendMessage(chatID, prompt, &TGForceReply{
+		ForceReply:            true,
+		Selective:             true,
+		InputFieldPlaceholder: l.T("Passphrase..."),
+	})
+	if err == nil {
+		sess.PromptMsgID = msg.MessageID
+	}
+}
+
+// handleTGLockInput stores the chosen passphrase and returns to the quote card.
+// The reply is deleted straight away so the passphrase doesn't linger in chat.
+func handleTGLockInput(chatID int64, sess *tgSession, msg *TGMessage) {
+	passphrase := strings.TrimSpace(msg.Text)
+	tgDeleteMessage(chatID, msg.MessageID)
+	if len([]rune(passphrase)) < orderLockMinPassphrase {
+		tgSendMessage(chatID, sess.loc().T("Passphrase must be at least %d characters. Please try again.", orderLockMinPassphrase), nil)
+		return
+	}
+
+	sess.LockPassphrase = passphrase
+	sess.State = stateQuoteConfirm
+	cleanupPromptReply(chatID, sess, 0)
+	if err := tgEditMessage(chatID, sess.CardMsgID, sess.QuoteCardText, quoteConfirmMarkup(sess)); err != nil {
+		log.Printf("tg edit locked quote card error: %v", err)
 	}
 }
 
 // handleTGAnyInputSwap handles ANY_INPUT mode: skip dry quote, issue real quote, show deposit card.
 func handleTGAnyInputSwap(chatID int64, sess *tgSession, fromToken, toToken *TokenInfo) {
-	tgEditMessage(chatID, sess.CardMsgID, "⏳ Setting up quick swap...\n<i>(may take up to 24s)</i>", nil)
+	l := sess.loc()
+	tgEditMessage(chatID, sess.CardMsgID, l.T("⏳ Setting up quick swap...")+"\n<i>"+l.T("(may take up to 24s)")+"</i>", nil)
 
 	// Use 1-unit reference amount for the quote
 	refAmount := "1" + strings.Repeat("0", fromToken.Decimals)
@@ -164,9 +230,15 @@ func handleTGAnyInputSwap(chatID int64, sess *tgSession, fromToken, toToken *Tok
 		AppFees:            []struct{}{},
 	}
 
-	quoteResp, err := requestQuote(req)
+	quoteResp, err := requestQuote(context.Background(), req)
 	if err != nil {
-		showErrorAndCard(chatID, sess, "Quick swap failed: "+err.Error())
+		_, msg := describeAPIError(err, fromToken)
+		showErrorAndCard(chatID, sess, l.T("Quick swap failed: %s", l.T(msg)))
+		return
+	}
+	if err := verifyQuoteResponse(req, quoteResp, fromToken); err != nil {
+		log.Printf("tg quick swap: %v", err)
+		showErrorAndCard(chatID, sess, l.T("Quick swap refused: the swap provider returned a quote that doesn't match your request. Please try again."))
 		return
 	}
 
@@ -184,6 +256,7 @@ func handleTGAnyInputSwap(chatID int64, sess *tgSession, fromToken, toToken *Tok
 		RefundAddr:  sess.RefundAddr,
 		RecvAddr:    sess.RecvAddr,
 		SwapType:    "ANY_INPUT",
+		Upstream:    quoteResp.endpoint,
 	}
 
 	orderToken, err := encryptOrderData(order)
@@ -192,10 +265,13 @@ func handleTGAnyInputSwap(chatID int64, sess *tgSession, fromToken, toToken *Tok
 		return
 	}
 	sess.OrderToken = orderToken
+	sess.OrderKey = nil // quick swaps are never locked
 	sess.State = stateOrderActive
+	tgHistory.record(chatID, orderToken)
+	storeAttestation("telegram", orderToken, nil, req, quoteResp)
 
 	// Build ANY_INPUT deposit card
-	depositCard := "<pre>" + renderAnyInputDepositCardMono(AnyInputCardData{
+	depositCard := "<pre>" + renderAnyInputDepositCardMono(l, AnyInputCardData{
 		FromTicker: sess.FromTicker,
 		ToTicker:   sess.ToTicker,
 		Network:    networkDisplayName(sess.FromNet),
@@ -203,20 +279,22 @@ func handleTGAnyInputSwap(chatID int64, sess *tgSession, fromToken, toToken *Tok
 		RecvAddr:   sess.RecvAddr,
 	}) + "</pre>"
 
-	depositCard += "\n\n<code>" + quoteResp.Quote.DepositAddress + "</code>"
+	depositCard += "\n\n<code>" + html.EscapeString(quoteResp.Quote.DepositAddress) + "</code>"
 	if quoteResp.Quote.DepositMemo != "" {
-		depositCard += "\n\nMemo: <code>" + quoteResp.Quote.DepositMemo + "</code>"
+		depositCard += "\n\n" + l.T("Memo:") + " <code>" + html.EscapeString(quoteResp.Quote.DepositMemo) + "</code>"
 	}
 
 	orderURL := tgAppURL + "/order/" + orderToken
 	markup := &TGInlineKeyboardMarkup{
 		InlineKeyboard: [][]TGInlineKeyboardButton{
 			{
-				{Text: "🔄 Refresh Status", CallbackData: "rs"},
-				{Text: "📱 Open Order", WebApp: &TGWebApp{URL: orderURL}},
+				{Text: l.T("🔄 Refresh Status"), CallbackData: "rs"},
+				{Text: l.T("📱 Open Order"), WebApp: &TGWebApp{URL: orderURL}},
 			},
 		},
 	}
+	addAttestationButton(markup, sess)
+	addWatchButton(markup, chatID, sess, "PENDING_DEPOSIT")
 
 	if err := tgEditMessage(chatID, sess.CardMsgID, depositCard, markup); err != nil {
 		log.Printf("tg edit any_input deposit card error: %v", err)
@@ -225,7 +303,7 @@ func handleTGAnyInputSwap(chatID int64, sess *tgSession, fromToken, toToken *Tok
 
 // handleTGConfirmSwap places a real quote and shows the unified deposit/order card.
 func handleTGConfirmSwap(chatID int64, sess *tgSession) {
-	if sess.State != stateQuoteConfirm {
+	if sess.State != stateQuoteConfirm && sess.State != stateEnterLockPass {
 		return
 	}
 
@@ -239,7 +317,9 @@ func handleTGConfirmSwap(chatID int64, sess *tgSession) {
 
 	var atomic string
 	var err error
+	amountToken := fromToken
 	if swapType == "EXACT_OUTPUT" {
+		amountToken = toToken
 		atomic, err = humanToAtomic(sess.AmountOut, toToken.Decimals)
 	} else {
 		atomic, err = humanToAtomic(sess.Amount, fromToken.Decimals)
@@ -249,7 +329,8 @@ func handleTGConfirmSwap(chatID int64, sess *tgSession) {
 	}
 
 	// Show loading state
-	tgEditMessage(chatID, sess.CardMsgID, "⏳ Placing order...\n<i>(may take up to 24s)</i>", nil)
+	l := sess.loc()
+	tgEditMessage(chatID, sess.CardMsgID, l.T("⏳ Placing order...")+"\n<i>"+l.T("(may take up to 24s)")+"</i>", nil)
 
 	bps, _ := slippageToBPS(sess.Slippage)
 
@@ -270,11 +351,18 @@ func handleTGConfirmSwap(chatID int64, sess *tgSession) {
 		AppFees:            []struct{}{},
 	}
 
-	quoteResp, err := requestQuote(req)
+	quoteResp, err := requestQuote(context.Background(), req)
 	if err != nil {
-		showErrorAndCard(chatID, sess, "Order failed: "+err.Error())
+		_, msg := describeAPIError(err, amountToken)
+		showErrorAndCard(chatID, sess, l.T("Order failed: %s", l.T(msg)))
 		return
 	}
+	if err := verifyQuoteResponse(req, quoteResp, fromToken); err != nil {
+		log.Printf("tg swap: %v", err)
+		showErrorAndCard(chatID, sess, l.T("Order refused: the swap provider returned a quote that doesn't match your request. Please try again."))
+		return
+	}
+	quoteReq := req
 
 	order := &OrderData{
 		DepositAddr: quoteResp.Quote.DepositAddress,
@@ -290,21 +378,32 @@ func handleTGConfirmSwap(chatID int64, sess *tgSession) {
 		RefundAddr:  sess.RefundAddr,
 		RecvAddr:    sess.RecvAddr,
 		SwapType:    swapType,
+		Upstream:    quoteResp.endpoint,
 	}
 
-	orderToken, err := encryptOrderData(order)
+	var orderToken string
+	sess.OrderKey = nil
+	if sess.LockPassphrase != "" {
+		orderToken, sess.OrderKey, err = encryptLockedOrderData(order, sess.LockPassphrase)
+		sess.LockPassphrase = ""
+	} else {
+		orderToken, err = encryptOrderData(order)
+	}
 	if err != nil {
 		log.Printf("tg encrypt order error: %v", err)
 		return
 	}
 	sess.OrderToken = orderToken
 	sess.State = stateOrderActive
+	tgHistory.record(chatID, orderToken)
+	cleanupPromptReply(chatID, sess, 0)
+	storeAttestation("telegram", orderToken, sess.OrderKey, quoteReq, quoteResp)
 
 	netName := networkDisplayName(sess.FromNet)
-	timeLeft := deadlineString(quoteResp.Quote.Deadline)
+	timeLeft := deadlineString(sess.loc(), quoteResp.Quote.Deadline)
 
 	// Build unified deposit/order card (step 0 of stepper)
-	depositCard := "<pre>" + renderDepositCardMono(DepositCardData{
+	depositCard := "<pre>" + renderDepositCardMono(l, DepositCardData{
 		FromTicker: sess.FromTicker,
 		ToTicker:   sess.ToTicker,
 		AmountIn:   order.AmountIn,
@@ -317,20 +416,25 @@ func handleTGConfirmSwap(chatID int64, sess *tgSession) {
 
 	// Copyable amount above address
 	depositCard += "\n\n<code>" + order.AmountIn + " " + sess.FromTicker + "</code>"
-	depositCard += "\n\n<code>" + quoteResp.Quote.DepositAddress + "</code>"
+	depositCard += "\n\n<code>" + html.EscapeString(quoteResp.Quote.DepositAddress) + "</code>"
 	if quoteResp.Quote.DepositMemo != "" {
-		depositCard += "\n\nMemo: <code>" + quoteResp.Quote.DepositMemo + "</code>"
+		depositCard += "\n\n" + l.T("Memo:") + " <code>" + html.EscapeString(quoteResp.Quote.DepositMemo) + "</code>"
+	}
+	if sess.OrderKey != nil {
+		depositCard += "\n\n🔒 <i>" + l.T("Order link locked — the order page and /status will ask for your passphrase.") + "</i>"
 	}
 
 	orderURL := tgAppURL + "/order/" + orderToken
 	markup := &TGInlineKeyboardMarkup{
 		InlineKeyboard: [][]TGInlineKeyboardButton{
 			{
-				{Text: "🔄 Refresh Status", CallbackData: "rs"},
-				{Text: "📱 Open Order", WebApp: &TGWebApp{URL: orderURL}},
+				{Text: l.T("🔄 Refresh Status"), CallbackData: "rs"},
+				{Text: l.T("📱 Open Order"), WebApp: &TGWebApp{URL: orderURL}},
 			},
 		},
 	}
+	addAttestationButton(markup, sess)
+	addWatchButton(markup, chatID, sess, "PENDING_DEPOSIT")
 
 	if err := tgEditMessage(chatID, sess.CardMsgID, depositCard, markup); err != nil {
 		log.Printf("tg edit deposit card error: %v", err)
@@ -341,6 +445,8 @@ func handleTGConfirmSwap(chatID int64, sess *tgSession) {
 func handleTGCancelQuote(chatID int64, sess *tgSession) {
 	sess.State = stateSwapCard
 	sess.DryQuote = nil
+	sess.LockPassphrase = ""
+	cleanupPromptReply(chatID, sess, 0)
 
 	text, markup := renderSwapCard(sess)
 	if sess.CardMsgID != 0 {
@@ -351,14 +457,14 @@ func handleTGCancelQuote(chatID int64, sess *tgSession) {
 }
 
 // buildOrderCard builds the unified order card text and markup for any order state.
-func buildOrderCard(order *OrderData, status *StatusResponse, orderToken string) (string, *TGInlineKeyboardMarkup) {
+func buildOrderCard(l *locale, order *OrderData, status *StatusResponse, orderToken string) (string, *TGInlineKeyboardMarkup) {
 	isTerminal := isTerminalStatus(status.Status)
 
 	var cardText string
 	statusUpper := strings.ToUpper(status.Status)
 	if statusUpper == "PENDING_DEPOSIT" || statusUpper == "KNOWN_DEPOSIT_TX" {
 		if order.SwapType == "ANY_INPUT" {
-			cardText = "<pre>" + renderAnyInputDepositCardMono(AnyInputCardData{
+			cardText = "<pre>" + renderAnyInputDepositCardMono(l, AnyInputCardData{
 				FromTicker: order.FromTicker,
 				ToTicker:   order.ToTicker,
 				Network:    networkDisplayName(order.FromNet),
@@ -367,8 +473,8 @@ func buildOrderCard(order *OrderData, status *StatusResponse, orderToken string)
 			}) + "</pre>"
 		} else {
 			netName := networkDisplayName(order.FromNet)
-			timeLeft := deadlineString(order.Deadline)
-			cardText = "<pre>" + renderDepositCardMono(DepositCardData{
+			timeLeft := deadlineString(l, order.Deadline)
+			cardText = "<pre>" + renderDepositCardMono(l, DepositCardData{
 				FromTicker: order.FromTicker,
 				ToTicker:   order.ToTicker,
 				AmountIn:   order.AmountIn,
@@ -382,17 +488,17 @@ func buildOrderCard(order *OrderData, status *StatusResponse, orderToken string)
 		}
 		cardText += "\n\n<code>" + order.DepositAddr + "</code>"
 		if order.Memo != "" {
-			cardText += "\n\nMemo: <code>" + order.Memo + "</code>"
+			cardText += "\n\n" + l.T("Memo:") + " <code>" + order.Memo + "</code>"
 		}
 	} else {
-		cardText = "<pre>" + renderAnyStatusCard(order, status) + "</pre>"
+		cardText = "<pre>" + renderAnyStatusCard(l, order, status) + "</pre>"
 	}
 
 	var rows [][]TGInlineKeyboardButton
 
 	if !isTerminal {
 		rows = append(rows, []TGInlineKeyboardButton{
-			{Text: "🔄 Refresh Status", CallbackData: "rs"},
+			{Text: l.T("🔄 Refresh Status"), CallbackData: "rs"},
 		})
 	}
 
@@ -400,7 +506,7 @@ func buildOrderCard(order *OrderData, status *StatusResponse, orderToken string)
 		for _, tx := range status.SwapDetails.DestTxs {
 			if tx.ExplorerURL != "" {
 				rows = append(rows, []TGInlineKeyboardButton{
-					{Text: "🔗 View TX", WebApp: &TGWebApp{URL: tx.ExplorerURL}},
+					{Text: l.T("🔗 View TX"), WebApp: &TGWebApp{URL: tx.ExplorerURL}},
 				})
 				break
 			}
@@ -409,14 +515,14 @@ func buildOrderCard(order *OrderData, status *StatusResponse, orderToken string)
 
 	if isTerminal {
 		rows = append(rows, []TGInlineKeyboardButton{
-			{Text: "🗑 Clear", CallbackData: "dm", Style: "danger"},
-			{Text: "🆕 New Swap", CallbackData: "ns", Style: "success"},
+			{Text: l.T("🗑 Clear"), CallbackData: "dm", Style: "danger"},
+			{Text: l.T("🆕 New Swap"), CallbackData: "ns", Style: "success"},
 		})
 	}
 
 	orderURL := tgAppURL + "/order/" + orderToken
 	rows = append(rows, []TGInlineKeyboardButton{
-		{Text: "📱 Open Order", WebApp: &TGWebApp{URL: orderURL}},
+		{Text: l.T("📱 Open Order"), WebApp: &TGWebApp{URL: orderURL}},
 	})
 
 	return cardText, &TGInlineKeyboardMarkup{InlineKeyboard: rows}
@@ -432,14 +538,24 @@ func handleTGRefreshStatus(chatID int64, sess *tgSession) {
 	if err != nil {
 		return
 	}
+	if order.Locked() {
+		if order, err = unlockOrderDataWithKey(order, sess.OrderKey); err != nil {
+			tgEditMessage(chatID, sess.CardMsgID, sess.loc().T("🔒 This order is locked. Use /status with its token to unlock it again."), nil)
+			return
+		}
+	}
 
-	status, err := fetchStatus(order.DepositAddr, order.Memo)
+	res, err := orderStatuses.get(context.Background(), order)
 	if err != nil {
-		tgEditMessage(chatID, sess.CardMsgID, "❌ Status check failed: "+err.Error(), nil)
+		tgEditMessage(chatID, sess.CardMsgID, sess.loc().T("❌ Status check failed: %s", err.Error()), nil)
 		return
 	}
 
-	cardText, markup := buildOrderCard(order, status, sess.OrderToken)
+	l := sess.loc()
+	cardText, markup := buildOrderCard(l, order, res.Status, sess.OrderToken)
+	addAttestationButton(markup, sess)
+	addWatchButton(markup, chatID, sess, res.Status.Status)
+	addSaveAddressesButton(l, markup, chatID, order, res.Status.Status)
 
 	if err := tgEditMessage(chatID, sess.CardMsgID, cardText, markup); err != nil {
 		log.Printf("tg refresh status edit error: %v", err)
@@ -459,11 +575,12 @@ func isTerminalStatus(s string) bool {
 
 // showErrorAndCard edits CardMsgID to show an error notice above the restored swap card.
 // All session inputs are preserved; no button tap required to recover.
+// errMsg is plain text: it may quote the user's input or the upstream error.
 func showErrorAndCard(chatID int64, sess *tgSession, errMsg string) {
 	sess.State = stateSwapCard
 	sess.DryQuote = nil
 	cardText, markup := renderSwapCard(sess)
-	text := "❌ " + errMsg + "\n\n" + cardText
+	text := "❌ " + html.EscapeString(errMsg) + "\n\n" + cardText
 	if err := tgEditMessage(chatID, sess.CardMsgID, text, markup); err != nil {
 		log.Printf("tg show error+card: %v", err)
 	}
@@ -479,3 +596,37 @@ func handleTGDeleteMessages(chatID int64, sess *tgSession) {
 	}
 	sess.reset()
 }
+
+// tgAttestationSecret is the secret the session's order attestation is
+// stored under: the unlock key for locked orders, else the token.
+func tgAttestationSecret(sess *tgSession) []byte {
+	if sess.OrderKey != nil {
+		return sess.OrderKey
+	}
+	return []byte(sess.OrderToken)
+}
+
+// addAttestationButton adds a "Signed Quote" row to an order card while the
+// session's order still has an attestation on file.
+func addAttestationButton(markup *TGInlineKeyboardMarkup, sess *tgSession) {
+	if markup == nil || sess.OrderToken == "" || attestations.get(tgAttestationSecret(sess)) == nil {
+		return
+	}
+	markup.InlineKeyboard = append(markup.InlineKeyboard, []TGInlineKeyboardButton{
+		{Text: "📜 " + sess.loc().T("Signed Quote"), CallbackData: "ad"},
+	})
+}
+
+// handleTGSendAttestation sends the signed quote attestation as a file.
+func handleTGSendAttestation(chatID int64, sess *tgSession) {
+	l := sess.loc()
+	doc := attestations.get(tgAttestationSecret(sess))
+	if sess.OrderToken == "" || doc == nil {
+		tgSendMessage(chatID, l.T("No signed quote is available for this order. Attestations are kept for 24 hours."), nil)
+		return
+	}
+	caption := l.T("📜 Signed quote attestation. Check it offline with <code>zero verify-attestation attestation.json</code> against the key on %s", tgAppURL+"/verify")
+	if _, err := tgSendDocument(chatID, "attestation.json", doc, caption); err != nil {
+		log.Printf("tg send attestation error: %v", err)
+	}
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Outbound Bot API queue.
+//
+// Every Bot API call except getUpdates goes through tgOutbox, which keeps
+// the bot inside Telegram's limits instead of discovering them one 429 at a
+// time: about 30 messages a second overall, one a second per private chat
+// and 20 a minute per group. User-facing swap traffic is always sent before
+// reseller monitor posts. A 429 pauses the chat (or the whole bot) for the
+// retry_after Telegram asks for; network errors and 5xx answers are retried
+// with backoff, and so is an attempt that takes longer than tgCallTimeout,
+// so a stalled connection can't hold a worker. An edit of a message that is
+// still waiting to be sent replaces the queued one, so a burst of status
+// redraws costs one request.
+// Calls to one chat are sent one at a time, in the order they were queued.
+
+const (
+	tgPriorityUser = iota
+	tgPriorityMonitor
+	tgPriorities
+)
+
+// tgRate is a token bucket: perSec requests a second, up to burst at once.
+type tgRate struct {
+	perSec float64
+	burst  float64
+}
+
+// Variables rather than constants so tests can change them.
+var (
+	tgGlobalRate     = tgRate{perSec: 30, burst: 30}
+	tgPrivateRate    = tgRate{perSec: 1, burst: 8}
+	tgGroupRate      = tgRate{perSec: 20.0 / 60, burst: 5}
+	tgRetryAfterUnit = time.Second // unit of parameters.retry_after
+	tgRetryBackoff   = 500 * time.Millisecond
+	tgCallTimeout    = 15 * time.Second // one attempt; a hung connection frees its worker
+)
+
+const (
+	tgMaxAttempts   = 5
+	tgOutboxWorkers = 8
+	tgOutboxChatMax = 10000 // idle chat buckets are dropped beyond this
+)
+
+// tgQueueCap bounds each priority's queue. A full queue refuses new calls
+// rather than letting them wait for minutes.
+var tgQueueCap = [tgPriorities]int{2000, 500}
+
+var errTGQueueFull = errors.New("tg outbox full")
+
+type tgResult struct {
+	result json.RawMessage
+	err    error
+}
+
+// tgCall is one queued Bot API request.
+type tgCall struct {
+	priority    int
+	method      string
+	chatID      int64  // 0 for calls that aren't sent to a chat
+	mergeKey    string // calls with the same key replace each other while queued
+	contentType string
+	body        []byte
+
+	attempts  int
+	notBefore time.Time
+	waiters   []chan tgResult
+}
+
+type tgBucket struct {
+	rate        tgRate
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+func newTGBucket(rate tgRate, now time.Time) *tgBucket {
+	return &tgBucket{rate: rate, tokens: rate.burst, last: now}
+}
+
+// wait returns how long until the bucket has a token; 0 means now.
+func (b *tgBucket) wait(now time.Time) time.Duration {
+	if now.Before(b.pausedUntil) {
+		return b.pausedUntil.Sub(now)
+	}
+	b.tokens += now.Sub(b.last).Seconds() * b.rate.perSec
+	if b.tokens > b.rate.burst {
+		b.tokens = b.rate.burst
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate.perSec * float64(time.Second))
+}
+
+func (b *tgBucket) take() { b.tokens-- }
+
+// TGOutboxStatus is the outbox summary shown on /verify.
+type TGOutboxStatus struct {
+	UserQueued    int
+	MonitorQueued int
+	Sent          int64
+	Merged        int64
+	Retried       int64
+	RateLimited   int64 // 429 answers from Telegram
+	Dropped       int64 // refused on a full queue or failed after every retry
+}
+
+type tgOutboxQueue struct {
+	mu      sync.Mutex
+	queues  [tgPriorities][]*tgCall
+	pending map[string]*tgCall // queued calls by merge key
+	sending map[string]bool    // orderKey of every call being sent
+	global  *tgBucket
+	chats   map[int64]*tgBucket
+	wake    chan struct{}
+	stats   TGOutboxStatus
+	started sync.Once
+}
+
+var tgOutbox = newTGOutbox()
+
+func newTGOutbox() *tgOutboxQueue {
+	return &tgOutboxQueue{
+		pending: make(map[string]*tgCall),
+		sending: make(map[string]bool),
+		global:  newTGBucket(tgGlobalRate, time.Now()),
+		chats:   make(map[int64]*tgBucket),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// tgCallTarget returns the chat a request is sent to and, for requests that
+// only set the latest state of something, the key under which a newer
+// request makes an older queued one redundant.
+func tgCallTarget(method string, body []byte) (int64, string) {
+	var target struct {
+		ChatID          json.RawMessage `json:"chat_id"`
+		MessageID       int             `json:"message_id"`
+		InlineMessageID string          `json:"inline_message_id"`
+		MessageThreadID int             `json:"message_thread_id"`
+	}
+	json.Unmarshal(body, &target)
+	chatID, _ := strconv.ParseInt(string(target.ChatID), 10, 64) // @username chats only count globally
+	chat := strconv.FormatInt(chatID, 10)
+
+	switch method {
+	case "editMessageText", "editMessageReplyMarkup", "editMessageCaption":
+		if target.InlineMessageID != "" {
+			return chatID, method + ":" + target.InlineMessageID
+		}
+		if chatID != 0 && target.MessageID != 0 {
+			return chatID, method + ":" + chat + ":" + strconv.Itoa(target.MessageID)
+		}
+	case "editForumTopic":
+		return chatID, method + ":" + chat + ":" + strconv.Itoa(target.MessageThreadID)
+	case "setChatDescription":
+		return chatID, method + ":" + chat
+	}
+	return chatID, ""
+}
+
+// orderKey returns the key under which call must wait for an earlier call
+// to finish: its chat, or for edits of inline messages (which have no chat)
+// the message. Other calls have no order to keep.
+func (c *tgCall) orderKey() string {
+	if c.chatID != 0 {
+		return "chat:" + strconv.FormatInt(c.chatID, 10)
+	}
+	return c.mergeKey
+}
+
+// do queues call and waits for its result.
+func (o *tgOutboxQueue) do(call *tgCall) (json.RawMessage, error) {
+	res := <-o.enqueue(call)
+	return res.result, res.err
+}
+
+// enqueue adds call to its queue, or folds it into a queued call with the
+// same merge key, and returns the channel its result arrives on.
+func (o *tgOutboxQueue) enqueue(call *tgCall) <-chan tgResult {
+	done := make(chan tgResult, 1)
+	call.waiters = append(call.waiters, done)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if call.mergeKey != "" {
+		if queued, ok := o.pending[call.mergeKey]; ok {
+			queued.body = call.body
+			queued.waiters = append(queued.waiters, done)
+			if call.priority < queued.priority {
+				o.remove(queued)
+				queued.priority = call.priority
+				o.queues[queued.priority] = append(o.queues[queued.priority], queued)
+			}
+			o.stats.Merged++
+			return done
+		}
+	}
+	if len(o.queues[call.priority]) >= tgQueueCap[call.priority] {
+		o.stats.Dropped++
+		done <- tgResult{err: errTGQueueFull}
+		return done
+	}
+	o.queues[call.priority] = append(o.queues[call.priority], call)
+	if call.mergeKey != "" {
+		o.pending[call.mergeKey] = call
+	}
+	o.signal()
+	return done
+}
+
+// signal wakes the dispatcher. Caller must hold o.mu.
+func (o *tgOutboxQueue) signal() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// remove takes call out of its queue. Caller must hold o.mu.
+func (o *tgOutboxQueue) remove(call *tgCall) {
+	q := o.queues[call.priority]
+	for i, c := range q {
+		if c == call {
+			o.queues[call.priority] = append(q[:i], q[i+1:]...)
+			return
+		}
+	}
+}
+
+func (o *tgOutboxQueue) chatBucket(chatID int64, now time.Time) *tgBucket {
+	b, ok := o.chats[chatID]
+	if !ok {
+		if len(o.chats) >= tgOutboxChatMax {
+			for id, c := range o.chats {
+				if c.wait(now) == 0 && c.tokens >= c.rate.burst {
+					delete(o.chats, id)
+				}
+			}
+		}
+		rate := tgPrivateRate
+		if chatID < 0 {
+			rate = tgGroupRate
+		}
+		b = newTGBucket(rate, now)
+		o.chats[chatID] = b
+	}
+	return b
+}
+
+// next removes and returns the first call that may be sent now, user
+// traffic first. Otherwise it returns how long until one might be; 0 means
+// nothing can be sent until a call in flight finishes (or the queues are
+// empty). Calls to one chat keep their order: a chat's next call waits
+// until its previous one has finished, so an older edit can't land after a
+// newer one. Caller must hold o.mu.
+func (o *tgOutboxQueue) next(now time.Time) (*tgCall, time.Duration) {
+	if d := o.global.wait(now); d > 0 {
+		return nil, d
+	}
+	var soonest time.Duration
+	later := func(d time.Duration) {
+		if soonest == 0 || d < soonest {
+			soonest = d
+		}
+	}
+	blocked := make(map[int64]bool)
+	for p := range o.queues {
+		for i, call := range o.queues[p] {
+			if call.chatID != 0 && blocked[call.chatID] {
+				continue
+			}
+			if key := call.orderKey(); key != "" && o.sending[key] {
+				if call.chatID != 0 {
+					blocked[call.chatID] = true
+				}
+				continue
+			}
+			d := call.notBefore.Sub(now)
+			if call.chatID != 0 {
+				if cd := o.chatBucket(call.chatID, now).wait(now); cd > d {
+					d = cd
+				}
+			}
+			if d > 0 {
+				later(d)
+				if call.chatID != 0 {
+					blocked[call.chatID] = true
+				}
+				continue
+			}
+			o.global.take()
+			if call.chatID != 0 {
+				o.chats[call.chatID].take()
+			}
+			o.queues[p] = append(o.queues[p][:i], o.queues[p][i+1:]...)
+			if call.mergeKey != "" && o.pending[call.mergeKey] == call {
+				delete(o.pending, call.mergeKey)
+			}
+			if key := call.orderKey(); key != "" {
+				o.sending[key] = true
+			}
+			return call, 0
+		}
+	}
+	return nil, soonest
+}
+
+// finished lets the next call to call's chat go. Caller must hold o.mu.
+func (o *tgOutboxQueue) finished(call *tgCall) {
+	if key := call.orderKey(); key != "" && o.sending[key] {
+		delete(o.sending, key)
+		o.signal()
+	}
+}
+
+// start runs the dispatcher.
+func (o *tgOutboxQueue) start() {
+	o.started.Do(func() { go o.run() })
+}
+
+func (o *tgOutboxQueue) run() {
+	sem := make(chan struct{}, tgOutboxWorkers)
+	for {
+		o.mu.Lock()
+		call, wait := o.next(time.Now())
+		o.mu.Unlock()
+		if call == nil {
+			var timer <-chan time.Time
+			if wait > 0 {
+				timer = time.After(wait)
+			}
+			select {
+			case <-o.wake:
+			case <-timer:
+			}
+			continue
+		}
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem }()
+			o.send(call)
+		}()
+	}
+}
+
+// send makes one attempt at call and either delivers the result or puts
+// the call back for a retry.
+func (o *tgOutboxQueue) send(call *tgCall) {
+	ctx, cancel := context.WithTimeout(context.Background(), tgCallTimeout)
+	result, err := tgPost(ctx, call.method, call.contentType, call.body)
+	cancel()
+	call.attempts++
+
+	var apiErr *tgAPIError
+	isAPIErr := errors.As(err, &apiErr)
+	retry := err != nil && call.attempts < tgMaxAttempts &&
+		(!isAPIErr || apiErr.Code == 429 || apiErr.Code >= 500)
+
+	o.mu.Lock()
+	o.finished(call)
+	switch {
+	case err == nil:
+		o.stats.Sent++
+	case isAPIErr && apiErr.Code == 429:
+		o.stats.RateLimited++
+		pause := time.Duration(apiErr.RetryAfter) * tgRetryAfterUnit
+		if pause <= 0 {
+			pause = tgRetryAfterUnit
+		}
+		until := time.Now().Add(pause)
+		if call.chatID != 0 {
+			o.chatBucket(call.chatID, time.Now()).pausedUntil = until
+		} else {
+			o.global.pausedUntil = until
+		}
+	case retry:
+		call.notBefore = time.Now().Add(tgRetryBackoff << (call.attempts - 1))
+	}
+	if !retry {
+		if err != nil && (!isAPIErr || apiErr.Code == 429 || apiErr.Code >= 500) {
+			o.stats.Dropped++
+		}
+		o.mu.Unlock()
+		for _, w := range call.waiters {
+			w <- tgResult{result: result, err: err}
+		}
+		return
+	}
+	o.stats.Retried++
+	if call.mergeKey != "" {
+		if newer, ok := o.pending[call.mergeKey]; ok {
+			// A newer edit is already queued; it supersedes this one.
+			newer.waiters = append(newer.waiters, call.waiters...)
+			o.stats.Merged++
+			o.mu.Unlock()
+			return
+		}
+		o.pending[call.mergeKey] = call
+	}
+	o.queues[call.priority] = append([]*tgCall{call}, o.queues[call.priority]...)
+	o.signal()
+	o.mu.Unlock()
+}
+
+// status summarizes the queues and counters.
+func (o *tgOutboxQueue) status() TGOutboxStatus {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	s := o.stats
+	s.UserQueued = len(o.queues[tgPriorityUser])
+	s.MonitorQueued = len(o.queues[tgPriorityMonitor])
+	return s
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// Telegram update delivery.
+//
+// By default Telegram pushes updates to /tg/webhook/<secret>, which needs a
+// public HTTPS URL. With TG_MODE=polling the bot deletes its webhook and
+// pulls updates with getUpdates instead, so it also runs locally, behind NAT
+// or as a Tor-only instance. Both modes feed the same dispatchTGUpdate.
+
+const (
+	tgModeWebhook = "webhook"
+	tgModePolling = "polling"
+)
+
+// tgMode is how the running bot receives updates; "" when the bot is off.
+var tgMode string
+
+// Variables rather than constants so tests can shorten them.
+var (
+	tgPollTimeout     = 30 * time.Second // getUpdates long-poll
+	tgPollBackoffBase = time.Second
+	tgPollBackoffMax  = time.Minute
+)
+
+// tgAllowedUpdates are the update types the bot asks Telegram for.
+var tgAllowedUpdates = []string{"message", "callback_query", "inline_query"}
+
+// tgInflight counts update handlers still running, so shutdown can wait
+// for them.
+var tgInflight sync.WaitGroup
+
+// tgRecentUpdates remembers this many update IDs for deduplication.
+const tgRecentUpdates = 4096
+
+// tgUpdateSet is a bounded set of recently seen update IDs. Telegram
+// redelivers an update when the webhook answer is slow or lost; handling it
+// twice could place a second order.
+type tgUpdateSet struct {
+	mu   sync.Mutex
+	seen map[int]bool
+	ring [tgRecentUpdates]int
+	next int
+}
+
+var tgSeenUpdates = &tgUpdateSet{seen: make(map[int]bool)}
+
+// add records id and reports whether it is new.
+func (s *tgUpdateSet) add(id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen[id] {
+		return false
+	}
+	if old := s.ring[s.next]; old != 0 {
+		delete(s.seen, old)
+	}
+	s.ring[s.next] = id
+	s.next = (s.next + 1) % tgRecentUpdates
+	s.seen[id] = true
+	return true
+}
+
+// dispatchTGUpdate routes an update to its handler in the background,
+// once per update_id.
+func dispatchTGUpdate(update *TGUpdate) {
+	if update.UpdateID != 0 && !tgSeenUpdates.add(update.UpdateID) {
+		return
+	}
+	var handle func()
+	switch {
+	case update.InlineQuery != nil:
+		handle = func() { handleTGInlineQuery(update.InlineQuery) }
+	case update.CallbackQuery != nil:
+		handle = func() { handleTGCallback(update.CallbackQuery) }
+	case update.Message != nil:
+		handle = func() { handleTGMessage(update.Message) }
+	default:
+		return
+	}
+	tgInflight.Add(1)
+	go func() {
+		defer tgInflight.Done()
+		handle()
+	}()
+}
+
+// tgGetUpdates long-polls for updates from offset on.
+func tgGetUpdates(ctx context.Context, offset int, timeout time.Duration) ([]TGUpdate, error) {
+	payload := map[string]interface{}{
+		"offset":          offset,
+		"timeout":         int(timeout / time.Second),
+		"allowed_updates": tgAllowedUpdates,
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout+10*time.Second)
+	defer cancel()
+	result, err := tgRequestContext(ctx, "getUpdates", payload)
+	if err != nil {
+		return nil, err
+	}
+	var updates []TGUpdate
+	if err := json.Unmarshal(result, &updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// startTGPolling runs the getUpdates loop until ctx is cancelled. The
+// returned channel is closed once the loop has stopped and the last batch
+// has been confirmed, so Telegram doesn't deliver it again after a restart.
+func startTGPolling(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		offset, failures := 0, 0
+		for ctx.Err() == nil {
+			updates, err := tgGetUpdates(ctx, offset, tgPollTimeout)
+			if ctx.Err() != nil {
+				break
+			}
+			if err != nil {
+				delay := tgPollBackoffBase << failures
+				if delay > tgPollBackoffMax || delay <= 0 {
+					delay = tgPollBackoffMax
+				} else {
+					failures++
+				}
+				log.Printf("tg getUpdates error (retrying in %s): %v", delay, err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(delay):
+				}
+				continue
+			}
+			failures = 0
+			for i := range updates {
+				if updates[i].UpdateID >= offset {
+					offset = updates[i].UpdateID + 1
+				}
+				dispatchTGUpdate(&updates[i])
+			}
+		}
+		if offset != 0 {
+			confirmCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if _, err := tgGetUpdates(confirmCtx, offset, 0); err != nil {
+				log.Printf("tg getUpdates confirm error: %v", err)
+			}
+		}
+	}()
+	return done
+}
+
+// drainTelegram waits for running update handlers until ctx expires.
+func drainTelegram(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		tgInflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("Telegram handlers still running at shutdown")
+	}
+}
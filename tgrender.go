@@ -1,7 +1,6 @@
 package main
 
 import (
-	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -41,18 +40,20 @@ func safeRunes(s string, max int) string {
 	return s
 }
 
-// padRight pads (or truncates) s to exactly n runes using spaces.
+// padRight pads (or truncates) s to exactly n columns using spaces. Card
+// text is measured in columns, not runes, so translations with wide or
+// combining characters keep the borders aligned (see textWidth).
 func padRight(s string, n int) string {
-	r := []rune(s)
-	if len(r) >= n {
-		return string(r[:n])
-	}
-	return s + strings.Repeat(" ", n-len(r))
+	s = truncWidth(s, n)
+	return s + strings.Repeat(" ", n-textWidth(s))
 }
 
-// runeLen returns the rune count of s.
-func runeLen(s string) int {
-	return len([]rune(s))
+// padCenter centers (or truncates) s in exactly n columns.
+func padCenter(s string, n int) string {
+	s = truncWidth(s, n)
+	total := n - textWidth(s)
+	left := total / 2
+	return strings.Repeat(" ", left) + s + strings.Repeat(" ", total-left)
 }
 
 // --- Box-drawing row builders ---
@@ -76,42 +77,34 @@ func cardRow(s string) string {
 
 // cardRowRight renders a right-aligned row.
 func cardRowRight(s string) string {
-	s = safeRunes(s, cardInner)
-	pad := cardInner - runeLen(s)
-	if pad < 0 {
-		pad = 0
-	}
-	return "│" + strings.Repeat(" ", pad) + s + "│"
+	s = truncWidth(s, cardInner)
+	return "│" + strings.Repeat(" ", cardInner-textWidth(s)) + s + "│"
 }
 
 // cardRowCenter renders a centered row.
 func cardRowCenter(s string) string {
-	s = safeRunes(s, cardInner)
-	n := runeLen(s)
-	total := cardInner - n
-	left := total / 2
-	right := total - left
-	return "│" + strings.Repeat(" ", left) + s + strings.Repeat(" ", right) + "│"
+	return "│" + padCenter(s, cardInner) + "│"
 }
 
 // cardRowKV renders a key-value row: " KEY   VALUE " with key left, value right.
 // Always has 1 leading space and 1 trailing space; key and value separated by ≥1 space.
 func cardRowKV(key, val string) string {
-	// Inner layout: " " + key + spaces + val + " " = 31 chars
-	// So spaces = 29 - len(key) - len(val)
-	k := []rune(key)
-	v := []rune(val)
-	gap := cardInner - 2 - len(k) - len(v)
+	// Inner layout: " " + key + spaces + val + " " = 31 columns
+	// So spaces = 29 - width(key) - width(val)
+	gap := cardInner - 2 - textWidth(key) - textWidth(val)
 	if gap < 1 {
 		// Truncate value to fit
-		maxV := cardInner - 2 - len(k) - 1
+		maxV := cardInner - 2 - textWidth(key) - 1
 		if maxV < 0 {
 			maxV = 0
 		}
-		v = []rune(safeRunes(val, maxV))
-		gap = 1
+		val = truncWidth(val, maxV)
+		gap = cardInner - 2 - textWidth(key) - textWidth(val)
+		if gap < 1 {
+			gap = 1
+		}
 	}
-	content := " " + string(k) + strings.Repeat(" ", gap) + string(v) + " "
+	content := " " + key + strings.Repeat(" ", gap) + val + " "
 	return "│" + padRight(content, cardInner) + "│"
 }
 
@@ -159,14 +152,22 @@ type DepositCardData struct {
 
 // --- Card renderers (return plain string, no <pre> wrapping) ---
 
+// Card labels are translated with l. Translations must fit the card:
+// labels in 12 columns, titles in 14 (see tgCardLabels).
+
+// cardTitle is the first row of a card: the brand and the card's name.
+func cardTitle(l *locale, name string) string {
+	return cardRow(" Ø USWAP ZERO \u2014 " + l.T(name))
+}
+
 // renderSwapCardMono builds the monospace swap card string.
-func renderSwapCardMono(sess *tgSession) string {
+func renderSwapCardMono(l *locale, sess *tgSession) string {
 	var sb strings.Builder
 
 	// Header
 	sb.WriteString(cardTop() + "\n")
 	sb.WriteString(cardRow(" Ø USWAP ZERO") + "\n")
-	sb.WriteString(cardRow(" Zero fees · Non-custodial") + "\n")
+	sb.WriteString(cardRow(" "+l.T("Zero fees · Non-custodial")) + "\n")
 	sb.WriteString(cardMid() + "\n")
 
 	// SEND / RECEIVE token rows
@@ -191,40 +192,40 @@ func renderSwapCardMono(sess *tgSession) string {
 		recvVal = safeRunes("─── "+toTicker+" / "+toNetS, 24)
 	}
 
-	sb.WriteString(cardRowKV("SEND", sendVal) + "\n")
-	sb.WriteString(cardRowKV("RECEIVE", recvVal) + "\n")
+	sb.WriteString(cardRowKV(l.T("SEND"), sendVal) + "\n")
+	sb.WriteString(cardRowKV(l.T("RECEIVE"), recvVal) + "\n")
 	sb.WriteString(cardMid() + "\n")
 
 	// Fields
 	amount := sess.Amount
 	if amount == "" {
-		amount = "─── (not set)"
+		amount = "─── " + l.T("(not set)")
 	}
-	sb.WriteString(cardRowKV("AMOUNT", safeRunes(amount, 18)) + "\n")
-	sb.WriteString(cardRowKV("SLIPPAGE", sess.Slippage+"%") + "\n")
+	sb.WriteString(cardRowKV(l.T("AMOUNT"), truncWidth(amount, 18)) + "\n")
+	sb.WriteString(cardRowKV(l.T("SLIPPAGE"), sess.Slippage+"%") + "\n")
 
-	refund := "(not set)"
+	refund := l.T("(not set)")
 	if sess.RefundAddr != "" {
 		refund = truncAddr(sess.RefundAddr) + " \u2713"
 	}
-	sb.WriteString(cardRowKV("REFUND", safeRunes(refund, 18)) + "\n")
+	sb.WriteString(cardRowKV(l.T("REFUND"), truncWidth(refund, 18)) + "\n")
 
-	recv := "(not set)"
+	recv := l.T("(not set)")
 	if sess.RecvAddr != "" {
 		recv = truncAddr(sess.RecvAddr) + " \u2713"
 	}
-	sb.WriteString(cardRowKV("RECEIVE ADDR", safeRunes(recv, 16)) + "\n")
+	sb.WriteString(cardRowKV(l.T("RECEIVE ADDR"), truncWidth(recv, 16)) + "\n")
 
 	sb.WriteString(cardBot())
 	return sb.String()
 }
 
 // renderQuoteCardMono builds the monospace quote card string.
-func renderQuoteCardMono(p QuoteCardData) string {
+func renderQuoteCardMono(l *locale, p QuoteCardData) string {
 	var sb strings.Builder
 
 	sb.WriteString(cardTop() + "\n")
-	sb.WriteString(cardRow(" Ø USWAP ZERO \u2014 QUOTE") + "\n")
+	sb.WriteString(cardTitle(l, "QUOTE") + "\n")
 	sb.WriteString(cardMid() + "\n")
 
 	// SEND section
@@ -235,13 +236,12 @@ func renderQuoteCardMono(p QuoteCardData) string {
 	amtInUSD := safeRunes(p.AmountInUSD, 12)
 	amtOutUSD := safeRunes(p.AmountOutUSD, 12)
 
-	sendLabel := "SEND"
-	recvLabel := "RECEIVE"
+	sendLabel := l.T("SEND")
+	recvLabel := l.T("RECEIVE")
 	sendPrefix := ""
 	recvPrefix := "~ "
 	if p.SwapType == "EXACT_OUTPUT" {
-		sendLabel = "SEND (est.)"
-		recvLabel = "RECEIVE"
+		sendLabel = l.T("SEND (est.)")
 		sendPrefix = "~ "
 		recvPrefix = ""
 	}
@@ -262,39 +262,40 @@ func renderQuoteCardMono(p QuoteCardData) string {
 	// Rate
 	if p.Rate != "" {
 		rate := safeRunes(p.Rate, 24)
-		sb.WriteString(cardRowKV("RATE", rate) + "\n")
+		sb.WriteString(cardRowKV(l.T("RATE"), rate) + "\n")
 	}
 	sb.WriteString(cardMid() + "\n")
 
 	// Fee breakdown
-	sb.WriteString(cardRowKV("USWAP FEE", "\u00D8 (none)") + "\n")
-	sb.WriteString(cardRowKV("PROTO FEE", "\u00D8 (none)") + "\n")
+	none := "\u00D8 " + l.T("(none)")
+	sb.WriteString(cardRowKV(l.T("USWAP FEE"), none) + "\n")
+	sb.WriteString(cardRowKV(l.T("PROTO FEE"), none) + "\n")
 
 	if p.SpreadUSD != "" && p.SpreadPct != "" {
 		spread := safeRunes("~ $"+p.SpreadUSD+" ("+p.SpreadPct+"%)", 18)
-		sb.WriteString(cardRowKV("SPREAD", spread) + "\n")
+		sb.WriteString(cardRowKV(l.T("SPREAD"), spread) + "\n")
 	} else {
-		sb.WriteString(cardRowKV("SPREAD", "\u00D8 (none)") + "\n")
+		sb.WriteString(cardRowKV(l.T("SPREAD"), none) + "\n")
 	}
 	sb.WriteString(cardMid() + "\n")
 
-	sb.WriteString(cardRowKV("FEES CHARGED", "$0.00") + "\n")
+	sb.WriteString(cardRowKV(l.T("FEES CHARGED"), "$0.00") + "\n")
 	sb.WriteString(cardBot())
 	return sb.String()
 }
 
 // renderDepositCardMono builds the monospace order/deposit card string (step 0).
 // Note: deposit address is NOT included here — callers add it as a separate <code> block.
-func renderDepositCardMono(p DepositCardData) string {
+func renderDepositCardMono(l *locale, p DepositCardData) string {
 	var sb strings.Builder
 
 	sb.WriteString(cardTop() + "\n")
-	sb.WriteString(cardRow(" Ø USWAP ZERO \u2014 ORDER") + "\n")
+	sb.WriteString(cardTitle(l, "ORDER") + "\n")
 	sb.WriteString(cardMid() + "\n")
 
 	// Stepper at step 0 (awaiting deposit)
 	sb.WriteString(cardRowCenter(stepperRow(0)) + "\n")
-	sb.WriteString(cardRowCenter("Await    Proc.    Done") + "\n")
+	sb.WriteString(cardRow(stepperLabels(l)) + "\n")
 	sb.WriteString(cardMid() + "\n")
 
 	// Swap summary
@@ -302,26 +303,26 @@ func renderDepositCardMono(p DepositCardData) string {
 	toT := safeRunes(p.ToTicker, 8)
 	amtIn := safeRunes(trimAmount(p.AmountIn, 8), 12)
 	amtOut := safeRunes(trimAmount(p.AmountOut, 8), 12)
-	sb.WriteString(cardRowKV("SEND", amtIn+" "+fromT) + "\n")
-	sb.WriteString(cardRowKV("RECEIVE", "~"+amtOut+" "+toT) + "\n")
+	sb.WriteString(cardRowKV(l.T("SEND"), amtIn+" "+fromT) + "\n")
+	sb.WriteString(cardRowKV(l.T("RECEIVE"), "~"+amtOut+" "+toT) + "\n")
 	sb.WriteString(cardMid() + "\n")
 
 	// Network + deadline
 	network := safeRunes(p.Network, 18)
-	sb.WriteString(cardRowKV("NETWORK", network) + "\n")
+	sb.WriteString(cardRowKV(l.T("NETWORK"), network) + "\n")
 	if p.Deadline != "" {
-		deadline := safeRunes(p.Deadline, 18)
-		sb.WriteString(cardRowKV("DEADLINE", deadline) + "\n")
+		deadline := truncWidth(p.Deadline, 18)
+		sb.WriteString(cardRowKV(l.T("DEADLINE"), deadline) + "\n")
 	}
 
 	// Addresses (truncated)
 	if p.RefundAddr != "" || p.RecvAddr != "" {
 		sb.WriteString(cardMid() + "\n")
 		if p.RefundAddr != "" {
-			sb.WriteString(cardRowKV("REFUND", safeRunes(truncAddr(p.RefundAddr), 16)) + "\n")
+			sb.WriteString(cardRowKV(l.T("REFUND"), safeRunes(truncAddr(p.RefundAddr), 16)) + "\n")
 		}
 		if p.RecvAddr != "" {
-			sb.WriteString(cardRowKV("RECEIVE", safeRunes(truncAddr(p.RecvAddr), 16)) + "\n")
+			sb.WriteString(cardRowKV(l.T("RECEIVE"), safeRunes(truncAddr(p.RecvAddr), 16)) + "\n")
 		}
 	}
 
@@ -331,30 +332,30 @@ func renderDepositCardMono(p DepositCardData) string {
 
 // renderAnyInputDepositCardMono builds the monospace deposit card for ANY_INPUT mode.
 // Note: deposit address is NOT included — callers add it as a separate <code> block.
-func renderAnyInputDepositCardMono(p AnyInputCardData) string {
+func renderAnyInputDepositCardMono(l *locale, p AnyInputCardData) string {
 	var sb strings.Builder
 
 	sb.WriteString(cardTop() + "\n")
-	sb.WriteString(cardRow(" Ø USWAP ZERO \u2014 QUICK SWAP") + "\n")
+	sb.WriteString(cardTitle(l, "QUICK SWAP") + "\n")
 	sb.WriteString(cardMid() + "\n")
 
 	fromT := safeRunes(p.FromTicker, 8)
 	toT := safeRunes(p.ToTicker, 8)
 
-	sb.WriteString(cardRowKV("SEND", "any "+fromT) + "\n")
-	sb.WriteString(cardRowKV("RECEIVE", "~ market "+toT) + "\n")
+	sb.WriteString(cardRowKV(l.T("SEND"), l.T("any %s", fromT)) + "\n")
+	sb.WriteString(cardRowKV(l.T("RECEIVE"), l.T("~ market %s", toT)) + "\n")
 	sb.WriteString(cardMid() + "\n")
 
 	network := safeRunes(p.Network, 18)
-	sb.WriteString(cardRowKV("NETWORK", network) + "\n")
+	sb.WriteString(cardRowKV(l.T("NETWORK"), network) + "\n")
 
 	if p.RefundAddr != "" || p.RecvAddr != "" {
 		sb.WriteString(cardMid() + "\n")
 		if p.RefundAddr != "" {
-			sb.WriteString(cardRowKV("REFUND", safeRunes(truncAddr(p.RefundAddr), 16)) + "\n")
+			sb.WriteString(cardRowKV(l.T("REFUND"), safeRunes(truncAddr(p.RefundAddr), 16)) + "\n")
 		}
 		if p.RecvAddr != "" {
-			sb.WriteString(cardRowKV("RECEIVE", safeRunes(truncAddr(p.RecvAddr), 16)) + "\n")
+			sb.WriteString(cardRowKV(l.T("RECEIVE"), safeRunes(truncAddr(p.RecvAddr), 16)) + "\n")
 		}
 	}
 
@@ -379,12 +380,23 @@ func stepperRow(step int) string {
 	return nodes[0] + "\u2500\u2500\u2500\u2500" + nodes[1] + "\u2500\u2500\u2500\u2500" + nodes[2]
 }
 
+// stepperLabels returns the row under a centered stepperRow: each step's
+// name, cut to 6 columns, centered under its node. The nodes are 7 columns
+// apart with the first centered at column 8.
+func stepperLabels(l *locale) string {
+	row := strings.Repeat(" ", 5)
+	for _, name := range []string{"Await", "Proc.", "Done"} {
+		row += padCenter(truncWidth(l.T(name), 6), 7)
+	}
+	return row
+}
+
 // renderStatusCardMono builds the monospace status card string.
-func renderStatusCardMono(order *OrderData, status *StatusResponse) string {
+func renderStatusCardMono(l *locale, order *OrderData, status *StatusResponse) string {
 	var sb strings.Builder
 
 	sb.WriteString(cardTop() + "\n")
-	sb.WriteString(cardRow(" Ø USWAP ZERO \u2014 STATUS") + "\n")
+	sb.WriteString(cardTitle(l, "STATUS") + "\n")
 	sb.WriteString(cardMid() + "\n")
 
 	var step int
@@ -398,7 +410,7 @@ func renderStatusCardMono(order *OrderData, status *StatusResponse) string {
 	}
 
 	sb.WriteString(cardRowCenter(stepperRow(step)) + "\n")
-	sb.WriteString(cardRowCenter("Await    Proc.    Done") + "\n")
+	sb.WriteString(cardRow(stepperLabels(l)) + "\n")
 	sb.WriteString(cardMid() + "\n")
 
 	fromTicker := safeRunes(order.FromTicker, 8)
@@ -413,11 +425,11 @@ func renderStatusCardMono(order *OrderData, status *StatusResponse) string {
 }
 
 // renderCompletionCardMono builds the monospace completion card string.
-func renderCompletionCardMono(order *OrderData, status *StatusResponse) string {
+func renderCompletionCardMono(l *locale, order *OrderData, status *StatusResponse) string {
 	var sb strings.Builder
 
 	sb.WriteString(cardTop() + "\n")
-	sb.WriteString(cardRow(" Ø USWAP ZERO \u2014 COMPLETE \u2713") + "\n")
+	sb.WriteString(cardRow(" Ø USWAP ZERO \u2014 "+l.T("COMPLETE")+" \u2713") + "\n")
 	sb.WriteString(cardMid() + "\n")
 
 	sb.WriteString(cardRowCenter(stepperRow(3)) + "\n")
@@ -426,7 +438,7 @@ func renderCompletionCardMono(order *OrderData, status *StatusResponse) string {
 	fromTicker := safeRunes(order.FromTicker, 8)
 	toTicker := safeRunes(order.ToTicker, 8)
 	amtIn := safeRunes(order.AmountIn, 14)
-	sb.WriteString(cardRowKV("SENT", amtIn+" "+fromTicker) + "\n")
+	sb.WriteString(cardRowKV(l.T("SENT"), amtIn+" "+fromTicker) + "\n")
 
 	// Use actual received amount if available
 	amtOut := order.AmountOut
@@ -434,33 +446,33 @@ func renderCompletionCardMono(order *OrderData, status *StatusResponse) string {
 		amtOut = status.SwapDetails.AmountOutFmt
 	}
 	amtOutS := safeRunes(amtOut, 14)
-	sb.WriteString(cardRowKV("RECEIVED", amtOutS+" "+toTicker) + "\n")
+	sb.WriteString(cardRowKV(l.T("RECEIVED"), amtOutS+" "+toTicker) + "\n")
 	sb.WriteString(cardMid() + "\n")
 
-	sb.WriteString(cardRowKV("FEES CHARGED", "\u00D8 (zero)") + "\n")
+	sb.WriteString(cardRowKV(l.T("FEES CHARGED"), "\u00D8 "+l.T("(zero)")) + "\n")
 	sb.WriteString(cardBot())
 	return sb.String()
 }
 
 // renderRefundCardMono builds the monospace refund card string.
-func renderRefundCardMono(order *OrderData, status *StatusResponse) string {
+func renderRefundCardMono(l *locale, order *OrderData, status *StatusResponse) string {
 	var sb strings.Builder
 
 	sb.WriteString(cardTop() + "\n")
-	sb.WriteString(cardRow(" Ø USWAP ZERO \u2014 REFUNDED") + "\n")
+	sb.WriteString(cardTitle(l, "REFUNDED") + "\n")
 	sb.WriteString(cardMid() + "\n")
 
 	fromTicker := safeRunes(order.FromTicker, 8)
 	toTicker := safeRunes(order.ToTicker, 8)
 	amtIn := safeRunes(order.AmountIn, 14)
 
-	sb.WriteString(cardRowKV("SENT", amtIn+" "+fromTicker) + "\n")
-	sb.WriteString(cardRowKV("SWAP TO", toTicker) + "\n")
+	sb.WriteString(cardRowKV(l.T("SENT"), amtIn+" "+fromTicker) + "\n")
+	sb.WriteString(cardRowKV(l.T("SWAP TO"), toTicker) + "\n")
 
 	if status.SwapDetails != nil && status.SwapDetails.RefundReason != "" {
-		reason := safeRunes(status.SwapDetails.RefundReason, 20)
+		reason := truncWidth(status.SwapDetails.RefundReason, 20)
 		sb.WriteString(cardMid() + "\n")
-		sb.WriteString(cardRowKV("REASON", reason) + "\n")
+		sb.WriteString(cardRowKV(l.T("REASON"), reason) + "\n")
 	}
 
 	sb.WriteString(cardBot())
@@ -468,24 +480,24 @@ func renderRefundCardMono(order *OrderData, status *StatusResponse) string {
 }
 
 // renderFailedCardMono builds the monospace failed card string.
-func renderFailedCardMono(order *OrderData, status *StatusResponse) string {
+func renderFailedCardMono(l *locale, order *OrderData, status *StatusResponse) string {
 	var sb strings.Builder
 
 	sb.WriteString(cardTop() + "\n")
-	sb.WriteString(cardRow(" Ø USWAP ZERO \u2014 FAILED") + "\n")
+	sb.WriteString(cardTitle(l, "FAILED") + "\n")
 	sb.WriteString(cardMid() + "\n")
 
 	fromTicker := safeRunes(order.FromTicker, 8)
 	toTicker := safeRunes(order.ToTicker, 8)
 	amtIn := safeRunes(order.AmountIn, 14)
 
-	sb.WriteString(cardRowKV("SENT", amtIn+" "+fromTicker) + "\n")
-	sb.WriteString(cardRowKV("SWAP TO", toTicker) + "\n")
+	sb.WriteString(cardRowKV(l.T("SENT"), amtIn+" "+fromTicker) + "\n")
+	sb.WriteString(cardRowKV(l.T("SWAP TO"), toTicker) + "\n")
 
 	if status.SwapDetails != nil && status.SwapDetails.RefundReason != "" {
-		reason := safeRunes(status.SwapDetails.RefundReason, 20)
+		reason := truncWidth(status.SwapDetails.RefundReason, 20)
 		sb.WriteString(cardMid() + "\n")
-		sb.WriteString(cardRowKV("REASON", reason) + "\n")
+		sb.WriteString(cardRowKV(l.T("REASON"), reason) + "\n")
 	}
 
 	sb.WriteString(cardBot())
@@ -495,39 +507,39 @@ func renderFailedCardMono(order *OrderData, status *StatusResponse) string {
 // renderAnyStatusCard dispatches to the correct card renderer based on status.
 // API status values: PENDING_DEPOSIT, KNOWN_DEPOSIT_TX, INCOMPLETE_DEPOSIT,
 // PROCESSING, SUCCESS, REFUNDED, FAILED
-func renderAnyStatusCard(order *OrderData, status *StatusResponse) string {
+func renderAnyStatusCard(l *locale, order *OrderData, status *StatusResponse) string {
 	switch strings.ToUpper(status.Status) {
 	case "SUCCESS":
-		return renderCompletionCardMono(order, status)
+		return renderCompletionCardMono(l, order, status)
 	case "REFUNDED":
-		return renderRefundCardMono(order, status)
+		return renderRefundCardMono(l, order, status)
 	case "FAILED", "INCOMPLETE_DEPOSIT":
-		return renderFailedCardMono(order, status)
+		return renderFailedCardMono(l, order, status)
 	default:
-		return renderStatusCardMono(order, status)
+		return renderStatusCardMono(l, order, status)
 	}
 }
 
 // deadlineString returns a human-readable deadline remaining string.
-func deadlineString(deadlineRFC3339 string) string {
+func deadlineString(l *locale, deadlineRFC3339 string) string {
 	if deadlineRFC3339 == "" {
-		return "60m remaining"
+		return l.T("%dm remaining", 60)
 	}
 	dl, err := time.Parse(time.RFC3339, deadlineRFC3339)
 	if err != nil {
-		return "60m remaining"
+		return l.T("%dm remaining", 60)
 	}
 	remaining := time.Until(dl)
 	if remaining <= 0 {
-		return "expired"
+		return l.T("expired")
 	}
 	h := int(remaining.Hours())
 	m := int(remaining.Minutes()) % 60
 	if h > 0 {
 		if m > 0 {
-			return fmt.Sprintf("%dh %dm remaining", h, m)
+			return l.T("%dh %dm remaining", h, m)
 		}
-		return fmt.Sprintf("%dh remaining", h)
+		return l.T("%dh remaining", h)
 	}
-	return fmt.Sprintf("%dm remaining", int(remaining.Minutes()))
+	return l.T("%dm remaining", int(remaining.Minutes()))
 }
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"sync"
 	"time"
 )
@@ -18,6 +20,8 @@ const (
 	stateQuoteConfirm  = 8
 	stateOrderActive   = 9
 	stateEnterAmountOut = 10
+	stateEnterLockPass  = 11 // choosing a passphrase on the quote card
+	stateEnterUnlock    = 12 // /status on a locked token
 )
 
 // tgSession holds the swap state for a single Telegram chat.
@@ -38,6 +42,7 @@ type tgSession struct {
 	RefundAddr string
 	RecvAddr   string
 	Slippage   string // percentage string: "0.5", "1", "2", "3"
+	Lang       string // the user's Telegram language_code, for the cards
 
 	// Token picker context
 	PickSide string // "from" or "to"
@@ -53,13 +58,28 @@ type tgSession struct {
 	OrderMsgIDs  []int // all message IDs related to this swap
 
 	// Quote cache
-	DryQuote *DryQuoteResponse
+	DryQuote      *DryQuoteResponse
+	QuoteCardText string // rendered quote card, re-shown after lock prompts
+
+	// Passphrase lock. LockPassphrase lives only until the order is placed;
+	// OrderKey is the derived key that opens a locked OrderToken. Neither
+	// is ever written to TG_SESSIONS_FILE.
+	LockPassphrase string `json:"-"`
+	OrderKey       []byte `json:"-"`
+	PendingToken   string // locked token awaiting its passphrase
+	UnlockTries    int
 }
 
+// tgSessionTTL is how long an untouched session is kept.
+const tgSessionTTL = 2 * time.Hour
+
 // tgSessionStore manages sessions keyed by chat_id.
 type tgSessionStore struct {
 	mu       sync.Mutex
 	sessions map[int64]*tgSession
+
+	file  *sealedFile               // nil unless TG_SESSIONS_FILE is set
+	saved map[int64]json.RawMessage // last written copy of each session
 }
 
 var tgSessions = &tgSessionStore{
@@ -80,6 +100,63 @@ func (s *tgSessionStore) get(chatID int64) *tgSession {
 		s.sessions[chatID] = sess
 	}
 	sess.LastTouch = time.Now()
+	s.file.markDirty()
+	return sess
+}
+
+// setLang records the language of the chat's user, creating the session
+// if needed.
+func (s *tgSessionStore) setLang(chatID int64, tag string) {
+	sess := s.get(chatID)
+	sess.mu.Lock()
+	sess.Lang = tag
+	sess.mu.Unlock()
+}
+
+// loc returns the catalog for the session's language.
+func (s *tgSession) loc() *locale {
+	return localeFor(s.Lang)
+}
+
+// locFor returns the catalog for a chat's language, for callers that don't
+// hold the session's lock.
+func (s *tgSessionStore) locFor(chatID int64) *locale {
+	sess := s.find(chatID)
+	if sess == nil {
+		return defaultLocale
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.loc()
+}
+
+// tgErrorText renders a store error for a chat. The limits the bot reports
+// itself are translated; anything else is shown as it is.
+func tgErrorText(l *locale, err error) string {
+	switch {
+	case errors.Is(err, errTGAddrBookChatLimit):
+		return l.T("your address book is full (%d addresses); delete one with /addresses", tgAddrBookMaxPerChat)
+	case errors.Is(err, errTGAddrBookFull):
+		return l.T("the address book is full right now; please try again later")
+	case errors.Is(err, errTGAlertChatLimit):
+		return l.T("you can have at most %d alerts; delete one with /alerts", tgAlertMaxPerChat)
+	case errors.Is(err, errTGAlertFull):
+		return l.T("alerts are full right now; please try again later")
+	case errors.Is(err, errTGHistoryFull):
+		return l.T("order history is full right now; please try again later")
+	}
+	return err.Error()
+}
+
+// find returns the session for a chat, or nil if it has none (or it expired).
+// Unlike get it neither creates nor touches the session.
+func (s *tgSessionStore) find(chatID int64) *tgSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := s.sessions[chatID]
+	if sess != nil {
+		s.file.markDirty() // the caller may change it
+	}
 	return sess
 }
 
@@ -104,6 +181,11 @@ func (sess *tgSession) reset() {
 	sess.DepositMsgID = 0
 	sess.OrderMsgIDs = nil
 	sess.DryQuote = nil
+	sess.QuoteCardText = ""
+	sess.LockPassphrase = ""
+	sess.OrderKey = nil
+	sess.PendingToken = ""
+	sess.UnlockTries = 0
 }
 
 // trackMsg records a message ID for later cleanup.
@@ -140,11 +222,81 @@ func (s *tgSessionStore) startCleanup() {
 			s.mu.Lock()
 			now := time.Now()
 			for id, sess := range s.sessions {
-				if now.Sub(sess.LastTouch) > 2*time.Hour {
+				if now.Sub(sess.LastTouch) > tgSessionTTL {
 					delete(s.sessions, id)
+					s.file.markDirty()
 				}
 			}
 			s.mu.Unlock()
 		}
 	}()
 }
+
+// ── Optional persistence ──
+//
+// With TG_SESSIONS_FILE set, sessions are written to an encrypted file (see
+// sealedfile.go) and restored on startup, so a deploy doesn't leave swap
+// cards with dead buttons. Passphrases and unlock keys are never written: a
+// restored locked order asks for its passphrase again.
+
+// tgSessionSettle is how long after its last update a session keeps being
+// re-checked for changes. Handlers change a session after get returns, so
+// one snapshot right after get may miss the change.
+const tgSessionSettle = time.Minute
+
+// initTGSessions restores sessions from TG_SESSIONS_FILE, if set, dropping
+// those past tgSessionTTL.
+func initTGSessions() {
+	var saved map[int64]json.RawMessage
+	f := openSealedFile("TG_SESSIONS_FILE", "tg-sessions", &saved, tgSessions.snapshot)
+	if f == nil {
+		return
+	}
+	tgSessions.mu.Lock()
+	defer tgSessions.mu.Unlock()
+	tgSessions.file = f
+	now := time.Now()
+	for chatID, raw := range saved {
+		sess := &tgSession{}
+		if err := json.Unmarshal(raw, sess); err != nil || now.Sub(sess.LastTouch) > tgSessionTTL {
+			continue
+		}
+		if sess.State == stateEnterLockPass { // the passphrase prompt's answer was never saved
+			sess.State = stateQuoteConfirm
+		}
+		tgSessions.sessions[chatID] = sess
+	}
+}
+
+// snapshot encodes every session for TG_SESSIONS_FILE. A session busy with
+// a request keeps its last written copy and is picked up next time.
+func (s *tgSessionStore) snapshot() interface{} {
+	now := time.Now()
+	s.mu.Lock()
+	sessions := make(map[int64]*tgSession, len(s.sessions))
+	for id, sess := range s.sessions {
+		sessions[id] = sess
+		if now.Sub(sess.LastTouch) < tgSessionSettle {
+			s.file.markDirty()
+		}
+	}
+	s.mu.Unlock()
+
+	out := make(map[int64]json.RawMessage, len(sessions))
+	for id, sess := range sessions {
+		if !sess.mu.TryLock() {
+			if prev, ok := s.saved[id]; ok {
+				out[id] = prev
+			}
+			s.file.markDirty()
+			continue
+		}
+		raw, err := json.Marshal(sess)
+		sess.mu.Unlock()
+		if err == nil {
+			out[id] = raw
+		}
+	}
+	s.saved = out
+	return out
+}
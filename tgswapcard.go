@@ -1,7 +1,6 @@
 package main
 
 import (
-	"fmt"
 	"log"
 	"net/url"
 	"strconv"
@@ -28,7 +27,7 @@ func tokenLabel(ticker, net string) string {
 	return ticker
 }
 
-// buildAppURL builds the webapp URL with session params pre-filled.
+// buildAppURL builds the Mini App URL with session params pre-filled.
 func buildAppURL(sess *tgSession) string {
 	params := url.Values{}
 	if sess.FromTicker != "" {
@@ -57,31 +56,32 @@ func buildAppURL(sess *tgSession) string {
 	}
 	q := params.Encode()
 	if q != "" {
-		return tgAppURL + "/?" + q
+		return tgAppURL + "/tg/app?" + q
 	}
-	return tgAppURL + "/"
+	return tgAppURL + "/tg/app"
 }
 
 // renderSwapCard builds the swap card text and inline keyboard.
 func renderSwapCard(sess *tgSession) (string, *TGInlineKeyboardMarkup) {
+	l := sess.loc()
 	var sb strings.Builder
 
 	// Inline capability hint — only shown when monitor is running
 	if monitorTotalFeeUSD() > 0 {
-		sb.WriteString("Bring 0% with you on the go with <b>inline mode</b>.\n" +
-			"Type <code>@" + tgBotUsername + " 0.5 BTC ETH</code> to swap in any chat.\n\n")
+		sb.WriteString(l.T("Bring 0% with you on the go with <b>inline mode</b>.") + "\n" +
+			l.T("Type %s to swap in any chat.", "<code>@"+tgBotUsername+" 0.5 BTC ETH</code>") + "\n\n")
 	}
 
-	sb.WriteString("<pre>" + renderSwapCardMono(sess) + "</pre>")
+	sb.WriteString("<pre>" + renderSwapCardMono(l, sess) + "</pre>")
 
 	// Footer links
 	sb.WriteString("\n\n")
 	if commitHash != "development" && commitHash != "unknown" && commitHash != "" {
-		sb.WriteString("<a href=\"https://github.com/uSwapExchange/zero/commit/" + commitHash + "\">[Commit]</a> · ")
+		sb.WriteString("<a href=\"https://github.com/uSwapExchange/zero/commit/" + commitHash + "\">[" + l.T("Commit") + "]</a> · ")
 	}
-	sb.WriteString("<a href=\"https://github.com/uSwapExchange/zero\">[Source Code]</a>")
+	sb.WriteString("<a href=\"https://github.com/uSwapExchange/zero\">[" + l.T("Source Code") + "]</a>")
 	if buildLogURL != "" && buildLogURL != "unknown" {
-		sb.WriteString(" · <a href=\"" + buildLogURL + "\">[Build Log]</a>")
+		sb.WriteString(" · <a href=\"" + buildLogURL + "\">[" + l.T("Build Log") + "]</a>")
 	}
 
 	text := sb.String()
@@ -93,9 +93,9 @@ func renderSwapCard(sess *tgSession) (string, *TGInlineKeyboardMarkup) {
 
 	// Row 1: Token pickers + flip button
 	rows = append(rows, []TGInlineKeyboardButton{
-		{Text: "[Send] " + fromLabel, CallbackData: "pf", Style: "danger"},
+		{Text: "[" + l.T("Send") + "] " + fromLabel, CallbackData: "pf", Style: "danger"},
 		{Text: "🔁", CallbackData: "sw"},
-		{Text: "[Recv] " + toLabel, CallbackData: "pt", Style: "success"},
+		{Text: "[" + l.T("Recv") + "] " + toLabel, CallbackData: "pt", Style: "success"},
 	})
 
 	// Row 2: Inline slippage — always visible, selected marked with ●
@@ -119,45 +119,45 @@ func renderSwapCard(sess *tgSession) (string, *TGInlineKeyboardMarkup) {
 	// Row 3: Set Send Amount + Set Receive Amount
 	sendAmtBtn := TGInlineKeyboardButton{CallbackData: "sa"}
 	if sess.Amount != "" {
-		sendAmtBtn.Text = "✓ Send: " + sess.Amount + " " + fromLabel
+		sendAmtBtn.Text = "✓ " + l.T("Send: %s", sess.Amount+" "+fromLabel)
 		sendAmtBtn.Style = "primary"
 	} else {
-		sendAmtBtn.Text = "Set Send Amt"
+		sendAmtBtn.Text = l.T("Set Send Amt")
 	}
 	recvAmtBtn := TGInlineKeyboardButton{CallbackData: "sao"}
 	if sess.AmountOut != "" {
-		recvAmtBtn.Text = "✓ Recv: " + sess.AmountOut + " " + toLabel
+		recvAmtBtn.Text = "✓ " + l.T("Recv: %s", sess.AmountOut+" "+toLabel)
 		recvAmtBtn.Style = "primary"
 	} else {
-		recvAmtBtn.Text = "Set Recv Amt"
+		recvAmtBtn.Text = l.T("Set Recv Amt")
 	}
 	rows = append(rows, []TGInlineKeyboardButton{sendAmtBtn, recvAmtBtn})
 
 	// Row 4: Set Refund Address
 	refundBtn := TGInlineKeyboardButton{CallbackData: "sr"}
 	if sess.RefundAddr != "" {
-		refundBtn.Text = "✓ Refund: " + truncAddr(sess.RefundAddr)
+		refundBtn.Text = "✓ " + l.T("Refund: %s", truncAddr(sess.RefundAddr))
 		refundBtn.Style = "primary"
 	} else {
-		refundBtn.Text = "Set Refund Address"
+		refundBtn.Text = l.T("Set Refund Address")
 	}
 	rows = append(rows, []TGInlineKeyboardButton{refundBtn})
 
 	// Row 5: Set Receive Address
 	recvBtn := TGInlineKeyboardButton{CallbackData: "sp"}
 	if sess.RecvAddr != "" {
-		recvBtn.Text = "✓ Receive: " + truncAddr(sess.RecvAddr)
+		recvBtn.Text = "✓ " + l.T("Receive: %s", truncAddr(sess.RecvAddr))
 		recvBtn.Style = "primary"
 	} else {
-		recvBtn.Text = "Set Receive Address"
+		recvBtn.Text = l.T("Set Receive Address")
 	}
 	rows = append(rows, []TGInlineKeyboardButton{recvBtn})
 
 	// Row 6: Get Quote / Quick Swap (only when all fields filled)
 	if sess.isComplete() {
-		quoteLabel := "✅ Get Quote →"
+		quoteLabel := "✅ " + l.T("Get Quote") + " →"
 		if sess.Amount == "" && sess.AmountOut == "" {
-			quoteLabel = "⚡ Quick Swap →"
+			quoteLabel = "⚡ " + l.T("Quick Swap") + " →"
 		}
 		rows = append(rows, []TGInlineKeyboardButton{
 			{Text: quoteLabel, CallbackData: "gq", Style: "success"},
@@ -166,7 +166,7 @@ func renderSwapCard(sess *tgSession) (string, *TGInlineKeyboardMarkup) {
 
 	// Row 7: Open in web app with session params pre-filled
 	rows = append(rows, []TGInlineKeyboardButton{
-		{Text: "📱 Open Quote in App", WebApp: &TGWebApp{URL: buildAppURL(sess)}},
+		{Text: "📱 " + l.T("Open Quote in App"), WebApp: &TGWebApp{URL: buildAppURL(sess)}},
 	})
 
 	return text, &TGInlineKeyboardMarkup{InlineKeyboard: rows}
@@ -202,10 +202,11 @@ func handleTGPickToken(chatID int64, sess *tgSession, side string) {
 		tgDeleteMessage(chatID, sess.PromptMsgID)
 		sess.PromptMsgID = 0
 	}
-	msg, err := tgSendMessage(chatID, "Or <u>type to search</u> by name or ticker:", &TGForceReply{
+	l := sess.loc()
+	msg, err := tgSendMessage(chatID, l.T("Or <u>type to search</u> by name or ticker:"), &TGForceReply{
 		ForceReply:            true,
 		Selective:             true,
-		InputFieldPlaceholder: "e.g. BTC, Ethereum, USDT...",
+		InputFieldPlaceholder: l.T("e.g. BTC, Ethereum, USDT..."),
 	})
 	if err == nil {
 		sess.PromptMsgID = msg.MessageID
@@ -214,11 +215,12 @@ func handleTGPickToken(chatID int64, sess *tgSession, side string) {
 
 // renderTokenPicker builds the token picker grid.
 func renderTokenPicker(sess *tgSession, page int) (string, *TGInlineKeyboardMarkup) {
-	side := "Send"
+	l := sess.loc()
+	title := l.T("Select Send Token")
 	if sess.PickSide == "to" {
-		side = "Receive"
+		title = l.T("Select Receive Token")
 	}
-	text := fmt.Sprintf("<b>Select %s Token</b>\n\nTap a token or <u>type to search</u>.", side)
+	text := "<b>" + title + "</b>\n\n" + l.T("Tap a token or <u>type to search</u>.")
 
 	var rows [][]TGInlineKeyboardButton
 
@@ -237,7 +239,7 @@ func renderTokenPicker(sess *tgSession, page int) (string, *TGInlineKeyboardMark
 
 	// Back row
 	rows = append(rows, []TGInlineKeyboardButton{
-		{Text: "← Back", CallbackData: "bk"},
+		{Text: l.T("← Back"), CallbackData: "bk"},
 	})
 
 	return text, &TGInlineKeyboardMarkup{InlineKeyboard: rows}
@@ -249,12 +251,13 @@ func handleTGTokenSearch(chatID int64, sess *tgSession, msg *TGMessage) {
 	// Clean up the ForceReply prompt and the user's typed message.
 	cleanupPromptReply(chatID, sess, msg.MessageID)
 
+	l := sess.loc()
 	results := searchTokens(query)
 	if len(results) == 0 {
-		text := "<b>No tokens found for:</b> " + query + "\n\nTry a different ticker or name."
+		text := "<b>" + l.T("No tokens found for:") + "</b> " + query + "\n\n" + l.T("Try a different ticker or name.")
 		markup := &TGInlineKeyboardMarkup{
 			InlineKeyboard: [][]TGInlineKeyboardButton{
-				{{Text: "← Back", CallbackData: "bk"}},
+				{{Text: l.T("← Back"), CallbackData: "bk"}},
 			},
 		}
 		if sess.CardMsgID != 0 {
@@ -276,7 +279,7 @@ func handleTGTokenSearch(chatID int64, sess *tgSession, msg *TGMessage) {
 		}
 	}
 
-	text := fmt.Sprintf("<b>Results for:</b> %s", query)
+	text := "<b>" + l.T("Results for:") + "</b> " + query
 	var rows [][]TGInlineKeyboardButton
 	for i := 0; i < len(unique); i += 3 {
 		var row []TGInlineKeyboardButton
@@ -289,7 +292,7 @@ func handleTGTokenSearch(chatID int64, sess *tgSession, msg *TGMessage) {
 		rows = append(rows, row)
 	}
 	rows = append(rows, []TGInlineKeyboardButton{
-		{Text: "← Back", CallbackData: "bk"},
+		{Text: l.T("← Back"), CallbackData: "bk"},
 	})
 
 	if sess.CardMsgID != 0 {
@@ -340,7 +343,8 @@ func handleTGTokenSelected(chatID int64, sess *tgSession, ticker string) {
 		}
 	}
 
-	text := fmt.Sprintf("<b>Select network for %s:</b>", ticker)
+	l := sess.loc()
+	text := "<b>" + l.T("Select network for %s:", ticker) + "</b>"
 	var rows [][]TGInlineKeyboardButton
 	for i := 0; i < len(unique); i += 3 {
 		var row []TGInlineKeyboardButton
@@ -354,7 +358,7 @@ func handleTGTokenSelected(chatID int64, sess *tgSession, ticker string) {
 		rows = append(rows, row)
 	}
 	rows = append(rows, []TGInlineKeyboardButton{
-		{Text: "← Back", CallbackData: "bk"},
+		{Text: l.T("← Back"), CallbackData: "bk"},
 	})
 
 	if sess.CardMsgID != 0 {
@@ -427,12 +431,13 @@ func handleTGSwapDirection(chatID int64, sess *tgSession) {
 // --- Amount & Address Input ---
 
 func handleTGPromptAmount(chatID int64, sess *tgSession) {
+	l := sess.loc()
 	sess.State = stateEnterAmount
-	prompt := fmt.Sprintf("Enter amount of %s to swap:", sess.FromTicker)
+	prompt := l.T("Enter amount of %s to swap:", sess.FromTicker)
 	msg, err := tgSendMessage(chatID, prompt, &TGForceReply{
 		ForceReply:            true,
 		Selective:             true,
-		InputFieldPlaceholder: "e.g. 0.5",
+		InputFieldPlaceholder: l.T("e.g. %s", "0.5"),
 	})
 	if err == nil {
 		sess.PromptMsgID = msg.MessageID
@@ -444,7 +449,7 @@ func handleTGAmountInput(chatID int64, sess *tgSession, msg *TGMessage) {
 
 	// Basic validation
 	if _, err := humanToAtomic(amount, 8); err != nil {
-		errMsg, _ := tgSendMessage(chatID, "Invalid amount. Please enter a number (e.g. 0.5).", nil)
+		errMsg, _ := tgSendMessage(chatID, sess.loc().T("Invalid amount. Please enter a number (e.g. %s).", "0.5"), nil)
 		if errMsg != nil {
 			go func() {
 				tgDeleteMessage(chatID, errMsg.MessageID)
@@ -462,12 +467,13 @@ func handleTGAmountInput(chatID int64, sess *tgSession, msg *TGMessage) {
 }
 
 func handleTGPromptAmountOut(chatID int64, sess *tgSession) {
+	l := sess.loc()
 	sess.State = stateEnterAmountOut
-	prompt := fmt.Sprintf("Enter amount of %s to receive:", sess.ToTicker)
+	prompt := l.T("Enter amount of %s to receive:", sess.ToTicker)
 	msg, err := tgSendMessage(chatID, prompt, &TGForceReply{
 		ForceReply:            true,
 		Selective:             true,
-		InputFieldPlaceholder: "e.g. 1.0",
+		InputFieldPlaceholder: l.T("e.g. %s", "1.0"),
 	})
 	if err == nil {
 		sess.PromptMsgID = msg.MessageID
@@ -478,7 +484,7 @@ func handleTGAmountOutInput(chatID int64, sess *tgSession, msg *TGMessage) {
 	amount := strings.TrimSpace(msg.Text)
 
 	if _, err := humanToAtomic(amount, 8); err != nil {
-		errMsg, _ := tgSendMessage(chatID, "Invalid amount. Please enter a number (e.g. 1.0).", nil)
+		errMsg, _ := tgSendMessage(chatID, sess.loc().T("Invalid amount. Please enter a number (e.g. %s).", "1.0"), nil)
 		if errMsg != nil {
 			go func() {
 				tgDeleteMessage(chatID, errMsg.MessageID)
@@ -496,13 +502,19 @@ func handleTGAmountOutInput(chatID int64, sess *tgSession, msg *TGMessage) {
 }
 
 func handleTGPromptRefund(chatID int64, sess *tgSession) {
+	l := sess.loc()
 	sess.State = stateEnterRefund
-	prompt := fmt.Sprintf("Enter your %s refund address:", sess.FromTicker)
-	msg, err := tgSendMessage(chatID, prompt, &TGForceReply{
+	prompt := l.T("Enter your %s refund address:", sess.FromTicker)
+	var markup interface{} = &TGForceReply{
 		ForceReply:            true,
 		Selective:             true,
-		InputFieldPlaceholder: "Paste address...",
-	})
+		InputFieldPlaceholder: l.T("Paste address..."),
+	}
+	if picker := tgAddrPicker(l, chatID, sess.FromNet); picker != nil {
+		prompt = l.T("Enter your %s refund address, or pick a saved one:", sess.FromTicker)
+		markup = picker
+	}
+	msg, err := tgSendMessage(chatID, prompt, markup)
 	if err == nil {
 		sess.PromptMsgID = msg.MessageID
 	}
@@ -510,8 +522,8 @@ func handleTGPromptRefund(chatID int64, sess *tgSession) {
 
 func handleTGRefundInput(chatID int64, sess *tgSession, msg *TGMessage) {
 	addr := strings.TrimSpace(msg.Text)
-	if len(addr) < 10 {
-		tgSendMessage(chatID, "Address seems too short. Please try again.", nil)
+	if problem := addressProblem("refund", sess.FromNet, addr); problem != "" {
+		tgSendMessage(chatID, "❌ "+problem+" "+sess.loc().T("Please try again."), nil)
 		return
 	}
 
@@ -523,13 +535,19 @@ func handleTGRefundInput(chatID int64, sess *tgSession, msg *TGMessage) {
 }
 
 func handleTGPromptRecv(chatID int64, sess *tgSession) {
+	l := sess.loc()
 	sess.State = stateEnterRecv
-	prompt := fmt.Sprintf("Enter your %s receive address:", sess.ToTicker)
-	msg, err := tgSendMessage(chatID, prompt, &TGForceReply{
+	prompt := l.T("Enter your %s receive address:", sess.ToTicker)
+	var markup interface{} = &TGForceReply{
 		ForceReply:            true,
 		Selective:             true,
-		InputFieldPlaceholder: "Paste address...",
-	})
+		InputFieldPlaceholder: l.T("Paste address..."),
+	}
+	if picker := tgAddrPicker(l, chatID, sess.ToNet); picker != nil {
+		prompt = l.T("Enter your %s receive address, or pick a saved one:", sess.ToTicker)
+		markup = picker
+	}
+	msg, err := tgSendMessage(chatID, prompt, markup)
 	if err == nil {
 		sess.PromptMsgID = msg.MessageID
 	}
@@ -537,8 +555,8 @@ func handleTGPromptRecv(chatID int64, sess *tgSession) {
 
 func handleTGRecvInput(chatID int64, sess *tgSession, msg *TGMessage) {
 	addr := strings.TrimSpace(msg.Text)
-	if len(addr) < 10 {
-		tgSendMessage(chatID, "Address seems too short. Please try again.", nil)
+	if problem := addressProblem("recipient", sess.ToNet, addr); problem != "" {
+		tgSendMessage(chatID, "❌ "+problem+" "+sess.loc().T("Please try again."), nil)
 		return
 	}
 
@@ -601,6 +619,9 @@ func networkDisplayName(chain string) string {
 		"sui": "Sui", "apt": "Aptos", "aptos": "Aptos", "doge": "Dogecoin",
 		"ltc": "Litecoin", "xrp": "XRP", "bch": "Bitcoin Cash",
 		"xlm": "Stellar", "stellar": "Stellar", "zec": "Zcash",
+		"cardano": "Cardano", "starknet": "StarkNet", "gnosis": "Gnosis",
+		"bera": "Berachain", "monad": "Monad", "plasma": "Plasma",
+		"xlayer": "X Layer", "aleo": "Aleo",
 	}
 	if name, ok := names[strings.ToLower(chain)]; ok {
 		return name
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Order status notifications for Telegram.
+//
+// "🔔 Notify me" on an order card registers the chat with tgWatches, which
+// polls orderStatuses in the background. On a status change it edits the
+// card in place (if the chat is still showing that order) and sends a short
+// message for the steps worth a ping. A watch ends on a terminal status, a
+// while after the order's deadline, or when toggled off. Nothing is
+// persisted: a restart forgets every watch.
+
+// Variables rather than constants so tests can shorten them.
+var (
+	tgWatchInterval = 15 * time.Second
+	tgWatchGrace    = time.Hour      // keep watching this long past the deadline
+	tgWatchMaxAge   = 24 * time.Hour // for orders without a deadline
+)
+
+// tgWatchMax bounds concurrent watches across all chats.
+const tgWatchMax = 5000
+
+// tgWatch is one chat watching one order. A chat watches at most one order:
+// the one on its card.
+type tgWatch struct {
+	token      string
+	key        []byte // unlock key for locked tokens
+	order      *OrderData
+	lastStatus string
+	until      time.Time
+}
+
+type tgWatchStore struct {
+	mu      sync.Mutex
+	watches map[int64]*tgWatch
+}
+
+var tgWatches = &tgWatchStore{watches: make(map[int64]*tgWatch)}
+
+// watching reports whether chatID is watching the order behind token.
+func (s *tgWatchStore) watching(chatID int64, token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, ok := s.watches[chatID]
+	return ok && w.token == token
+}
+
+// add starts watching order for chatID, replacing any earlier watch, and
+// reports whether there was room.
+func (s *tgWatchStore) add(chatID int64, token string, key []byte, order *OrderData, status string) bool {
+	until := time.Now().Add(tgWatchMaxAge)
+	if dl, err := time.Parse(time.RFC3339, order.Deadline); err == nil {
+		until = dl.Add(tgWatchGrace)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.watches[chatID]; !ok && len(s.watches) >= tgWatchMax {
+		return false
+	}
+	s.watches[chatID] = &tgWatch{token: token, key: key, order: order, lastStatus: status, until: until}
+	return true
+}
+
+func (s *tgWatchStore) remove(chatID int64) {
+	s.mu.Lock()
+	delete(s.watches, chatID)
+	s.mu.Unlock()
+}
+
+// start polls every watched order each tgWatchInterval.
+func (s *tgWatchStore) start() {
+	go func() {
+		for {
+			time.Sleep(tgWatchInterval)
+			s.poll()
+		}
+	}()
+}
+
+// poll checks every watch once, a few at a time.
+func (s *tgWatchStore) poll() {
+	s.mu.Lock()
+	chats := make([]int64, 0, len(s.watches))
+	for chatID := range s.watches {
+		chats = append(chats, chatID)
+	}
+	s.mu.Unlock()
+
+	sem := make(chan struct{}, 8)
+	var wg sync.WaitGroup
+	for _, chatID := range chats {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(chatID int64) {
+			defer func() { <-sem; wg.Done() }()
+			s.check(chatID)
+		}(chatID)
+	}
+	wg.Wait()
+}
+
+// check looks up one watched order and reports a status change.
+func (s *tgWatchStore) check(chatID int64) {
+	s.mu.Lock()
+	w, ok := s.watches[chatID]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	if time.Now().After(w.until) {
+		s.remove(chatID)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), nearTimeoutStatus)
+	res, err := orderStatuses.get(ctx, w.order)
+	cancel()
+	if err != nil || res.Status.Status == w.lastStatus {
+		return
+	}
+
+	s.mu.Lock()
+	if s.watches[chatID] != w {
+		s.mu.Unlock() // toggled off or replaced meanwhile
+		return
+	}
+	w.lastStatus = res.Status.Status
+	terminal := isTerminalStatus(w.lastStatus)
+	if terminal {
+		delete(s.watches, chatID)
+	}
+	s.mu.Unlock()
+
+	notifyTGStatusChange(chatID, w, res.Status)
+}
+
+// notifyTGStatusChange updates the chat's order card if it still shows the
+// watched order, then sends a short notice for steps worth a ping.
+func notifyTGStatusChange(chatID int64, w *tgWatch, status *StatusResponse) {
+	var card *tgSession // the chat's session, if its card still shows this order
+	l := defaultLocale
+	if sess := tgSessions.find(chatID); sess != nil {
+		sess.mu.Lock()
+		defer sess.mu.Unlock()
+		l = sess.loc()
+		if sess.OrderToken == w.token && sess.CardMsgID != 0 {
+			card = sess
+		}
+	}
+	if card != nil {
+		cardText, markup := buildOrderCard(l, w.order, status, w.token)
+		addAttestationButton(markup, card)
+		addWatchButton(markup, chatID, card, status.Status)
+		addSaveAddressesButton(l, markup, chatID, w.order, status.Status)
+		if err := tgEditMessage(chatID, card.CardMsgID, cardText, markup); err != nil {
+			log.Printf("tg watch edit error: %v", err)
+		}
+	}
+
+	text := tgStatusNotice(l, w.order, status.Status)
+	if text == "" {
+		return
+	}
+	msg, err := tgSendMessage(chatID, text, nil)
+	if err != nil {
+		log.Printf("tg watch notify error: %v", err)
+		return
+	}
+	if card != nil {
+		card.trackMsg(msg.MessageID)
+	}
+}
+
+// tgStatusNotice is the message sent when a watched order reaches status,
+// or "" for steps that only update the card.
+func tgStatusNotice(l *locale, order *OrderData, status string) string {
+	pair := order.FromTicker + " → " + order.ToTicker
+	switch status {
+	case "PROCESSING":
+		return l.T("⏳ Deposit received — your %s swap is processing.", pair)
+	case "SUCCESS":
+		if order.AmountIn == "" { // ANY_INPUT
+			return l.T("✅ Your %s swap is complete.", pair)
+		}
+		return l.T("✅ Swap complete: %s → %s.", order.AmountIn+" "+order.FromTicker, order.AmountOut+" "+order.ToTicker)
+	case "REFUNDED":
+		return l.T("↩️ Your %s swap was refunded to your refund address.", pair)
+	case "INCOMPLETE_DEPOSIT":
+		return l.T("⚠️ Incomplete deposit for your %s swap — less than the quoted amount arrived.", pair)
+	}
+	return ""
+}
+
+// addWatchButton adds the notification toggle to a non-terminal order card.
+func addWatchButton(markup *TGInlineKeyboardMarkup, chatID int64, sess *tgSession, status string) {
+	if markup == nil || sess.OrderToken == "" || isTerminalStatus(status) {
+		return
+	}
+	l := sess.loc()
+	text := l.T("🔔 Notify me")
+	if tgWatches.watching(chatID, sess.OrderToken) {
+		text = l.T("🔕 Stop notifications")
+	}
+	markup.InlineKeyboard = append(markup.InlineKeyboard, []TGInlineKeyboardButton{
+		{Text: text, CallbackData: "nw"},
+	})
+}
+
+// handleTGToggleWatch turns notifications for the session's order on or off
+// and redraws the card.
+func handleTGToggleWatch(chatID int64, sess *tgSession) {
+	if sess.OrderToken == "" {
+		return
+	}
+	if tgWatches.watching(chatID, sess.OrderToken) {
+		tgWatches.remove(chatID)
+	} else if !startTGWatch(chatID, sess.OrderToken, sess.OrderKey) {
+		tgSendMessage(chatID, sess.loc().T("❌ Notifications are busy right now. Use 🔄 Refresh Status instead."), nil)
+		return
+	}
+	handleTGRefreshStatus(chatID, sess)
+}
+
+// startTGWatch registers chatID for the order behind token.
+func startTGWatch(chatID int64, token string, key []byte) bool {
+	order, err := decryptOrderData(token)
+	if err != nil {
+		return false
+	}
+	if order.Locked() {
+		if order, err = unlockOrderDataWithKey(order, key); err != nil {
+			return false
+		}
+	}
+	status := ""
+	if res, err := orderStatuses.get(context.Background(), order); err == nil {
+		status = res.Status.Status
+	}
+	return tgWatches.add(chatID, token, key, order, status)
+}
+
+// ── Web → Telegram hand-off ──
+//
+// Order tokens are far longer than Telegram's 64-character start parameter,
+// so "Continue in Telegram" on the web order page parks the token (and the
+// unlock key of an unlocked view) under a short random ID for a few minutes.
+// /start watch_<id> claims it once, shows the order card and turns on
+// notifications.
+
+const (
+	tgHandoffTTL = 15 * time.Minute
+	tgHandoffMax = 10000
+)
+
+type tgHandoff struct {
+	token   string
+	key     []byte
+	expires time.Time
+}
+
+type tgHandoffStore struct {
+	mu      sync.Mutex
+	entries map[string]tgHandoff
+}
+
+var tgHandoffs = &tgHandoffStore{entries: make(map[string]tgHandoff)}
+
+// put parks token and returns its hand-off ID, or "" if the store is full.
+func (s *tgHandoffStore) put(token string, key []byte) string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	id := base64.RawURLEncoding.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if len(s.entries) >= tgHandoffMax {
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+	}
+	if len(s.entries) >= tgHandoffMax {
+		return ""
+	}
+	s.entries[id] = tgHandoff{token: token, key: key, expires: now.Add(tgHandoffTTL)}
+	return id
+}
+
+// take removes and returns the hand-off stored under id.
+func (s *tgHandoffStore) take(id string) (tgHandoff, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.entries[id]
+	delete(s.entries, id)
+	if !ok || time.Now().After(h.expires) {
+		return tgHandoff{}, false
+	}
+	return h, true
+}
+
+// handleTGWatchStart handles /start watch_<id> from the web order page.
+func handleTGWatchStart(chatID int64, id string) {
+	l := tgSessions.locFor(chatID)
+	h, ok := tgHandoffs.take(id)
+	if !ok {
+		tgSendMessage(chatID, l.T("This link has expired. Open the order page and tap “Continue in Telegram” again, or use /status with the order token."), nil)
+		return
+	}
+	order, err := decryptOrderData(h.token)
+	if err == nil && order.Locked() {
+		order, err = unlockOrderDataWithKey(order, h.key)
+	}
+	if err != nil {
+		tgSendMessage(chatID, l.T("Invalid order token."), nil)
+		return
+	}
+
+	sess := tgSessions.get(chatID)
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	startTGWatch(chatID, h.token, h.key)
+	sendTGStatusCard(chatID, sess, order, h.token, h.key)
+}
+
+// continueInTelegram serves /order/{token}/telegram: it parks the order and
+// redirects to the bot's /start watch_<id> deep link. Locked orders arrive
+// here by POST with their unlock key, like the attestation download.
+func continueInTelegram(w http.ResponseWriter, r *http.Request, token string, locked bool, unlockKey string) {
+	if locked || tgBotUsername == "" {
+		renderError(w, r, 404, "Not Available", "Telegram notifications are not available for this order.", "Back to Order", "/order/"+token)
+		return
+	}
+	if !limiter.allowScoped("handoff", clientIP(r), 10, time.Minute) {
+		renderError(w, r, 429, "Too Many Requests", "Please wait a minute before trying again.", "Back to Order", "/order/"+token)
+		return
+	}
+	id := tgHandoffs.put(token, decodeUnlockKey(unlockKey))
+	if id == "" {
+		renderError(w, r, 503, "Try Again Later", "Too many pending Telegram links. Please try again in a few minutes.", "Back to Order", "/order/"+token)
+		return
+	}
+	http.Redirect(w, r, "https://t.me/"+tgBotUsername+"?start=watch_"+id, http.StatusSeeOther)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"sort"
 	"strings"
@@ -28,7 +29,7 @@ var cache = &tokenCache{}
 
 // refreshTokenCache fetches and caches the token list from NEAR Intents.
 func refreshTokenCache() error {
-	tokens, err := fetchTokens()
+	tokens, err := fetchTokens(context.Background())
 	if err != nil {
 		return err
 	}
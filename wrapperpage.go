@@ -151,12 +151,13 @@ func handleWrapperLogs(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	pd := newPageData(r, "Wrapper Logs")
+	pd.MetaRefresh = 60
+
 	// Build sort toggle URLs — clicking a sorted column reverses direction
-	sortFeeURL := sortToggleURL(query, filterReseller, "fee", sortBy, sortDir)
-	sortDateURL := sortToggleURL(query, filterReseller, "date", sortBy, sortDir)
+	sortFeeURL := pd.Link(sortToggleURL(query, filterReseller, "fee", sortBy, sortDir))
+	sortDateURL := pd.Link(sortToggleURL(query, filterReseller, "date", sortBy, sortDir))
 
-	pd := newPageData("Wrapper Logs")
-	pd.MetaRefresh = 60
 	data := WrapperLogsPageData{
 		PageData:       pd,
 		Entries:        rows,